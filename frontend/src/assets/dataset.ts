@@ -1,7 +1,48 @@
 export default {
     videoHeight: ['copy', '480', '720', '1080', '1440', '2160', '3840', '4320'],
-    videoCodec: ['copy', 'h264', 'h265'],
-    audioCodec: ['copy', 'aac', 'mp3'],
+    videoCodec: ['copy', 'h264', 'h265', 'av1', 'vp9'],
+    audioCodec: ['copy', 'aac', 'mp3', 'opus'],
+    container: ['', 'mp4', 'mkv', 'mov', 'webm', 'ts', 'm4a', 'mp3'],
+    audioBitrate: ['', '64k', '96k', '128k', '160k', '192k', '256k', '320k'],
+    sampleRate: [0, 22050, 32000, 44100, 48000],
+    audioChannels: [
+        { label: '与源音频相同', value: '' },
+        { label: '单声道', value: 'mono' },
+        { label: '立体声（多声道混缩）', value: 'stereo' },
+        { label: '5.1声道', value: '5.1' },
+    ],
+    audioTracks: [
+        { label: '只保留一个音轨', value: '' },
+        { label: '保留所有音轨', value: 'all' },
+        { label: '按语言选择', value: 'language' },
+    ],
+    languages: [
+        { label: '中文', value: 'chi' },
+        { label: '英语', value: 'eng' },
+        { label: '日语', value: 'jpn' },
+        { label: '韩语', value: 'kor' },
+        { label: '法语', value: 'fre' },
+        { label: '德语', value: 'ger' },
+        { label: '西班牙语', value: 'spa' },
+        { label: '俄语', value: 'rus' },
+    ],
+    subtitleSource: [
+        { label: '不烧录', value: '' },
+        { label: '选择字幕文件', value: 'file' },
+        { label: '同名字幕文件', value: 'sidecar' },
+        { label: '内嵌字幕', value: 'stream' },
+    ],
+    subtitlePosition: [
+        { label: '底部', value: '' },
+        { label: '中间', value: 'middle' },
+        { label: '顶部', value: 'top' },
+    ],
+    subtitleFormat: [
+        { label: '不导出', value: '' },
+        { label: 'SRT', value: 'srt' },
+        { label: 'ASS', value: 'ass' },
+        { label: 'WebVTT', value: 'vtt' },
+    ],
     fps: ['copy', '23.976', '24', '25', '29', '30', '60'],
     rotate: ['copy', '90', '180', '270'],
     videoBitrate: ['copy', '262144', '524288', '786432', '1048576', '1572864', '2097152', '3145728', '4194304', '5242880', '7340032', '10485760', '20971520', '41943040', ' 52428800'],
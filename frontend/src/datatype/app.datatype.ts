@@ -1,9 +1,24 @@
 export interface AppData {
     outputDirectory: string;
     cpuThread: number;
-    gpu: boolean;
+    maxParallelJobs: number;
+    outputCollisionPolicy: outputCollisionPolicy;
+    outputNameTemplate: string;
+    import: importOptions;
+    defaultPreset: string;
+    hardwareBackend: hardwareBackend;
 }
 
+export interface importOptions {
+    includePatterns: string[];
+    excludePatterns: string[];
+    followSymlinks: boolean;
+}
+
+export type hardwareBackend = '' | 'nvenc' | 'qsv' | 'amf' | 'vaapi' | 'videotoolbox';
+
+export type outputCollisionPolicy = 'skip' | 'overwrite' | 'rename' | 'fail';
+
 export interface videoInfo {
     id: string;
     name: string,
@@ -19,11 +34,30 @@ export interface videoInfo {
     audio_codec: string,
     video_bitrate: number,
     audio_bitrate: number,
+    frame_rate: string,
+    sar: string,
+    pix_fmt: string,
+    sample_rate: number,
+    channels: number,
+    base_dir: string,
+    streams: streamInfo[] | null,
+}
 
+export interface streamInfo {
+    index: number;
+    type: 'video' | 'audio' | 'subtitle' | 'attachment' | 'data';
+    codec: string;
+    language: string;
+    title: string;
+    default: boolean;
+    forced: boolean;
+    attached_pic: boolean;
+    channels?: number;
 }
 
 export interface videoInfoHasParams extends videoInfo {
     outputSetParams: null | videoParams,
+    trim: null | trimRange,
     transcodeVideoInfo: null | videoInfo,
     progress: number,
 }
@@ -40,4 +74,225 @@ export interface videoParams {
     rotate: string;
     use_gpu: boolean;
     cpu_threads: number;
+    preset: string;
+    rate_control: '' | 'crf' | 'vbr' | 'cbr' | 'abr';
+    quality: null | number;
+    max_rate: string;
+    buf_size: string;
+    encoder_preset: string;
+    tune: string;
+    profile: string;
+    level: string;
+    pix_fmt: string;
+    gop: number;
+    two_pass: boolean;
+    target_size_mb: number;
+    container: '' | 'mp4' | 'mkv' | 'mov' | 'webm' | 'ts' | 'm4a' | 'mp3';
+    trim: trimRange;
+    segment: segmentOptions;
+    loudness: loudnessOptions;
+    audio_bitrate: string;
+    sample_rate: number;
+    audio_channels: '' | 'mono' | 'stereo' | '5.1';
+    mute_audio: boolean;
+    streams: streamMapping;
+    burn_subtitle: burnSubtitleOptions;
+    soft_subtitles: softSubtitleOptions;
+}
+
+export interface trimRange {
+    start: string;
+    end: string;
+    duration: string;
+    mode: '' | 'fast' | 'accurate' | 'smart';
+}
+
+export interface segmentOptions {
+    mode: '' | 'duration' | 'size' | 'scene';
+    duration: string;
+    size_mb: number;
+    scene_threshold: number;
+    pattern: string;
+}
+
+export interface streamMapping {
+    audio_tracks: '' | 'all' | 'language';
+    languages: string[] | null;
+    default_language: string;
+    subtitles: boolean;
+    attachments: boolean;
+}
+
+export interface burnSubtitleOptions {
+    source: '' | 'file' | 'sidecar' | 'stream';
+    file: string;
+    stream_index: number;
+    font: string;
+    font_size: number;
+    color: string;
+    outline_color: string;
+    outline: number;
+    bold: boolean;
+    italic: boolean;
+    position: '' | 'middle' | 'top';
+    margin: number;
+}
+
+export type subtitleFormat = '' | 'srt' | 'ass' | 'vtt';
+
+export interface subtitleTrack {
+    file: string;
+    language: string;
+    title: string;
+    default: boolean;
+}
+
+export interface softSubtitleOptions {
+    sidecars: boolean;
+    tracks: subtitleTrack[];
+    extract: subtitleFormat;
+}
+
+export interface subtitleJobOptions {
+    format: subtitleFormat;
+}
+
+export interface subtitleOutput {
+    path: string;
+    format: subtitleFormat;
+    language: string;
+    title: string;
+    source: string;
+    stream_index: number;
+}
+
+export interface loudnessOptions {
+    enabled: boolean;
+    integrated: number;
+    true_peak: number;
+    lra: number;
+}
+
+export interface transcodePreset {
+    name: string;
+    description: string;
+    params: videoParams;
+    builtIn: boolean;
+}
+
+export interface transcodeJob {
+    id: string;
+    type: transcodeJobType;
+    path: string;
+    params: videoParams;
+    index: number;
+    base_dir: string;
+    trim: null | trimRange;
+    merge?: mergeOptions;
+    subtitle?: subtitleJobOptions;
+}
+
+export type transcodeJobType = '' | 'merge' | 'subtitle';
+
+export interface mergeOptions {
+    inputs: string[];
+    crossfade: number;
+    transition: string;
+}
+
+export interface transcodeJobEvent {
+    id: string;
+    path: string;
+    status: transcodeJobStatus;
+    message: string;
+    result: null | transcodeResult;
+}
+
+export type transcodeJobStatus = 'pending' | 'running' | 'completed' | 'failed' | 'paused' | 'cancelled' | 'skipped';
+
+export interface jobRecord {
+    id: string;
+    type?: transcodeJobType;
+    path: string;
+    base_dir: string;
+    index: number;
+    params: videoParams;
+    trim?: trimRange;
+    merge?: mergeOptions;
+    subtitle?: subtitleJobOptions;
+    status: transcodeJobStatus;
+    attempts: number;
+    submitted_at: string;
+    started_at: string;
+    finished_at: string;
+    result: null | transcodeResult;
+}
+
+export interface jobHistoryFilter {
+    status: transcodeJobStatus[];
+    query: string;
+    interrupted: boolean;
+    since: number;
+    limit: number;
+}
+
+export interface transcodeBatchStatus {
+    total: number;
+    pending: number;
+    running: number;
+    completed: number;
+    failed: number;
+    cancelled: number;
+    skipped: number;
+    paused: boolean;
+    finished: boolean;
+}
+
+export interface transcodeResult {
+    id: string;
+    status: 'success' | 'failed' | 'cancelled' | 'skipped';
+    input_path: string;
+    input_paths?: string[];
+    output_path: string;
+    elapsed_seconds: number;
+    input_info: null | videoInfo;
+    output_info: null | videoInfo;
+    size_ratio: number;
+    segments?: transcodeResult[];
+    subtitles?: subtitleOutput[];
+    args: string[];
+    video_encoder: string;
+    audio_encoder: string;
+    passes: number;
+    target_video_bitrate: number;
+    warnings: string[];
+    exit_code: number;
+    error_kind: '' | 'ffmpeg_unavailable' | 'missing_encoder' | 'bad_input' | 'disk_full' | 'permission_denied' | 'output_exists' | 'cancelled' | 'interrupted' | 'invalid_params' | 'unknown';
+    error: string;
+    stderr_tail: string[];
+}
+
+export interface ffmpegCapabilities {
+    version: string;
+    encoders: string[];
+    decoders: string[];
+    filters: string[];
+    hwaccels: string[];
+}
+
+export interface transcodeProgress {
+    id: string;
+    percentage: number;
+    out_time: string;
+    out_time_seconds: number;
+    duration: number;
+    frame: number;
+    fps: number;
+    speed: number;
+    bitrate: string;
+    total_size: number;
+    eta_seconds: number;
+    pass: number;
+    passes: number;
+    completed: boolean;
 }
\ No newline at end of file
@@ -1,5 +1,6 @@
-import { videoInfo, videoParams } from "@/datatype/app.datatype";
-import { AppData, OpenOutputDirectory, Transcode, OpenTranscodeVideo } from "../../wailsjs/go/process/App";
+import { ffmpegCapabilities, hardwareBackend, jobHistoryFilter, jobRecord, outputCollisionPolicy, transcodeBatchStatus, transcodeJob, transcodeJobEvent, transcodePreset, transcodeProgress, transcodeResult, videoInfo, videoParams } from "@/datatype/app.datatype";
+import { AppData, OpenOutputDirectory, Transcode, TranscodeBatch, SetMaxParallelJobs, SetOutputCollisionPolicy, SetOutputNameTemplate, SetHardwareBackend, HardwareBackends, OpenTranscodeVideo, CancelTranscode, CancelAllTranscodes, PauseTranscode, PauseAllTranscodes, ResumeTranscode, ResumeAllTranscodes, ListTranscodePresets, CreateTranscodePreset, UpdateTranscodePreset, DeleteTranscodePreset, SetDefaultTranscodePreset, ListJobHistory, InterruptedJobs, RequeueJobs, ClearJobHistory, FFmpegCapabilities, RefreshFFmpegCapabilities } from "../../wailsjs/go/process/App";
+import { process } from "../../wailsjs/go/models";
 import { EventsOn } from "../../wailsjs/runtime";
 
 export const EventsOn_Loading = (callback: (isLoading: boolean) => void) => {
@@ -20,14 +21,106 @@ export const openTranscodeVideo = async (path: string) => {
     await OpenTranscodeVideo(path);
 };
 
-export const transcode = async (id: string, path: string, params: videoParams): Promise<string> => {
+export const transcode = async (id: string, path: string, params: videoParams): Promise<transcodeResult> => {
     return await Transcode(id, path, params);
 };
 
-export const EventsOn_videoTranscodeProcessor = (callback: (arg0: string, arg1: number, arg2: string | 'completed') => void) => {
+export const transcodeBatch = async (jobs: transcodeJob[]) => {
+    await TranscodeBatch(jobs.map(job => process.TranscodeJob.createFrom(job)));
+};
+
+export const cancelTranscode = async (id: string) => {
+    await CancelTranscode(id);
+};
+
+export const cancelAllTranscodes = async () => {
+    await CancelAllTranscodes();
+};
+
+export const pauseTranscode = async (id: string) => {
+    await PauseTranscode(id);
+};
+
+export const pauseAllTranscodes = async () => {
+    await PauseAllTranscodes();
+};
+
+export const resumeTranscode = async (id: string) => {
+    await ResumeTranscode(id);
+};
+
+export const resumeAllTranscodes = async () => {
+    await ResumeAllTranscodes();
+};
+
+export const setMaxParallelJobs = async (count: number): Promise<number> => {
+    return await SetMaxParallelJobs(count);
+};
+
+export const setOutputCollisionPolicy = async (policy: outputCollisionPolicy) => {
+    await SetOutputCollisionPolicy(policy);
+};
+
+export const setOutputNameTemplate = async (template: string) => {
+    await SetOutputNameTemplate(template);
+};
+
+export const getHardwareBackends = async (): Promise<hardwareBackend[]> => {
+    return await HardwareBackends() as hardwareBackend[];
+};
+
+export const setHardwareBackend = async (backend: hardwareBackend) => {
+    await SetHardwareBackend(backend);
+};
+
+export const listTranscodePresets = async (): Promise<transcodePreset[]> => {
+    return await ListTranscodePresets();
+};
+
+export const createTranscodePreset = async (preset: transcodePreset) => {
+    await CreateTranscodePreset(process.TranscodePreset.createFrom(preset));
+};
+
+export const updateTranscodePreset = async (name: string, preset: transcodePreset) => {
+    await UpdateTranscodePreset(name, process.TranscodePreset.createFrom(preset));
+};
+
+export const deleteTranscodePreset = async (name: string) => {
+    await DeleteTranscodePreset(name);
+};
+
+export const setDefaultTranscodePreset = async (name: string) => {
+    await SetDefaultTranscodePreset(name);
+};
+
+export const listJobHistory = async (filter: jobHistoryFilter): Promise<jobRecord[]> => {
+    return await ListJobHistory(process.JobHistoryFilter.createFrom(filter)) as unknown as jobRecord[];
+};
+
+export const interruptedJobs = async (): Promise<jobRecord[]> => {
+    return await InterruptedJobs() as unknown as jobRecord[];
+};
+
+export const requeueJobs = async (ids: string[]): Promise<videoInfo[]> => {
+    return await RequeueJobs(ids);
+};
+
+export const clearJobHistory = async () => {
+    await ClearJobHistory();
+};
+
+export const getFFmpegCapabilities = async (): Promise<ffmpegCapabilities> => {
+    return await FFmpegCapabilities();
+};
+
+export const refreshFFmpegCapabilities = async (): Promise<ffmpegCapabilities> => {
+    return await RefreshFFmpegCapabilities();
+};
+
+export const EventsOn_videoTranscodeProcessor = (callback: (arg0: transcodeProgress) => void) => {
     // 监听视频转码进度
-    EventsOn("videoTranscodeProcessor", (id: string, process: number, currentTime: string | 'completed') => {
-        callback(id, process, currentTime)
+    EventsOn("videoTranscodeProcessor", (progress: transcodeProgress) => {
+        callback(progress)
     });
 
 };
@@ -36,4 +129,16 @@ export const EventsOn_videoTranscodeSuccess = (callback: (arg0: videoInfo) => vo
     EventsOn("videoTranscodeSuccess", (transcodeVideoInfo: videoInfo) => {
         callback(transcodeVideoInfo)
     });
+};
+export const EventsOn_videoTranscodeJobStatus = (callback: (arg0: transcodeJobEvent) => void) => {
+    // 监听队列中单个任务的状态
+    EventsOn("videoTranscodeJobStatus", (jobEvent: transcodeJobEvent) => {
+        callback(jobEvent)
+    });
+};
+export const EventsOn_videoTranscodeBatchStatus = (callback: (arg0: transcodeBatchStatus) => void) => {
+    // 监听整个批次的状态
+    EventsOn("videoTranscodeBatchStatus", (batchStatus: transcodeBatchStatus) => {
+        callback(batchStatus)
+    });
 };
\ No newline at end of file
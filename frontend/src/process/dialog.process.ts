@@ -1,10 +1,19 @@
-import { videoInfo } from "@/datatype/app.datatype";
-import { OpenMultipleVideoFilesDialog, OpenDirectoryDialogSetOutput, OpenWatermarkImageDialog } from "../../wailsjs/go/process/App";
+import { importOptions, videoInfo } from "@/datatype/app.datatype";
+import { OpenMultipleVideoFilesDialog, OpenVideoDirectoryDialog, OpenDirectoryDialogSetOutput, OpenWatermarkImageDialog, OpenSubtitleFileDialog, SetImportOptions } from "../../wailsjs/go/process/App";
+import { process } from "../../wailsjs/go/models";
 import { EventsOn } from "../../wailsjs/runtime";
 export const openVideoDialog = async () => {
     return await OpenMultipleVideoFilesDialog();
 };
 
+export const openVideoDirectoryDialog = async () => {
+    return await OpenVideoDirectoryDialog();
+};
+
+export const setImportOptions = async (options: importOptions) => {
+    await SetImportOptions(process.ImportOptions.createFrom(options));
+};
+
 export const EventsOn_filesSelectedMultipleVideoFiles = (callback: (arg0: videoInfo[]) => void) => {
     // 监听文件选择事件
     EventsOn("filesSelectedMultipleVideoFilesSuccess", (videoInfoSlc: videoInfo[]) => {
@@ -37,4 +46,14 @@ export const EventsOn_watermarkImageDialog = (callback: (arg0: string) => void)
     EventsOn("fileSelectedWatermarkImageSuccess", (watermarkImagePath: string) => {
         callback(watermarkImagePath)
     });
+}
+
+export const openSubtitleFileDialog = async () => {
+    return await OpenSubtitleFileDialog();
+};
+export const EventsOn_subtitleFileDialog = (callback: (arg0: string) => void) => {
+    // 监听选择事件
+    EventsOn("fileSelectedSubtitleSuccess", (subtitlePath: string) => {
+        callback(subtitlePath)
+    });
 }
\ No newline at end of file
@@ -3,7 +3,12 @@ export namespace process {
 	export class AppData {
 	    outputDirectory: string;
 	    cpuThread: number;
-	    gpu: boolean;
+	    maxParallelJobs: number;
+	    outputCollisionPolicy: string;
+	    outputNameTemplate: string;
+	    import: ImportOptions;
+	    defaultPreset: string;
+	    hardwareBackend: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new AppData(source);
@@ -13,9 +18,414 @@ export namespace process {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.outputDirectory = source["outputDirectory"];
 	        this.cpuThread = source["cpuThread"];
-	        this.gpu = source["gpu"];
+	        this.maxParallelJobs = source["maxParallelJobs"];
+	        this.outputCollisionPolicy = source["outputCollisionPolicy"];
+	        this.outputNameTemplate = source["outputNameTemplate"];
+	        this.import = this.convertValues(source["import"], ImportOptions);
+	        this.defaultPreset = source["defaultPreset"];
+	        this.hardwareBackend = source["hardwareBackend"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class BurnSubtitleOptions {
+	    source: string;
+	    file: string;
+	    stream_index: number;
+	    font: string;
+	    font_size: number;
+	    color: string;
+	    outline_color: string;
+	    outline: number;
+	    bold: boolean;
+	    italic: boolean;
+	    position: string;
+	    margin: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new BurnSubtitleOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.source = source["source"];
+	        this.file = source["file"];
+	        this.stream_index = source["stream_index"];
+	        this.font = source["font"];
+	        this.font_size = source["font_size"];
+	        this.color = source["color"];
+	        this.outline_color = source["outline_color"];
+	        this.outline = source["outline"];
+	        this.bold = source["bold"];
+	        this.italic = source["italic"];
+	        this.position = source["position"];
+	        this.margin = source["margin"];
+	    }
+	}
+	export class FFmpegCapabilities {
+	    version: string;
+	    encoders: string[];
+	    decoders: string[];
+	    filters: string[];
+	    hwaccels: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new FFmpegCapabilities(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.version = source["version"];
+	        this.encoders = source["encoders"];
+	        this.decoders = source["decoders"];
+	        this.filters = source["filters"];
+	        this.hwaccels = source["hwaccels"];
+	    }
+	}
+	export class ImportOptions {
+	    includePatterns: string[];
+	    excludePatterns: string[];
+	    followSymlinks: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.includePatterns = source["includePatterns"];
+	        this.excludePatterns = source["excludePatterns"];
+	        this.followSymlinks = source["followSymlinks"];
+	    }
+	}
+	export class JobHistoryFilter {
+	    status: string[];
+	    query: string;
+	    interrupted: boolean;
+	    since: number;
+	    limit: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new JobHistoryFilter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.status = source["status"];
+	        this.query = source["query"];
+	        this.interrupted = source["interrupted"];
+	        this.since = source["since"];
+	        this.limit = source["limit"];
+	    }
+	}
+	export class JobRecord {
+	    id: string;
+	    type?: string;
+	    path: string;
+	    base_dir: string;
+	    index: number;
+	    params: TranscodeParams;
+	    trim?: TrimRange;
+	    merge?: MergeOptions;
+	    subtitle?: SubtitleJobOptions;
+	    status: string;
+	    attempts: number;
+	    submitted_at: any;
+	    started_at: any;
+	    finished_at: any;
+	    result?: TranscodeResult;
+	
+	    static createFrom(source: any = {}) {
+	        return new JobRecord(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.type = source["type"];
+	        this.path = source["path"];
+	        this.base_dir = source["base_dir"];
+	        this.index = source["index"];
+	        this.params = this.convertValues(source["params"], TranscodeParams);
+	        this.trim = this.convertValues(source["trim"], TrimRange);
+	        this.merge = this.convertValues(source["merge"], MergeOptions);
+	        this.subtitle = this.convertValues(source["subtitle"], SubtitleJobOptions);
+	        this.status = source["status"];
+	        this.attempts = source["attempts"];
+	        this.submitted_at = this.convertValues(source["submitted_at"], null);
+	        this.started_at = this.convertValues(source["started_at"], null);
+	        this.finished_at = this.convertValues(source["finished_at"], null);
+	        this.result = this.convertValues(source["result"], TranscodeResult);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class LoudnessOptions {
+	    enabled: boolean;
+	    integrated: number;
+	    true_peak: number;
+	    lra: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new LoudnessOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.integrated = source["integrated"];
+	        this.true_peak = source["true_peak"];
+	        this.lra = source["lra"];
+	    }
+	}
+	export class MergeOptions {
+	    inputs: string[];
+	    crossfade: number;
+	    transition: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new MergeOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.inputs = source["inputs"];
+	        this.crossfade = source["crossfade"];
+	        this.transition = source["transition"];
+	    }
+	}
+	export class SegmentOptions {
+	    mode: string;
+	    duration: string;
+	    size_mb: number;
+	    scene_threshold: number;
+	    pattern: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SegmentOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mode = source["mode"];
+	        this.duration = source["duration"];
+	        this.size_mb = source["size_mb"];
+	        this.scene_threshold = source["scene_threshold"];
+	        this.pattern = source["pattern"];
+	    }
+	}
+	export class SoftSubtitleOptions {
+	    sidecars: boolean;
+	    tracks: SubtitleTrack[];
+	    extract: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SoftSubtitleOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.sidecars = source["sidecars"];
+	        this.tracks = this.convertValues(source["tracks"], SubtitleTrack);
+	        this.extract = source["extract"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class StreamInfo {
+	    index: number;
+	    type: string;
+	    codec: string;
+	    language: string;
+	    title: string;
+	    default: boolean;
+	    forced: boolean;
+	    attached_pic: boolean;
+	    channels?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new StreamInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.index = source["index"];
+	        this.type = source["type"];
+	        this.codec = source["codec"];
+	        this.language = source["language"];
+	        this.title = source["title"];
+	        this.default = source["default"];
+	        this.forced = source["forced"];
+	        this.attached_pic = source["attached_pic"];
+	        this.channels = source["channels"];
 	    }
 	}
+	export class StreamMapping {
+	    audio_tracks: string;
+	    languages: string[];
+	    default_language: string;
+	    subtitles: boolean;
+	    attachments: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new StreamMapping(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.audio_tracks = source["audio_tracks"];
+	        this.languages = source["languages"];
+	        this.default_language = source["default_language"];
+	        this.subtitles = source["subtitles"];
+	        this.attachments = source["attachments"];
+	    }
+	}
+	export class SubtitleJobOptions {
+	    format: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SubtitleJobOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.format = source["format"];
+	    }
+	}
+	export class SubtitleOutput {
+	    path: string;
+	    format: string;
+	    language: string;
+	    title: string;
+	    source: string;
+	    stream_index: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SubtitleOutput(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.format = source["format"];
+	        this.language = source["language"];
+	        this.title = source["title"];
+	        this.source = source["source"];
+	        this.stream_index = source["stream_index"];
+	    }
+	}
+	export class SubtitleTrack {
+	    file: string;
+	    language: string;
+	    title: string;
+	    default: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SubtitleTrack(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.file = source["file"];
+	        this.language = source["language"];
+	        this.title = source["title"];
+	        this.default = source["default"];
+	    }
+	}
+	export class TranscodeJob {
+	    id: string;
+	    type: string;
+	    path: string;
+	    params: TranscodeParams;
+	    index: number;
+	    base_dir: string;
+	    trim?: TrimRange;
+	    merge?: MergeOptions;
+	    subtitle?: SubtitleJobOptions;
+	
+	    static createFrom(source: any = {}) {
+	        return new TranscodeJob(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.type = source["type"];
+	        this.path = source["path"];
+	        this.params = this.convertValues(source["params"], TranscodeParams);
+	        this.index = source["index"];
+	        this.base_dir = source["base_dir"];
+	        this.trim = this.convertValues(source["trim"], TrimRange);
+	        this.merge = this.convertValues(source["merge"], MergeOptions);
+	        this.subtitle = this.convertValues(source["subtitle"], SubtitleJobOptions);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class TranscodeParams {
 	    video_codec: string;
 	    audio_codec: string;
@@ -28,6 +438,30 @@ export namespace process {
 	    rotate: string;
 	    use_gpu: boolean;
 	    cpu_threads: number;
+	    rate_control: string;
+	    quality?: number;
+	    max_rate: string;
+	    buf_size: string;
+	    encoder_preset: string;
+	    tune: string;
+	    profile: string;
+	    level: string;
+	    pix_fmt: string;
+	    gop: number;
+	    two_pass: boolean;
+	    target_size_mb: number;
+	    container: string;
+	    trim: TrimRange;
+	    segment: SegmentOptions;
+	    loudness: LoudnessOptions;
+	    audio_bitrate: string;
+	    sample_rate: number;
+	    audio_channels: string;
+	    mute_audio: boolean;
+	    streams: StreamMapping;
+	    burn_subtitle: BurnSubtitleOptions;
+	    soft_subtitles: SoftSubtitleOptions;
+	    preset: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new TranscodeParams(source);
@@ -46,7 +480,243 @@ export namespace process {
 	        this.rotate = source["rotate"];
 	        this.use_gpu = source["use_gpu"];
 	        this.cpu_threads = source["cpu_threads"];
+	        this.rate_control = source["rate_control"];
+	        this.quality = source["quality"];
+	        this.max_rate = source["max_rate"];
+	        this.buf_size = source["buf_size"];
+	        this.encoder_preset = source["encoder_preset"];
+	        this.tune = source["tune"];
+	        this.profile = source["profile"];
+	        this.level = source["level"];
+	        this.pix_fmt = source["pix_fmt"];
+	        this.gop = source["gop"];
+	        this.two_pass = source["two_pass"];
+	        this.target_size_mb = source["target_size_mb"];
+	        this.container = source["container"];
+	        this.trim = this.convertValues(source["trim"], TrimRange);
+	        this.segment = this.convertValues(source["segment"], SegmentOptions);
+	        this.loudness = this.convertValues(source["loudness"], LoudnessOptions);
+	        this.audio_bitrate = source["audio_bitrate"];
+	        this.sample_rate = source["sample_rate"];
+	        this.audio_channels = source["audio_channels"];
+	        this.mute_audio = source["mute_audio"];
+	        this.streams = this.convertValues(source["streams"], StreamMapping);
+	        this.burn_subtitle = this.convertValues(source["burn_subtitle"], BurnSubtitleOptions);
+	        this.soft_subtitles = this.convertValues(source["soft_subtitles"], SoftSubtitleOptions);
+	        this.preset = source["preset"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TranscodePreset {
+	    name: string;
+	    description: string;
+	    params: TranscodeParams;
+	    builtIn: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new TranscodePreset(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.params = this.convertValues(source["params"], TranscodeParams);
+	        this.builtIn = source["builtIn"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TranscodeResult {
+	    id: string;
+	    status: string;
+	    input_path: string;
+	    input_paths?: string[];
+	    output_path: string;
+	    elapsed_seconds: number;
+	    input_info?: VideoInfo;
+	    output_info?: VideoInfo;
+	    size_ratio: number;
+	    segments?: TranscodeResult[];
+	    subtitles?: SubtitleOutput[];
+	    args: string[];
+	    video_encoder: string;
+	    audio_encoder: string;
+	    passes: number;
+	    target_video_bitrate: number;
+	    warnings: string[];
+	    exit_code: number;
+	    error_kind: string;
+	    error: string;
+	    stderr_tail: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new TranscodeResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.status = source["status"];
+	        this.input_path = source["input_path"];
+	        this.input_paths = source["input_paths"];
+	        this.output_path = source["output_path"];
+	        this.elapsed_seconds = source["elapsed_seconds"];
+	        this.input_info = this.convertValues(source["input_info"], VideoInfo);
+	        this.output_info = this.convertValues(source["output_info"], VideoInfo);
+	        this.size_ratio = source["size_ratio"];
+	        this.segments = this.convertValues(source["segments"], TranscodeResult);
+	        this.subtitles = this.convertValues(source["subtitles"], SubtitleOutput);
+	        this.args = source["args"];
+	        this.video_encoder = source["video_encoder"];
+	        this.audio_encoder = source["audio_encoder"];
+	        this.passes = source["passes"];
+	        this.target_video_bitrate = source["target_video_bitrate"];
+	        this.warnings = source["warnings"];
+	        this.exit_code = source["exit_code"];
+	        this.error_kind = source["error_kind"];
+	        this.error = source["error"];
+	        this.stderr_tail = source["stderr_tail"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TrimRange {
+	    start: string;
+	    end: string;
+	    duration: string;
+	    mode: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TrimRange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.start = source["start"];
+	        this.end = source["end"];
+	        this.duration = source["duration"];
+	        this.mode = source["mode"];
+	    }
+	}
+	export class VideoInfo {
+	    id: string;
+	    name: string;
+	    path: string;
+	    thumbnail: string;
+	    size: number;
+	    duration: number;
+	    bitrate: number;
+	    width: number;
+	    height: number;
+	    fps: number;
+	    audio_codec: string;
+	    video_codec: string;
+	    video_bitrate: number;
+	    audio_bitrate: number;
+	    base_dir: string;
+	    frame_rate: string;
+	    sar: string;
+	    pix_fmt: string;
+	    sample_rate: number;
+	    channels: number;
+	    streams: StreamInfo[];
+	
+	    static createFrom(source: any = {}) {
+	        return new VideoInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.path = source["path"];
+	        this.thumbnail = source["thumbnail"];
+	        this.size = source["size"];
+	        this.duration = source["duration"];
+	        this.bitrate = source["bitrate"];
+	        this.width = source["width"];
+	        this.height = source["height"];
+	        this.fps = source["fps"];
+	        this.audio_codec = source["audio_codec"];
+	        this.video_codec = source["video_codec"];
+	        this.video_bitrate = source["video_bitrate"];
+	        this.audio_bitrate = source["audio_bitrate"];
+	        this.base_dir = source["base_dir"];
+	        this.frame_rate = source["frame_rate"];
+	        this.sar = source["sar"];
+	        this.pix_fmt = source["pix_fmt"];
+	        this.sample_rate = source["sample_rate"];
+	        this.channels = source["channels"];
+	        this.streams = this.convertValues(source["streams"], StreamInfo);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 
 }
@@ -4,14 +4,66 @@ import {process} from '../models';
 
 export function AppData():Promise<process.AppData>;
 
+export function CancelAllTranscodes():Promise<void>;
+
+export function CancelTranscode(arg1:string):Promise<void>;
+
+export function ClearJobHistory():Promise<void>;
+
+export function CreateTranscodePreset(arg1:process.TranscodePreset):Promise<void>;
+
+export function DeleteTranscodePreset(arg1:string):Promise<void>;
+
+export function FFmpegCapabilities():Promise<process.FFmpegCapabilities>;
+
+export function HardwareBackends():Promise<Array<string>>;
+
+export function InterruptedJobs():Promise<Array<process.JobRecord>>;
+
+export function ListJobHistory(arg1:process.JobHistoryFilter):Promise<Array<process.JobRecord>>;
+
+export function ListTranscodePresets():Promise<Array<process.TranscodePreset>>;
+
 export function OpenDirectoryDialogSetOutput():Promise<void>;
 
 export function OpenMultipleVideoFilesDialog():Promise<void>;
 
 export function OpenOutputDirectory():Promise<void>;
 
+export function OpenSubtitleFileDialog():Promise<void>;
+
 export function OpenTranscodeVideo(arg1:string):Promise<void>;
 
+export function OpenVideoDirectoryDialog():Promise<void>;
+
 export function OpenWatermarkImageDialog():Promise<void>;
 
-export function Transcode(arg1:string,arg2:string,arg3:process.TranscodeParams):Promise<string>;
+export function PauseAllTranscodes():Promise<void>;
+
+export function PauseTranscode(arg1:string):Promise<void>;
+
+export function RefreshFFmpegCapabilities():Promise<process.FFmpegCapabilities>;
+
+export function RequeueJobs(arg1:Array<string>):Promise<Array<process.VideoInfo>>;
+
+export function ResumeAllTranscodes():Promise<void>;
+
+export function ResumeTranscode(arg1:string):Promise<void>;
+
+export function SetDefaultTranscodePreset(arg1:string):Promise<void>;
+
+export function SetHardwareBackend(arg1:string):Promise<void>;
+
+export function SetImportOptions(arg1:process.ImportOptions):Promise<void>;
+
+export function SetMaxParallelJobs(arg1:number):Promise<number>;
+
+export function SetOutputCollisionPolicy(arg1:string):Promise<void>;
+
+export function SetOutputNameTemplate(arg1:string):Promise<void>;
+
+export function Transcode(arg1:string,arg2:string,arg3:process.TranscodeParams):Promise<process.TranscodeResult>;
+
+export function TranscodeBatch(arg1:Array<process.TranscodeJob>):Promise<void>;
+
+export function UpdateTranscodePreset(arg1:string,arg2:process.TranscodePreset):Promise<void>;
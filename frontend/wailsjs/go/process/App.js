@@ -6,6 +6,46 @@ export function AppData() {
   return window['go']['process']['App']['AppData']();
 }
 
+export function CancelAllTranscodes() {
+  return window['go']['process']['App']['CancelAllTranscodes']();
+}
+
+export function CancelTranscode(arg1) {
+  return window['go']['process']['App']['CancelTranscode'](arg1);
+}
+
+export function ClearJobHistory() {
+  return window['go']['process']['App']['ClearJobHistory']();
+}
+
+export function CreateTranscodePreset(arg1) {
+  return window['go']['process']['App']['CreateTranscodePreset'](arg1);
+}
+
+export function DeleteTranscodePreset(arg1) {
+  return window['go']['process']['App']['DeleteTranscodePreset'](arg1);
+}
+
+export function FFmpegCapabilities() {
+  return window['go']['process']['App']['FFmpegCapabilities']();
+}
+
+export function HardwareBackends() {
+  return window['go']['process']['App']['HardwareBackends']();
+}
+
+export function InterruptedJobs() {
+  return window['go']['process']['App']['InterruptedJobs']();
+}
+
+export function ListJobHistory(arg1) {
+  return window['go']['process']['App']['ListJobHistory'](arg1);
+}
+
+export function ListTranscodePresets() {
+  return window['go']['process']['App']['ListTranscodePresets']();
+}
+
 export function OpenDirectoryDialogSetOutput() {
   return window['go']['process']['App']['OpenDirectoryDialogSetOutput']();
 }
@@ -18,14 +58,78 @@ export function OpenOutputDirectory() {
   return window['go']['process']['App']['OpenOutputDirectory']();
 }
 
+export function OpenSubtitleFileDialog() {
+  return window['go']['process']['App']['OpenSubtitleFileDialog']();
+}
+
 export function OpenTranscodeVideo(arg1) {
   return window['go']['process']['App']['OpenTranscodeVideo'](arg1);
 }
 
+export function OpenVideoDirectoryDialog() {
+  return window['go']['process']['App']['OpenVideoDirectoryDialog']();
+}
+
 export function OpenWatermarkImageDialog() {
   return window['go']['process']['App']['OpenWatermarkImageDialog']();
 }
 
+export function PauseAllTranscodes() {
+  return window['go']['process']['App']['PauseAllTranscodes']();
+}
+
+export function PauseTranscode(arg1) {
+  return window['go']['process']['App']['PauseTranscode'](arg1);
+}
+
+export function RefreshFFmpegCapabilities() {
+  return window['go']['process']['App']['RefreshFFmpegCapabilities']();
+}
+
+export function RequeueJobs(arg1) {
+  return window['go']['process']['App']['RequeueJobs'](arg1);
+}
+
+export function ResumeAllTranscodes() {
+  return window['go']['process']['App']['ResumeAllTranscodes']();
+}
+
+export function ResumeTranscode(arg1) {
+  return window['go']['process']['App']['ResumeTranscode'](arg1);
+}
+
+export function SetDefaultTranscodePreset(arg1) {
+  return window['go']['process']['App']['SetDefaultTranscodePreset'](arg1);
+}
+
+export function SetHardwareBackend(arg1) {
+  return window['go']['process']['App']['SetHardwareBackend'](arg1);
+}
+
+export function SetImportOptions(arg1) {
+  return window['go']['process']['App']['SetImportOptions'](arg1);
+}
+
+export function SetMaxParallelJobs(arg1) {
+  return window['go']['process']['App']['SetMaxParallelJobs'](arg1);
+}
+
+export function SetOutputCollisionPolicy(arg1) {
+  return window['go']['process']['App']['SetOutputCollisionPolicy'](arg1);
+}
+
+export function SetOutputNameTemplate(arg1) {
+  return window['go']['process']['App']['SetOutputNameTemplate'](arg1);
+}
+
 export function Transcode(arg1, arg2, arg3) {
   return window['go']['process']['App']['Transcode'](arg1, arg2, arg3);
 }
+
+export function TranscodeBatch(arg1) {
+  return window['go']['process']['App']['TranscodeBatch'](arg1);
+}
+
+export function UpdateTranscodePreset(arg1, arg2) {
+  return window['go']['process']['App']['UpdateTranscodePreset'](arg1, arg2);
+}
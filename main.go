@@ -1,8 +1,10 @@
 package main
 
 import (
+	"cm_video_batch_process/process"
 	"embed"
 	"fmt"
+	"os"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -14,6 +16,11 @@ var assets embed.FS
 var version = "v0.1.1"
 
 func main() {
+	// 命令行模式: cm_video_batch_process cli [参数] 文件|通配符|文件夹...
+	if len(os.Args) > 1 && os.Args[1] == "cli" {
+		os.Exit(process.RunCLI(os.Args[2:]))
+	}
+
 	// Create an instance of the app structure
 	app := NewApp()
 
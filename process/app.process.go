@@ -2,24 +2,43 @@ package process
 
 import (
 	"context"
+	"fmt"
+	"log"
 
 	"github.com/skratchdot/open-golang/open"
 )
 
 type App struct {
-	ctx context.Context
+	ctx         context.Context
+	queue       *TranscodeQueue
+	history     *JobHistory
+	interrupted []JobRecord // 启动时发现的上次未完成的任务
 }
 
 type AppData struct {
-	OutputDirectory string `json:"outputDirectory"`
-	CPUThread       int    `json:"cpuThread"`
-	GPU             bool   `json:"gpu"`
+	OutputDirectory       string                `json:"outputDirectory"`
+	CPUThread             int                   `json:"cpuThread"`
+	MaxParallelJobs       int                   `json:"maxParallelJobs"`
+	OutputCollisionPolicy OutputCollisionPolicy `json:"outputCollisionPolicy"`
+	OutputNameTemplate    string                `json:"outputNameTemplate"`
+	Import                ImportOptions         `json:"import"`
+	DefaultPreset         string                `json:"defaultPreset"`
+	HardwareBackend       HardwareBackend       `json:"hardwareBackend"` // 配置中指定的硬件编码器，为空时自动选择
 }
 
 // Startup 应用启动时的初始化逻辑
 func (a *App) Startup(ctx context.Context) {
 	a.ctx = ctx
 	initConf()
+	history, err := OpenJobHistory(jobHistoryFile)
+	if err != nil {
+		log.Printf("%v，本次运行不记录任务历史", err)
+	}
+	a.history = history
+	a.interrupted = history.RecoverInterrupted()
+	a.queue = NewTranscodeQueue(ctx, history)
+	// 探测硬件编码器需要实际编码测试画面，在后台提前探测，结果会被缓存
+	go AvailableHardwareBackends()
 	// 注册拖拽监听事件
 	addDraggedFilesHandle(ctx)
 }
@@ -28,6 +47,7 @@ func (a *App) Startup(ctx context.Context) {
 func (a *App) Shutdown(ctx context.Context) {
 	//移除拖拽监听事件
 	removeDraggedFilesHandle(ctx)
+	a.history.Close()
 }
 
 // BeforeClose 在应用关闭前调用，返回true则阻止关闭，返回false则允许关闭
@@ -38,9 +58,14 @@ func (a *App) BeforeClose(ctx context.Context) bool {
 func (a *App) AppData() AppData {
 	outputDirectory := GetOutputDirectory()
 	return AppData{
-		OutputDirectory: outputDirectory,
-		CPUThread:       GetCPUThreadCount(),
-		GPU:             IsGPUSupported(),
+		OutputDirectory:       outputDirectory,
+		CPUThread:             GetCPUThreadCount(),
+		MaxParallelJobs:       a.queue.MaxParallelJobs(),
+		OutputCollisionPolicy: GetOutputCollisionPolicy(),
+		OutputNameTemplate:    GetOutputNameTemplate(),
+		Import:                GetImportOptions(),
+		DefaultPreset:         GetDefaultPresetName(),
+		HardwareBackend:       GetHardwareBackend(),
 	}
 }
 
@@ -48,10 +73,18 @@ func (a *App) OpenMultipleVideoFilesDialog() {
 	P_Dialog{}.OpenMultipleVideoFilesDialog(a.ctx)
 }
 
+func (a *App) OpenVideoDirectoryDialog() {
+	P_Dialog{}.OpenVideoDirectoryDialog(a.ctx)
+}
+
 func (a *App) OpenWatermarkImageDialog() {
 	P_Dialog{}.OpenWatermarkImageDialog(a.ctx)
 }
 
+func (a *App) OpenSubtitleFileDialog() {
+	P_Dialog{}.OpenSubtitleFileDialog(a.ctx)
+}
+
 func (a *App) OpenOutputDirectory() {
 	outputDirectory := GetOutputDirectory()
 	if !FileExists(outputDirectory) {
@@ -63,8 +96,148 @@ func (a *App) OpenDirectoryDialogSetOutput() {
 	P_Dialog{}.OpenDirectoryDialogSetOutput(a.ctx)
 }
 
-func (a *App) Transcode(id, path string, params TranscodeParams) string {
-	return VideoTranscodeProcessor(a.ctx, id, path, params)
+func (a *App) Transcode(id, path string, params TranscodeParams) TranscodeResult {
+	return VideoTranscodeProcessor(a.ctx, TranscodeJob{ID: id, Path: path, Params: params, Index: 1})
+}
+
+// TranscodeBatch 提交一批转码任务到队列，任务和批次状态通过事件通知前端
+func (a *App) TranscodeBatch(jobs []TranscodeJob) {
+	a.queue.Submit(jobs)
+}
+
+// CancelTranscode 取消单个转码任务，并删除未完成的输出文件
+func (a *App) CancelTranscode(id string) error {
+	return a.queue.Cancel(id)
+}
+
+// CancelAllTranscodes 取消整个批次
+func (a *App) CancelAllTranscodes() {
+	a.queue.CancelAll()
+}
+
+// PauseTranscode 暂停单个正在运行的转码任务
+func (a *App) PauseTranscode(id string) error {
+	return a.queue.Pause(id)
+}
+
+// ResumeTranscode 恢复单个被暂停的转码任务
+func (a *App) ResumeTranscode(id string) error {
+	return a.queue.Resume(id)
+}
+
+// PauseAllTranscodes 暂停整个批次
+func (a *App) PauseAllTranscodes() {
+	a.queue.PauseAll()
+}
+
+// ResumeAllTranscodes 恢复整个批次
+func (a *App) ResumeAllTranscodes() {
+	a.queue.ResumeAll()
+}
+
+// SetMaxParallelJobs 设置同时运行的转码任务数，返回实际生效的值
+func (a *App) SetMaxParallelJobs(count int) int {
+	limit := a.queue.SetMaxParallelJobs(count)
+	SaveConfig()
+	return limit
+}
+
+// SetOutputCollisionPolicy 设置输出文件同名时的处理策略
+func (a *App) SetOutputCollisionPolicy(policy OutputCollisionPolicy) error {
+	if !isValidOutputCollisionPolicy(policy) {
+		return fmt.Errorf("无效的同名文件处理策略: %s", policy)
+	}
+	Config.OutputCollisionPolicy = string(policy)
+	return SaveConfig()
+}
+
+// SetOutputNameTemplate 设置输出文件名模板
+func (a *App) SetOutputNameTemplate(template string) error {
+	if err := ValidateOutputNameTemplate(template); err != nil {
+		return err
+	}
+	Config.OutputNameTemplate = template
+	return SaveConfig()
+}
+
+// SetImportOptions 设置导入文件夹时的过滤选项
+func (a *App) SetImportOptions(options ImportOptions) error {
+	if err := ValidateImportOptions(options); err != nil {
+		return err
+	}
+	Config.Import = options
+	return SaveConfig()
+}
+
+// HardwareBackends 获取当前电脑上可用的硬件编码器，没有可用的硬件编码器时不能使用GPU加速
+//
+// 探测较慢，不放在AppData中，前端加载完成后单独获取，启动时已在后台开始探测
+func (a *App) HardwareBackends() []HardwareBackend {
+	return AvailableHardwareBackends()
+}
+
+// SetHardwareBackend 设置使用GPU时的硬件编码器，为空时自动选择
+func (a *App) SetHardwareBackend(backend HardwareBackend) error {
+	if err := ValidateHardwareBackend(backend); err != nil {
+		return err
+	}
+	Config.HardwareBackend = string(backend)
+	return SaveConfig()
+}
+
+// ListTranscodePresets 获取内置预设和用户预设
+func (a *App) ListTranscodePresets() []TranscodePreset {
+	return ListTranscodePresets()
+}
+
+// CreateTranscodePreset 新建转码参数预设
+func (a *App) CreateTranscodePreset(preset TranscodePreset) error {
+	return CreateTranscodePreset(preset)
+}
+
+// UpdateTranscodePreset 修改或重命名转码参数预设
+func (a *App) UpdateTranscodePreset(name string, preset TranscodePreset) error {
+	return UpdateTranscodePreset(name, preset)
+}
+
+// DeleteTranscodePreset 删除转码参数预设
+func (a *App) DeleteTranscodePreset(name string) error {
+	return DeleteTranscodePreset(name)
+}
+
+// SetDefaultTranscodePreset 设置默认预设，为空时清除
+func (a *App) SetDefaultTranscodePreset(name string) error {
+	return SetDefaultTranscodePreset(name)
+}
+
+// ListJobHistory 按条件查询历史任务
+func (a *App) ListJobHistory(filter JobHistoryFilter) []JobRecord {
+	return a.history.List(filter)
+}
+
+// InterruptedJobs 获取本次启动时发现的上次未完成的任务，这些任务已被标记为失败
+func (a *App) InterruptedJobs() []JobRecord {
+	return a.interrupted
+}
+
+// RequeueJobs 使用原来的参数重新运行历史任务，返回任务对应的视频信息以便前端加入列表
+func (a *App) RequeueJobs(ids []string) ([]VideoInfo, error) {
+	return a.queue.RequeueJobs(ids)
+}
+
+// ClearJobHistory 清空已结束的历史任务
+func (a *App) ClearJobHistory() error {
+	return a.history.Clear()
+}
+
+// FFmpegCapabilities 获取FFmpeg支持的编码器、解码器、滤镜和硬件加速方式
+func (a *App) FFmpegCapabilities() (*FFmpegCapabilities, error) {
+	return GetFFmpegCapabilities()
+}
+
+// RefreshFFmpegCapabilities 重新探测FFmpeg的能力，用于更换FFmpeg之后
+func (a *App) RefreshFFmpegCapabilities() (*FFmpegCapabilities, error) {
+	return RefreshFFmpegCapabilities()
 }
 
 func (a *App) OpenTranscodeVideo(path string) {
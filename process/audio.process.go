@@ -0,0 +1,192 @@
+package process
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type AudioChannels string
+
+const (
+	AudioChannels_Source   AudioChannels = ""       // 与源音频相同
+	AudioChannels_Mono     AudioChannels = "mono"   // 单声道
+	AudioChannels_Stereo   AudioChannels = "stereo" // 立体声，多声道音频会混缩为立体声
+	AudioChannels_Surround AudioChannels = "5.1"    // 5.1声道
+)
+
+// audioChannelCounts 声道布局对应的声道数
+var audioChannelCounts = map[AudioChannels]int{
+	AudioChannels_Mono:     1,
+	AudioChannels_Stereo:   2,
+	AudioChannels_Surround: 6,
+}
+
+// audioCodecLimit 音频编码支持的声道数、采样率和码率范围
+type audioCodecLimit struct {
+	MaxChannels int
+	SampleRates []int // 支持的采样率（Hz），为nil时不限制
+	MinBitrate  int64 // 码率范围（bit/s）
+	MaxBitrate  int64
+}
+
+// audioCodecLimits 参数中的音频编码对应的限制
+var audioCodecLimits = map[string]audioCodecLimit{
+	"aac": {
+		MaxChannels: 8,
+		SampleRates: []int{7350, 8000, 11025, 12000, 16000, 22050, 24000, 32000, 44100, 48000, 64000, 88200, 96000},
+		MinBitrate:  8000,
+		MaxBitrate:  512000,
+	},
+	"mp3": {
+		MaxChannels: 2,
+		SampleRates: []int{8000, 11025, 12000, 16000, 22050, 24000, 32000, 44100, 48000},
+		MinBitrate:  8000,
+		MaxBitrate:  320000,
+	},
+	"opus": {
+		MaxChannels: 8,
+		SampleRates: []int{8000, 12000, 16000, 24000, 48000},
+		MinBitrate:  6000,
+		MaxBitrate:  510000,
+	},
+}
+
+// hasAudioSettings 是否设置了需要重新编码音频的参数
+func hasAudioSettings(params TranscodeParams) bool {
+	return hasBitrate(params.AudioBitrate) || params.SampleRate > 0 || params.AudioChannels != AudioChannels_Source
+}
+
+// validateAudioParams 校验音频参数，已选择音频编码时检查编码是否支持这些参数
+func validateAudioParams(params TranscodeParams) error {
+	if params.MuteAudio {
+		return nil
+	}
+	if _, ok := audioChannelCounts[params.AudioChannels]; !ok && params.AudioChannels != AudioChannels_Source {
+		return fmt.Errorf("无效的声道: %s，可用: mono、stereo、5.1", params.AudioChannels)
+	}
+	if params.SampleRate < 0 {
+		return fmt.Errorf("无效的采样率: %d", params.SampleRate)
+	}
+	if hasBitrate(params.AudioBitrate) {
+		if _, err := parseBitrate(params.AudioBitrate); err != nil {
+			return fmt.Errorf("无效的音频码率: %s", params.AudioBitrate)
+		}
+	}
+	if params.AudioCodec == "copy" {
+		return nil
+	}
+	return checkAudioCodecLimits(params.AudioCodec, audioChannelCounts[params.AudioChannels], params.SampleRate, params.AudioBitrate)
+}
+
+// checkAudioCodecLimits 检查音频编码是否支持指定的声道数、采样率和码率，为0或空时不检查
+func checkAudioCodecLimits(codec string, channels, sampleRate int, bitrate string) error {
+	limit, ok := audioCodecLimits[codec]
+	if !ok {
+		return nil
+	}
+	name := strings.ToUpper(codec)
+	if channels > limit.MaxChannels {
+		return fmt.Errorf("%s 最多支持 %d 声道，不能输出 %d 声道", name, limit.MaxChannels, channels)
+	}
+	if sampleRate > 0 && limit.SampleRates != nil && !containsInt(limit.SampleRates, sampleRate) {
+		rates := make([]string, len(limit.SampleRates))
+		for i, rate := range limit.SampleRates {
+			rates[i] = strconv.Itoa(rate)
+		}
+		return fmt.Errorf("%s 不支持 %dHz 采样率，可用: %s", name, sampleRate, strings.Join(rates, "、"))
+	}
+	if hasBitrate(bitrate) {
+		value, err := parseBitrate(bitrate)
+		if err == nil && (value < limit.MinBitrate || value > limit.MaxBitrate) {
+			return fmt.Errorf("%s 的码率必须在 %dk 到 %dk 之间: %s", name, limit.MinBitrate/1000, limit.MaxBitrate/1000, bitrate)
+		}
+	}
+	return nil
+}
+
+// resolveAudioParams 在启动FFmpeg前确定音频参数，需要在确定输出格式之后调用
+//
+// 静音时忽略其他音频参数；直接复制的音频设置了码率、采样率或声道时改为重新编码；
+// 源音频的声道数超过编码支持的声道数时混缩为立体声
+//
+// 返回值:
+//
+//	TranscodeParams: 实际使用的参数
+//	[]string: 自动修改参数的说明
+//	error: 编码不支持指定的参数时返回错误
+func resolveAudioParams(params TranscodeParams, inputInfo *VideoInfo) (TranscodeParams, []string, error) {
+	var warnings []string
+	format, hasFormat := getContainerFormat(params.Container)
+	if params.MuteAudio {
+		if hasFormat && format.AudioOnly {
+			return params, nil, fmt.Errorf("%s 只包含音频，不能去掉音频", strings.ToUpper(string(format.Name)))
+		}
+		if hasAudioSettings(params) || params.Loudness.Enabled {
+			warnings = append(warnings, "已去掉音频，忽略音频参数")
+		}
+		params.AudioBitrate, params.SampleRate, params.AudioChannels = "", 0, AudioChannels_Source
+		params.Loudness.Enabled = false
+		return params, warnings, nil
+	}
+	if inputInfo != nil && inputInfo.AudioCodec == "" {
+		return params, nil, nil
+	}
+
+	if params.AudioCodec == "copy" {
+		if !hasAudioSettings(params) {
+			return params, nil, nil
+		}
+		sourceCodec := ""
+		if inputInfo != nil {
+			sourceCodec = inputInfo.AudioCodec
+		}
+		params.AudioCodec = reencodeAudioCodec(params.Container, sourceCodec)
+		warnings = append(warnings, fmt.Sprintf("调整音频码率、采样率或声道需要重新编码音频，已使用 %s 编码", params.AudioCodec))
+	}
+
+	channels := audioChannelCounts[params.AudioChannels]
+	if limit, ok := audioCodecLimits[params.AudioCodec]; ok && channels == 0 && inputInfo != nil && inputInfo.Channels > limit.MaxChannels {
+		params.AudioChannels = AudioChannels_Stereo
+		warnings = append(warnings, fmt.Sprintf("%s 最多支持 %d 声道，源音频的 %d 声道已混缩为立体声", strings.ToUpper(params.AudioCodec), limit.MaxChannels, inputInfo.Channels))
+	}
+	if err := checkAudioCodecLimits(params.AudioCodec, audioChannelCounts[params.AudioChannels], params.SampleRate, params.AudioBitrate); err != nil {
+		return params, nil, err
+	}
+	return params, warnings, nil
+}
+
+// audioOutputArgs 生成音频编码参数，静音或两遍编码的第一遍不输出音频
+//
+// 目标大小模式按估算的音频码率编码，否则使用指定的码率；多声道混缩使用FFmpeg默认的混缩矩阵
+func audioOutputArgs(params TranscodeParams, encoders encoderSelection, pass encodePass) []string {
+	if params.MuteAudio || pass.Number == 1 {
+		return []string{"-an"}
+	}
+	args := []string{"-c:a", encoders.Audio}
+	if encoders.Audio == "copy" {
+		return args
+	}
+	// FFmpeg自带的opus编码器仍是实验性的
+	if encoders.Audio == "opus" {
+		args = append(args, "-strict", "-2")
+	}
+	if pass.AudioBitrate > 0 {
+		args = append(args, "-b:a", strconv.FormatInt(pass.AudioBitrate, 10))
+	} else if hasBitrate(params.AudioBitrate) {
+		args = append(args, "-b:a", params.AudioBitrate)
+	}
+	if params.SampleRate > 0 {
+		args = append(args, "-ar", strconv.Itoa(params.SampleRate))
+	}
+	if channels := audioChannelCounts[params.AudioChannels]; channels > 0 {
+		args = append(args, "-ac", strconv.Itoa(channels))
+	}
+	return args
+}
+
+func containsInt(values []int, value int) bool {
+	index := sort.SearchInts(values, value)
+	return index < len(values) && values[index] == value
+}
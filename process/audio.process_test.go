@@ -0,0 +1,182 @@
+package process
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveAudioParams(t *testing.T) {
+	stereoAAC := &VideoInfo{AudioCodec: "aac", Channels: 2}
+	surroundAC3 := &VideoInfo{AudioCodec: "ac3", Channels: 6}
+
+	tests := []struct {
+		name         string
+		params       TranscodeParams
+		inputInfo    *VideoInfo
+		wantCodec    string
+		wantChannels AudioChannels
+		wantWarnings int
+		wantErr      bool
+	}{
+		{
+			name:      "直接复制",
+			params:    TranscodeParams{AudioCodec: "copy"},
+			inputInfo: stereoAAC,
+			wantCodec: "copy",
+		},
+		{
+			name:         "静音时忽略音频参数",
+			params:       TranscodeParams{AudioCodec: "aac", AudioBitrate: "128k", SampleRate: 44100, MuteAudio: true},
+			inputInfo:    stereoAAC,
+			wantCodec:    "aac",
+			wantWarnings: 1,
+		},
+		{
+			name:    "只输出音频的格式不能静音",
+			params:  TranscodeParams{AudioCodec: "copy", Container: OutputContainer_MP3, MuteAudio: true},
+			wantErr: true,
+		},
+		{
+			name:         "直接复制时设置码率改为重新编码",
+			params:       TranscodeParams{AudioCodec: "copy", AudioBitrate: "96k", Container: OutputContainer_MKV},
+			inputInfo:    surroundAC3,
+			wantCodec:    "aac",
+			wantWarnings: 1,
+		},
+		{
+			name:         "直接复制时设置采样率改为源音频的编码",
+			params:       TranscodeParams{AudioCodec: "copy", SampleRate: 48000},
+			inputInfo:    &VideoInfo{AudioCodec: "opus", Channels: 2},
+			wantCodec:    "opus",
+			wantWarnings: 1,
+		},
+		{
+			name:         "直接复制时设置声道改为重新编码",
+			params:       TranscodeParams{AudioCodec: "copy", AudioChannels: AudioChannels_Mono},
+			inputInfo:    stereoAAC,
+			wantCodec:    "aac",
+			wantChannels: AudioChannels_Mono,
+			wantWarnings: 1,
+		},
+		{
+			name:         "MP3混缩6声道为立体声",
+			params:       TranscodeParams{AudioCodec: "mp3"},
+			inputInfo:    surroundAC3,
+			wantCodec:    "mp3",
+			wantChannels: AudioChannels_Stereo,
+			wantWarnings: 1,
+		},
+		{
+			name:      "AAC保留6声道",
+			params:    TranscodeParams{AudioCodec: "aac"},
+			inputInfo: surroundAC3,
+			wantCodec: "aac",
+		},
+		{
+			name:    "MP3不支持5.1声道",
+			params:  TranscodeParams{AudioCodec: "mp3", AudioChannels: AudioChannels_Surround},
+			wantErr: true,
+		},
+		{
+			name:    "Opus不支持44100Hz",
+			params:  TranscodeParams{AudioCodec: "opus", SampleRate: 44100},
+			wantErr: true,
+		},
+		{
+			name:    "MP3码率过高",
+			params:  TranscodeParams{AudioCodec: "mp3", AudioBitrate: "384k"},
+			wantErr: true,
+		},
+		{
+			name:      "源文件没有音频",
+			params:    TranscodeParams{AudioCodec: "opus", SampleRate: 44100},
+			inputInfo: &VideoInfo{VideoCodec: "h264"},
+			wantCodec: "opus",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings, err := resolveAudioParams(tt.params, tt.inputInfo)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveAudioParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.AudioCodec != tt.wantCodec || got.AudioChannels != tt.wantChannels {
+				t.Errorf("音频 = %s %q, want %s %q", got.AudioCodec, got.AudioChannels, tt.wantCodec, tt.wantChannels)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("警告 = %q, want %d 条", warnings, tt.wantWarnings)
+			}
+			if got.MuteAudio && (got.AudioBitrate != "" || got.SampleRate != 0) {
+				t.Errorf("静音时应清除音频参数: %+v", got)
+			}
+		})
+	}
+}
+
+func TestCheckAudioCodecLimits(t *testing.T) {
+	tests := []struct {
+		codec      string
+		channels   int
+		sampleRate int
+		bitrate    string
+		wantErr    bool
+	}{
+		{codec: "aac", channels: 6, sampleRate: 44100, bitrate: "384k"},
+		{codec: "aac", sampleRate: 44000, wantErr: true},
+		{codec: "mp3", channels: 2, sampleRate: 48000, bitrate: "320k"},
+		{codec: "mp3", channels: 6, wantErr: true},
+		{codec: "opus", sampleRate: 48000, bitrate: "6k"},
+		{codec: "opus", sampleRate: 44100, wantErr: true},
+		{codec: "opus", bitrate: "5k", wantErr: true},
+		{codec: "flac", channels: 8, sampleRate: 44000, bitrate: "2M"},
+	}
+	for _, tt := range tests {
+		err := checkAudioCodecLimits(tt.codec, tt.channels, tt.sampleRate, tt.bitrate)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkAudioCodecLimits(%s, %d, %d, %q) error = %v, wantErr %v", tt.codec, tt.channels, tt.sampleRate, tt.bitrate, err, tt.wantErr)
+		}
+	}
+}
+
+func TestAudioOutputArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   TranscodeParams
+		encoders encoderSelection
+		pass     encodePass
+		want     []string
+	}{
+		{name: "直接复制", params: TranscodeParams{AudioBitrate: "128k"}, encoders: encoderSelection{Audio: "copy"}, want: []string{"-c:a", "copy"}},
+		{name: "静音", params: TranscodeParams{MuteAudio: true}, encoders: encoderSelection{Audio: "aac"}, want: []string{"-an"}},
+		{name: "两遍编码的第一遍", encoders: encoderSelection{Audio: "aac"}, pass: encodePass{Number: 1}, want: []string{"-an"}},
+		{
+			name:     "码率、采样率和混缩为立体声",
+			params:   TranscodeParams{AudioBitrate: "192k", SampleRate: 44100, AudioChannels: AudioChannels_Stereo},
+			encoders: encoderSelection{Audio: "libmp3lame"},
+			want:     []string{"-c:a", "libmp3lame", "-b:a", "192k", "-ar", "44100", "-ac", "2"},
+		},
+		{
+			name:     "目标大小模式使用估算的码率",
+			params:   TranscodeParams{AudioBitrate: "192k"},
+			encoders: encoderSelection{Audio: "aac"},
+			pass:     encodePass{Number: 2, AudioBitrate: 96000},
+			want:     []string{"-c:a", "aac", "-b:a", "96000"},
+		},
+		{
+			name:     "FFmpeg自带的Opus编码器",
+			params:   TranscodeParams{AudioChannels: AudioChannels_Mono},
+			encoders: encoderSelection{Audio: "opus"},
+			want:     []string{"-c:a", "opus", "-strict", "-2", "-ac", "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := audioOutputArgs(tt.params, tt.encoders, tt.pass); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("audioOutputArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package process
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FFmpegCapabilities FFmpeg支持的编码器、解码器、滤镜和硬件加速方式，解析自 -encoders、-decoders、-filters、-hwaccels 的输出
+type FFmpegCapabilities struct {
+	Version  string   `json:"version"`
+	Encoders []string `json:"encoders"`
+	Decoders []string `json:"decoders"`
+	Filters  []string `json:"filters"`
+	HWAccels []string `json:"hwaccels"`
+
+	encoders map[string]bool
+	decoders map[string]bool
+	filters  map[string]bool
+	hwaccels map[string]bool
+}
+
+var (
+	capabilitiesMu     sync.Mutex
+	cachedCapabilities *FFmpegCapabilities
+)
+
+// GetFFmpegCapabilities 获取FFmpeg的能力，第一次调用时探测，之后使用缓存的结果
+func GetFFmpegCapabilities() (*FFmpegCapabilities, error) {
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+	if cachedCapabilities != nil {
+		return cachedCapabilities, nil
+	}
+	caps, err := probeFFmpegCapabilities()
+	if err != nil {
+		return nil, err
+	}
+	cachedCapabilities = caps
+	return caps, nil
+}
+
+// RefreshFFmpegCapabilities 清除缓存并重新探测，用于更换FFmpeg之后
+func RefreshFFmpegCapabilities() (*FFmpegCapabilities, error) {
+	capabilitiesMu.Lock()
+	cachedCapabilities = nil
+	capabilitiesMu.Unlock()
+	hardwareTestMu.Lock()
+	hardwareTestResults = map[string]bool{}
+	hardwareTestMu.Unlock()
+	return GetFFmpegCapabilities()
+}
+
+// probeFFmpegCapabilities 运行FFmpeg并解析支持的编码器、解码器、滤镜和硬件加速方式
+func probeFFmpegCapabilities() (*FFmpegCapabilities, error) {
+	ffmpegPath, err := IsFFmpegAvailable()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg不可用: %v", err)
+	}
+	run := func(arg string) (string, error) {
+		output, err := createCommand(ffmpegPath, "-hide_banner", arg).Output()
+		if err != nil {
+			return "", fmt.Errorf("执行 ffmpeg %s 失败: %v", arg, err)
+		}
+		return string(output), nil
+	}
+
+	caps := &FFmpegCapabilities{}
+	if output, err := createCommand(ffmpegPath, "-version").Output(); err == nil {
+		caps.Version = parseFFmpegVersion(string(output))
+	}
+	encoders, err := run("-encoders")
+	if err != nil {
+		return nil, err
+	}
+	decoders, err := run("-decoders")
+	if err != nil {
+		return nil, err
+	}
+	filters, err := run("-filters")
+	if err != nil {
+		return nil, err
+	}
+	hwaccels, err := run("-hwaccels")
+	if err != nil {
+		return nil, err
+	}
+	caps.Encoders = parseCodecList(encoders)
+	caps.Decoders = parseCodecList(decoders)
+	caps.Filters = parseFilterList(filters)
+	caps.HWAccels = parseHWAccelList(hwaccels)
+	caps.index()
+	return caps, nil
+}
+
+// index 建立查找用的集合
+func (c *FFmpegCapabilities) index() {
+	toSet := func(names []string) map[string]bool {
+		set := make(map[string]bool, len(names))
+		for _, name := range names {
+			set[name] = true
+		}
+		return set
+	}
+	c.encoders = toSet(c.Encoders)
+	c.decoders = toSet(c.Decoders)
+	c.filters = toSet(c.Filters)
+	c.hwaccels = toSet(c.HWAccels)
+}
+
+func (c *FFmpegCapabilities) HasEncoder(name string) bool { return c.encoders[name] }
+func (c *FFmpegCapabilities) HasDecoder(name string) bool { return c.decoders[name] }
+func (c *FFmpegCapabilities) HasFilter(name string) bool  { return c.filters[name] }
+func (c *FFmpegCapabilities) HasHWAccel(name string) bool { return c.hwaccels[name] }
+
+// parseFFmpegVersion 从 -version 的第一行解析版本号，如 "ffmpeg version 6.1.1 Copyright..."
+func parseFFmpegVersion(output string) string {
+	firstLine, _, _ := strings.Cut(output, "\n")
+	fields := strings.Fields(firstLine)
+	if len(fields) >= 3 && fields[1] == "version" {
+		return fields[2]
+	}
+	return strings.TrimSpace(firstLine)
+}
+
+// parseCodecList 解析 -encoders 或 -decoders 的输出
+//
+// 格式为说明部分加 " ------" 分隔行，之后每行为 "标志 名称 描述"，如:
+//
+//	V....D libx264              libx264 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10 (codec h264)
+func parseCodecList(output string) []string {
+	var names []string
+	started := false
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !started {
+			started = strings.HasPrefix(trimmed, "---")
+			continue
+		}
+		fields := strings.Fields(trimmed)
+		if len(fields) < 2 || strings.Contains(fields[0], "=") {
+			continue
+		}
+		names = append(names, fields[1])
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseFilterList 解析 -filters 的输出，每行为 "标志 名称 输入->输出 描述"，如:
+//
+//	..C scale             V->V       Scale the input video size and/or convert the image format.
+func parseFilterList(output string) []string {
+	var names []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !strings.Contains(fields[2], "->") {
+			continue
+		}
+		names = append(names, fields[1])
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseHWAccelList 解析 -hwaccels 的输出，第一行为标题，之后每行一个硬件加速方式
+func parseHWAccelList(output string) []string {
+	var names []string
+	for _, line := range strings.Split(output, "\n") {
+		name := strings.TrimSpace(line)
+		if name == "" || strings.HasSuffix(name, ":") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// encoderSelection 实际使用的编码器，不可用的编码器被替换时记录说明
+type encoderSelection struct {
+	Video    string
+	Audio    string
+	Backend  *encoderBackend // 使用硬件编码时的硬件编码器，软件编码时为nil
+	Device   string          // 硬件设备，目前只有VA-API使用
+	Warnings []string
+}
+
+// videoCodecName 获取要重新编码的视频编码 h264/h265/av1/vp9，直接复制时返回空
+func videoCodecName(params TranscodeParams) string {
+	switch params.VideoCodec {
+	case "h264", "h265", "av1", "vp9":
+		return params.VideoCodec
+	}
+	// 如果要加水印或烧录字幕，不能使用copy，默认使用H.264重新编码
+	if params.WatermarkContent != "" || params.WatermarkImage != "" || params.BurnSubtitle.Enabled() {
+		return "h264"
+	}
+	return ""
+}
+
+// videoEncoderCandidates 按优先级列出软件视频编码器
+func videoEncoderCandidates(codec string) []string {
+	switch codec {
+	case "h265":
+		return []string{"libx265"}
+	case "h264":
+		return []string{"libx264", "libopenh264"}
+	case "av1":
+		// SVT-AV1 速度最快，libaom-av1 画质好但很慢
+		return []string{"libsvtav1", "libaom-av1", "librav1e"}
+	case "vp9":
+		return []string{"libvpx-vp9"}
+	default:
+		return []string{"copy"}
+	}
+}
+
+// audioEncoderCandidates 按优先级列出音频编码器
+func audioEncoderCandidates(params TranscodeParams) []string {
+	switch params.AudioCodec {
+	case "aac":
+		return []string{"libfdk_aac", "aac"}
+	case "mp3":
+		return []string{"libmp3lame", "mp3_mf"}
+	case "opus":
+		return []string{"libopus", "opus"}
+	default:
+		return []string{"copy"}
+	}
+}
+
+// pickEncoder 从候选中选出第一个可用的编码器，无法探测FFmpeg能力时使用第一个候选
+func pickEncoder(caps *FFmpegCapabilities, candidates []string) (string, bool) {
+	for _, candidate := range candidates {
+		if candidate == "copy" || caps == nil || caps.HasEncoder(candidate) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// resolveEncoders 根据参数和FFmpeg能力确定实际使用的编码器，使用GPU时选择可用的硬件编码器，都不可用时使用软件编码
+func resolveEncoders(params TranscodeParams, caps *FFmpegCapabilities) (encoderSelection, error) {
+	var selection encoderSelection
+	resolve := func(kind string, candidates []string) (string, error) {
+		encoder, ok := pickEncoder(caps, candidates)
+		if !ok {
+			return "", fmt.Errorf("FFmpeg不支持%s编码器: %s", kind, strings.Join(candidates, "、"))
+		}
+		if encoder != candidates[0] {
+			selection.Warnings = append(selection.Warnings, fmt.Sprintf("%s 不可用，使用 %s", candidates[0], encoder))
+		}
+		return encoder, nil
+	}
+
+	codec := videoCodecName(params)
+	if codec != "" && params.UseGpu {
+		if backend, ok := selectHardwareBackend(codec, caps); ok {
+			selection.Video = backend.Encoders[codec]
+			selection.Backend = &backend
+			selection.Device = GetVAAPIDevice()
+		} else {
+			selection.Warnings = append(selection.Warnings, "没有可用的硬件编码器，使用软件编码")
+		}
+	}
+	var err error
+	if selection.Video == "" {
+		// 改为软件编码时不使用硬件编码器的参数和滤镜
+		selection.Backend, selection.Device = nil, ""
+		if selection.Video, err = resolve("视频", videoEncoderCandidates(codec)); err != nil {
+			return selection, err
+		}
+	}
+	if selection.Audio, err = resolve("音频", audioEncoderCandidates(params)); err != nil {
+		return selection, err
+	}
+	return selection, nil
+}
@@ -0,0 +1,171 @@
+package process
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const encodersOutput = `Encoders:
+ V..... = Video
+ A..... = Audio
+ S..... = Subtitle
+ .F.... = Frame-level multithreading
+ ..S... = Slice-level multithreading
+ ...X.. = Codec is experimental
+ ....B. = Supports draw_horiz_band
+ .....D = Supports direct rendering method 1
+ ------
+ V....D libx264              libx264 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10 (codec h264)
+ V....D h264_nvenc           NVIDIA NVENC H.264 encoder (codec h264)
+ V....D libsvtav1            SVT-AV1(Scalable Video Technology for AV1) encoder (codec av1)
+ A....D aac                  AAC (Advanced Audio Coding)
+ A.X..D opus                 Opus
+ S..... srt                  SubRip subtitle
+`
+
+const filtersOutput = `Filters:
+  T.. = Timeline support
+  .S. = Slice threading
+  ..C = Command support
+  A = Audio input/output
+  V = Video input/output
+  N = Dynamic number and/or type of input/output
+  | = Source or sink filter
+ ... abuffer           |->A       Buffer audio frames, and make them accessible to the filterchain.
+ T.C scale             V->V       Scale the input video size and/or convert the image format.
+ TSC xfade             VV->V      Cross fade one video with another video.
+ ... loudnorm          A->A       EBU R128 loudness normalization
+ ... concat            N->N       Concatenate audio and video streams.
+`
+
+const hwaccelsOutput = `Hardware acceleration methods:
+vdpau
+cuda
+vaapi
+qsv
+
+`
+
+func TestParseCodecList(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{name: "编码器列表", output: encodersOutput, want: []string{"aac", "h264_nvenc", "libsvtav1", "libx264", "opus", "srt"}},
+		{name: "Windows换行", output: strings.ReplaceAll(encodersOutput, "\n", "\r\n"), want: []string{"aac", "h264_nvenc", "libsvtav1", "libx264", "opus", "srt"}},
+		{name: "没有分隔行", output: "Encoders:\n V....D libx264 libx264 H.264\n", want: nil},
+		{name: "空输出", output: "", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCodecList(tt.output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCodecList() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilterList(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{name: "滤镜列表", output: filtersOutput, want: []string{"abuffer", "concat", "loudnorm", "scale", "xfade"}},
+		{name: "Windows换行", output: strings.ReplaceAll(filtersOutput, "\n", "\r\n"), want: []string{"abuffer", "concat", "loudnorm", "scale", "xfade"}},
+		{name: "空输出", output: "", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseFilterList(tt.output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFilterList() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHWAccelList(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{name: "硬件加速列表", output: hwaccelsOutput, want: []string{"cuda", "qsv", "vaapi", "vdpau"}},
+		{name: "Windows换行", output: strings.ReplaceAll(hwaccelsOutput, "\n", "\r\n"), want: []string{"cuda", "qsv", "vaapi", "vdpau"}},
+		{name: "没有硬件加速", output: "Hardware acceleration methods:\n\n", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseHWAccelList(tt.output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseHWAccelList() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFFmpegVersion(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"ffmpeg version 6.1.1 Copyright (c) 2000-2023 the FFmpeg developers\nbuilt with gcc 13", "6.1.1"},
+		{"ffmpeg version n7.0-full_build-www.gyan.dev Copyright (c) 2000-2024", "n7.0-full_build-www.gyan.dev"},
+		{"unknown build\n", "unknown build"},
+	}
+	for _, tt := range tests {
+		if got := parseFFmpegVersion(tt.output); got != tt.want {
+			t.Errorf("parseFFmpegVersion(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestPickEncoder(t *testing.T) {
+	caps := &FFmpegCapabilities{Encoders: []string{"aac", "libaom-av1", "libopenh264", "libopus", "libx264"}}
+	caps.index()
+
+	tests := []struct {
+		name   string
+		caps   *FFmpegCapabilities
+		codec  string
+		want   string
+		wantOK bool
+	}{
+		{name: "使用第一个可用的编码器", caps: caps, codec: "h264", want: "libx264", wantOK: true},
+		{name: "没有探测结果时使用第一个候选", codec: "h265", want: "libx265", wantOK: true},
+		{name: "没有可用的编码器", caps: caps, codec: "h265", wantOK: false},
+		{name: "直接复制", caps: caps, codec: "", want: "copy", wantOK: true},
+		{name: "SVT-AV1不可用时使用libaom-av1", caps: caps, codec: "av1", want: "libaom-av1", wantOK: true},
+		{name: "没有VP9编码器", caps: caps, codec: "vp9", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := pickEncoder(tt.caps, videoEncoderCandidates(tt.codec))
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("pickEncoder() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	videoOnly := &FFmpegCapabilities{Encoders: []string{"libx264"}}
+	videoOnly.index()
+	audioTests := []struct {
+		codec  string
+		caps   *FFmpegCapabilities
+		want   string
+		wantOK bool
+	}{
+		{"aac", caps, "aac", true},
+		{"aac", nil, "libfdk_aac", true},
+		{"opus", caps, "libopus", true},
+		{"mp3", caps, "", false},
+		{"opus", videoOnly, "", false},
+	}
+	for _, tt := range audioTests {
+		got, ok := pickEncoder(tt.caps, audioEncoderCandidates(TranscodeParams{AudioCodec: tt.codec}))
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("音频编码 %s: pickEncoder() = %q, %v, want %q, %v", tt.codec, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
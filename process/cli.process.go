@@ -0,0 +1,651 @@
+package process
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 命令行模式的退出码
+const (
+	CLIExitOK        = 0   // 全部成功（包括按策略跳过的文件）
+	CLIExitFailed    = 1   // 有任务失败
+	CLIExitUsage     = 2   // 参数错误
+	CLIExitNoInput   = 3   // 没有找到可处理的视频文件
+	CLIExitCancelled = 130 // 被 Ctrl+C 取消
+)
+
+// CLISummary 命令行模式结束后输出的JSON汇总
+type CLISummary struct {
+	StartedAt      time.Time         `json:"started_at"`
+	FinishedAt     time.Time         `json:"finished_at"`
+	ElapsedSeconds float64           `json:"elapsed_seconds"`
+	Total          int               `json:"total"`
+	Completed      int               `json:"completed"`
+	Failed         int               `json:"failed"`
+	Cancelled      int               `json:"cancelled"`
+	Skipped        int               `json:"skipped"`
+	ExitCode       int               `json:"exit_code"`
+	Results        []TranscodeResult `json:"results"`
+}
+
+// RunCLI 以命令行模式运行批量转码，不启动窗口，返回进程退出码
+//
+// 用法: cm_video_batch_process cli [参数] 文件|通配符|文件夹...
+//
+//	cm_video_batch_process cli history [参数]
+func RunCLI(args []string) int {
+	attachParentConsole()
+	if len(args) > 0 && args[0] == "history" {
+		return runCLIHistory(args[1:])
+	}
+
+	fs := flag.NewFlagSet("cli", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: %s cli [参数] 文件|通配符|文件夹...\n      %s cli history [参数]  查询任务历史\n\n参数:\n", filepath.Base(os.Args[0]), filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+
+	params := TranscodeParams{}
+	presetName := fs.String("preset", "", "使用配置文件中的预设或内置预设（web-720p、archive-h265、phone-friendly），命令行中显式指定的参数优先")
+	paramsFile := fs.String("params", "", "从JSON文件读取转码参数（TranscodeParams），会覆盖预设中的值，命令行中显式指定的参数优先")
+	fs.StringVar(&params.VideoCodec, "vcodec", "copy", "视频编码: copy、h264、h265、av1、vp9")
+	fs.StringVar(&params.AudioCodec, "acodec", "copy", "音频编码: copy、aac、mp3、opus")
+	fs.StringVar(&params.VideoHeight, "height", "copy", "视频高度，如 720")
+	fs.StringVar(&params.Fps, "fps", "copy", "帧率，如 30")
+	fs.StringVar(&params.VideoBitrate, "vbitrate", "copy", "视频码率，如 2M")
+	fs.StringVar(&params.AudioBitrate, "abitrate", "", "音频码率，如 128k，默认使用编码器默认值")
+	fs.IntVar(&params.SampleRate, "sample-rate", 0, "音频采样率（Hz），如 44100、48000，默认与源音频相同")
+	channels := fs.String("channels", "", "声道: mono、stereo（多声道混缩为立体声）、5.1，默认与源音频相同")
+	fs.BoolVar(&params.MuteAudio, "mute", false, "去掉音频")
+	audioTracks := fs.String("audio-tracks", "", "音轨: all（保留所有音轨）、language（只保留 -languages 指定语言的音轨），默认只保留一个音轨")
+	languages := fs.String("languages", "", "保留的语言，多个用逗号分隔，如 chi,eng，同时用于筛选字幕")
+	fs.StringVar(&params.Streams.DefaultLanguage, "default-language", "", "标记为默认音轨的语言，如 chi")
+	fs.BoolVar(&params.Streams.Subtitles, "subtitles", false, "保留字幕，输出格式不支持的文字字幕会转换格式")
+	fs.BoolVar(&params.Streams.Attachments, "attachments", false, "保留附件（如字幕字体），只有MKV支持")
+	fs.StringVar(&params.WatermarkContent, "watermark-text", "", "文字水印")
+	fs.StringVar(&params.WatermarkImage, "watermark-image", "", "图片水印文件")
+	burnSubtitle := fs.String("burn-subtitle", "", "烧录字幕: file（-burn-subtitle-file 指定的文件）、sidecar（输入文件旁边同名的 srt/ass/vtt）、stream（内嵌字幕）")
+	fs.StringVar(&params.BurnSubtitle.File, "burn-subtitle-file", "", "烧录的字幕文件，指定后默认 -burn-subtitle file")
+	fs.IntVar(&params.BurnSubtitle.StreamIndex, "burn-subtitle-stream", 0, "烧录第几个内嵌字幕流，从0开始")
+	fs.StringVar(&params.BurnSubtitle.Font, "subtitle-font", "", "烧录字幕的字体名称，如 Microsoft YaHei")
+	fs.IntVar(&params.BurnSubtitle.FontSize, "subtitle-size", 0, "烧录字幕的字号，默认使用字幕文件的设置")
+	fs.StringVar(&params.BurnSubtitle.Color, "subtitle-color", "", "烧录字幕的文字颜色，如 #FFFFFF")
+	fs.StringVar(&params.BurnSubtitle.OutlineColor, "subtitle-outline-color", "", "烧录字幕的描边颜色，如 #000000")
+	fs.Float64Var(&params.BurnSubtitle.Outline, "subtitle-outline", 0, "烧录字幕的描边宽度")
+	fs.BoolVar(&params.BurnSubtitle.Bold, "subtitle-bold", false, "烧录字幕使用粗体")
+	fs.BoolVar(&params.BurnSubtitle.Italic, "subtitle-italic", false, "烧录字幕使用斜体")
+	subtitlePosition := fs.String("subtitle-position", "", "烧录字幕的位置: top、middle，默认在底部")
+	fs.IntVar(&params.BurnSubtitle.Margin, "subtitle-margin", 0, "烧录字幕与画面上下边缘的距离")
+	fs.BoolVar(&params.SoftSubtitles.Sidecars, "mux-sidecars", false, "把输入文件旁边同名的字幕文件封装为可选字幕轨，文件名后缀作为语言，如 video.zh.srt")
+	muxSubtitles := fs.String("mux-subtitle", "", "封装为可选字幕轨的字幕文件，多个用逗号分隔，可以用 语言=文件 指定语言，如 chi=a.srt,eng=b.srt")
+	extractSubtitles := fs.String("extract-subtitles", "", "把内嵌的文字字幕导出到输出目录: srt、ass、vtt")
+	subtitleJob := fs.String("subtitle-job", "", "只处理字幕: 字幕文件转换为指定格式，视频文件导出内嵌的文字字幕，可用: srt、ass、vtt")
+	placement := fs.String("watermark-placement", string(WatermarkPlacement_TopRight), "水印位置: top-right、random、horizontal、diagonal、bounce、spiral")
+	rotate := fs.String("rotate", string(VideoRotate_copy), "旋转: copy、90、180、270")
+	fs.BoolVar(&params.UseGpu, "gpu", false, "使用GPU加速")
+	rateControl := fs.String("rc", "", "码率控制: crf、vbr、cbr、abr，默认只使用 -vbitrate")
+	quality := fs.Int("crf", -1, "CRF/CQ值，越小画质越好，0为无损，不指定时使用编码器默认值")
+	fs.StringVar(&params.MaxRate, "maxrate", "", "最大码率，如 4M，用于VBR和限制CRF的峰值码率")
+	fs.StringVar(&params.BufSize, "bufsize", "", "码率控制缓冲区大小，默认为最大码率的两倍")
+	fs.StringVar(&params.EncoderPreset, "encoder-preset", "", "编码速度预设: ultrafast ... veryslow")
+	fs.StringVar(&params.Tune, "tune", "", "如 film、animation、grain、zerolatency")
+	fs.StringVar(&params.Profile, "profile", "", "如 high、main、main10")
+	fs.StringVar(&params.Level, "level", "", "如 4.1")
+	fs.StringVar(&params.PixelFormat, "pix-fmt", "", "像素格式，如 yuv420p、yuv420p10le")
+	fs.IntVar(&params.GOP, "gop", 0, "关键帧间隔（帧数），0为编码器默认值")
+	fs.BoolVar(&params.TwoPass, "two-pass", false, "两遍编码，需要指定 -vbitrate")
+	fs.Float64Var(&params.TargetSizeMB, "target-size", 0, "目标文件大小（MB），根据时长计算码率并两遍编码")
+	container := fs.String("container", "", "输出格式: mp4、mkv、mov、webm、ts、m4a、mp3，默认与输入文件相同")
+	fs.StringVar(&params.Trim.Start, "start", "", "截取的开始时间，如 90、1:30、00:01:30.5")
+	fs.StringVar(&params.Trim.End, "end", "", "截取的结束时间，不能与 -duration 同时使用")
+	fs.StringVar(&params.Trim.Duration, "duration", "", "截取的时长")
+	trimMode := fs.String("trim-mode", "", "切割方式: fast（关键帧对齐，可直接复制视频流）、accurate（逐帧精确，需要重新编码）、smart（智能切割，只重新编码首尾不完整的GOP）")
+	segmentMode := fs.String("segment", "", "分段输出: duration（按时长）、size（按大小）、scene（在场景切换处）")
+	fs.StringVar(&params.Segment.Duration, "segment-time", "", "每段的最长时长，如 600、10:00；按场景分段时为最短时长")
+	fs.Float64Var(&params.Segment.SizeMB, "segment-size", 0, "每段的大约最大大小（MB），如 2000")
+	fs.Float64Var(&params.Segment.SceneThreshold, "scene-threshold", 0, "场景变化阈值 0-1，越小切分点越多，默认 0.4")
+	fs.StringVar(&params.Segment.Pattern, "segment-pattern", "", "分段文件名后缀，{part} 为序号，默认 "+defaultSegmentPattern)
+	fs.BoolVar(&params.Loudness.Enabled, "loudnorm", false, "按EBU R128标准化响度，先分析再线性调整音量，需要重新编码音频")
+	fs.Float64Var(&params.Loudness.Integrated, "loudnorm-i", 0, "目标响度（LUFS），默认 -23")
+	fs.Float64Var(&params.Loudness.TruePeak, "loudnorm-tp", 0, "真峰值上限（dBTP），默认 -1")
+	fs.Float64Var(&params.Loudness.LRA, "loudnorm-lra", 0, "目标响度范围（LU），默认 7")
+	hardwareBackend := fs.String("gpu-backend", "", "使用GPU时的硬件编码器: nvenc、qsv、amf、vaapi、videotoolbox，默认自动选择")
+	vaapiDevice := fs.String("vaapi-device", "", "VA-API设备，默认 "+defaultVAAPIDevice)
+	fs.IntVar(&params.CpuThreads, "threads", 0, "每个FFmpeg进程的线程数，0为自动")
+
+	outputDirectory := fs.String("o", "", "输出目录，默认使用配置文件中的输出目录")
+	parallel := fs.Int("parallel", 0, "同时运行的任务数，默认使用配置文件中的值")
+	onExists := fs.String("on-exists", "", "输出文件已存在时: skip、overwrite、rename、fail")
+	nameTemplate := fs.String("name-template", "", "输出文件名模板，如 {name}_{height}p.{ext}")
+	include := fs.String("include", "", "导入文件夹时包含的文件模式，多个用逗号分隔")
+	exclude := fs.String("exclude", "", "导入文件夹时排除的文件或文件夹模式，多个用逗号分隔")
+	followSymlinks := fs.Bool("follow-symlinks", false, "导入文件夹时跟随符号链接")
+	summaryPath := fs.String("summary", "", "JSON汇总的输出文件，默认输出到标准输出")
+	historyPath := fs.String("history", cliJobHistoryFile, "任务历史文件，为空时不记录")
+	resume := fs.Bool("resume", false, "继续上次被中断的任务（如程序崩溃或被结束时未完成的任务）")
+	quiet := fs.Bool("quiet", false, "不输出进度")
+	merge := fs.Bool("merge", false, "把所有输入文件按顺序合并为一个文件，文件夹中的文件按文件名排序")
+	crossfade := fs.Float64("crossfade", 0, "合并时相邻片段之间的转场时长（秒），需要重新编码")
+	transition := fs.String("transition", "", "合并时的转场效果: "+strings.Join(mergeTransitions, "、")+"，默认 fade")
+
+	if err := fs.Parse(args); err != nil {
+		return CLIExitUsage
+	}
+	params.WatermarkPlacement = WatermarkPlacement(*placement)
+	params.Rotate = VideoRotate(*rotate)
+	params.RateControl = RateControlMode(*rateControl)
+	if *quality != -1 {
+		params.Quality = quality
+	}
+	params.Container = OutputContainer(*container)
+	params.Trim.Mode = TrimMode(*trimMode)
+	params.Segment.Mode = SegmentMode(*segmentMode)
+	params.AudioChannels = AudioChannels(*channels)
+	params.Streams.AudioTracks = AudioTrackMode(*audioTracks)
+	params.Streams.Languages = splitCLIList(*languages)
+	params.BurnSubtitle.Source = SubtitleSource(*burnSubtitle)
+	params.BurnSubtitle.Position = SubtitlePosition(*subtitlePosition)
+	params.SoftSubtitles.Tracks = parseCLISubtitleTracks(*muxSubtitles)
+	params.SoftSubtitles.Extract = SubtitleFormat(*extractSubtitles)
+
+	initConf()
+	// 参数优先级: 命令行中显式指定的参数 > 参数文件 > 预设 > 命令行参数默认值
+	baseParams := params
+	if *presetName != "" {
+		preset, ok := GetTranscodePreset(*presetName)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "预设不存在: %s\n", *presetName)
+			return CLIExitUsage
+		}
+		baseParams = preset.Params
+	}
+	if *paramsFile != "" {
+		fileParams, err := loadCLIParamsFile(*paramsFile, baseParams)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "读取参数文件失败: %v\n", err)
+			return CLIExitUsage
+		}
+		baseParams = fileParams
+	}
+	params = overrideCLIParams(fs, params, baseParams)
+	if err := validateTranscodeParams(params); err != nil {
+		fmt.Fprintf(os.Stderr, "参数无效: %v\n", err)
+		return CLIExitUsage
+	}
+
+	// 命令行参数只对本次运行生效，不写回配置文件
+	if *outputDirectory != "" {
+		Config.OutputDirectory = *outputDirectory
+	}
+	if *hardwareBackend != "" {
+		if err := ValidateHardwareBackend(HardwareBackend(*hardwareBackend)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return CLIExitUsage
+		}
+		Config.HardwareBackend = *hardwareBackend
+	}
+	if *vaapiDevice != "" {
+		Config.VAAPIDevice = *vaapiDevice
+	}
+	if *parallel > 0 {
+		Config.MaxParallelJobs = *parallel
+	}
+	if *onExists != "" {
+		if !isValidOutputCollisionPolicy(OutputCollisionPolicy(*onExists)) {
+			fmt.Fprintf(os.Stderr, "无效的 -on-exists: %s\n", *onExists)
+			return CLIExitUsage
+		}
+		Config.OutputCollisionPolicy = *onExists
+	}
+	if *nameTemplate != "" {
+		if err := ValidateOutputNameTemplate(*nameTemplate); err != nil {
+			fmt.Fprintf(os.Stderr, "无效的 -name-template: %v\n", err)
+			return CLIExitUsage
+		}
+		Config.OutputNameTemplate = *nameTemplate
+	}
+	importOptions := GetImportOptions()
+	if *include != "" {
+		importOptions.IncludePatterns = splitCLIList(*include)
+	}
+	if *exclude != "" {
+		importOptions.ExcludePatterns = splitCLIList(*exclude)
+	}
+	if *followSymlinks {
+		importOptions.FollowSymlinks = true
+	}
+	if err := ValidateImportOptions(importOptions); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return CLIExitUsage
+	}
+
+	if fs.NArg() == 0 && !*resume {
+		fs.Usage()
+		return CLIExitUsage
+	}
+
+	var history *JobHistory
+	if *historyPath != "" {
+		var err error
+		history, err = OpenJobHistory(*historyPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "警告: %v，本次运行不记录任务历史\n", err)
+		}
+		defer history.Close()
+	}
+
+	// 上次未结束的任务标记为失败，指定 -resume 时使用原来的参数重新运行
+	var jobs []TranscodeJob
+	for _, record := range history.RecoverInterrupted() {
+		if *resume && FileExists(record.Path) {
+			jobs = append(jobs, record.Job())
+		}
+	}
+	if *resume {
+		fmt.Fprintf(os.Stderr, "继续 %d 个上次被中断的任务\n", len(jobs))
+	}
+
+	inputs := expandCLIInputs(fs.Args())
+	files := CollectVideoFiles(inputs, importOptions)
+	switch {
+	case *subtitleJob != "":
+		// 直接指定的字幕文件转换格式，视频文件导出内嵌字幕
+		options := &SubtitleJobOptions{Format: SubtitleFormat(*subtitleJob)}
+		if err := validateSubtitleFormat(options.Format); err != nil {
+			fmt.Fprintf(os.Stderr, "参数无效: %v\n", err)
+			return CLIExitUsage
+		}
+		for _, input := range inputs {
+			if isSubtitleFile(input) && FileExists(input) {
+				jobs = append(jobs, TranscodeJob{ID: GetXid(), Type: TranscodeJobType_Subtitle, Path: input, Params: params, Subtitle: options})
+			}
+		}
+		for _, file := range files {
+			jobs = append(jobs, TranscodeJob{ID: GetXid(), Type: TranscodeJobType_Subtitle, Path: file.Path, Params: params, BaseDir: file.BaseDir, Subtitle: options})
+		}
+	case *merge && len(files) > 0:
+		// 合并为一个任务，输出文件以第一个文件命名
+		options := &MergeOptions{Crossfade: *crossfade, Transition: *transition}
+		for _, file := range files {
+			options.Inputs = append(options.Inputs, file.Path)
+		}
+		job := TranscodeJob{ID: GetXid(), Type: TranscodeJobType_Merge, Path: files[0].Path, Params: params, BaseDir: files[0].BaseDir, Merge: options}
+		if err := validateMergeJob(job); err != nil {
+			fmt.Fprintf(os.Stderr, "参数无效: %v\n", err)
+			return CLIExitUsage
+		}
+		jobs = append(jobs, job)
+	case !*merge:
+		for _, file := range files {
+			jobs = append(jobs, TranscodeJob{ID: GetXid(), Path: file.Path, Params: params, BaseDir: file.BaseDir})
+		}
+	}
+	if len(jobs) == 0 {
+		fmt.Fprintln(os.Stderr, "没有找到可处理的视频文件")
+		return CLIExitNoInput
+	}
+
+	var progressOutput io.Writer = os.Stderr
+	if *quiet {
+		progressOutput = io.Discard
+	}
+	summary := runCLIJobs(jobs, history, progressOutput)
+
+	if err := writeCLISummary(*summaryPath, summary); err != nil {
+		fmt.Fprintf(os.Stderr, "写入汇总失败: %v\n", err)
+	}
+	return summary.ExitCode
+}
+
+// runCLIJobs 通过转码队列运行所有任务并等待完成
+func runCLIJobs(jobs []TranscodeJob, history *JobHistory, progressOutput io.Writer) CLISummary {
+	reporter := newCLIReporter(jobs, progressOutput)
+	ctx := WithEventListener(context.Background(), reporter.handleEvent)
+	queue := NewTranscodeQueue(ctx, history)
+
+	// Ctrl+C 取消整个批次，已转码的部分文件会被删除
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	var cancelled atomic.Bool
+	go func() {
+		if _, ok := <-interrupt; ok {
+			cancelled.Store(true)
+			fmt.Fprintln(progressOutput, "\n正在取消...")
+			queue.CancelAll()
+		}
+	}()
+
+	summary := CLISummary{StartedAt: time.Now(), Total: len(jobs)}
+	fmt.Fprintf(progressOutput, "共 %d 个文件，同时运行 %d 个任务，输出目录: %s\n", len(jobs), GetMaxParallelJobs(), GetOutputDirectory())
+	queue.Submit(jobs)
+	<-reporter.done
+
+	summary.FinishedAt = time.Now()
+	summary.ElapsedSeconds = summary.FinishedAt.Sub(summary.StartedAt).Seconds()
+	summary.Results = reporter.orderedResults()
+	for _, result := range summary.Results {
+		switch result.Status {
+		case TranscodeResultStatus_Success:
+			summary.Completed++
+		case TranscodeResultStatus_Cancelled:
+			summary.Cancelled++
+		case TranscodeResultStatus_Skipped:
+			summary.Skipped++
+		default:
+			summary.Failed++
+		}
+	}
+	// 被取消前就被移出队列的任务没有结果
+	summary.Cancelled += summary.Total - len(summary.Results)
+
+	switch {
+	case cancelled.Load():
+		summary.ExitCode = CLIExitCancelled
+	case summary.Failed > 0:
+		summary.ExitCode = CLIExitFailed
+	default:
+		summary.ExitCode = CLIExitOK
+	}
+	fmt.Fprintf(progressOutput, "完成: 成功 %d，失败 %d，跳过 %d，取消 %d，耗时 %s\n",
+		summary.Completed, summary.Failed, summary.Skipped, summary.Cancelled, formatCLIDuration(summary.ElapsedSeconds))
+	return summary
+}
+
+// cliReporter 把队列事件输出到终端，并收集每个任务的结果
+type cliReporter struct {
+	mu        sync.Mutex
+	out       io.Writer
+	names     map[string]string
+	order     map[string]int
+	results   map[string]TranscodeResult
+	lastPrint map[string]time.Time
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newCLIReporter(jobs []TranscodeJob, out io.Writer) *cliReporter {
+	r := &cliReporter{
+		out:       out,
+		names:     map[string]string{},
+		order:     map[string]int{},
+		results:   map[string]TranscodeResult{},
+		lastPrint: map[string]time.Time{},
+		done:      make(chan struct{}),
+	}
+	for i, job := range jobs {
+		r.names[job.ID] = GetFileNameFromPath(job.Path, true)
+		r.order[job.ID] = i
+	}
+	return r
+}
+
+// cliProgressInterval 终端中同一任务进度的输出间隔
+const cliProgressInterval = 2 * time.Second
+
+func (r *cliReporter) handleEvent(name string, data ...interface{}) {
+	if len(data) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch event := data[0].(type) {
+	case TranscodeProgress:
+		if !event.Completed && time.Since(r.lastPrint[event.ID]) < cliProgressInterval {
+			return
+		}
+		r.lastPrint[event.ID] = time.Now()
+		if event.Duration > 0 {
+			fmt.Fprintf(r.out, "  %s  %6.2f%%  %.2fx  剩余 %s\n", r.names[event.ID], event.Percentage, event.Speed, formatCLIDuration(event.EtaSeconds))
+		} else {
+			fmt.Fprintf(r.out, "  %s  %s\n", r.names[event.ID], event.OutTime)
+		}
+	case TranscodeJobEvent:
+		switch event.Status {
+		case TranscodeJobStatus_Running:
+			fmt.Fprintf(r.out, "[开始] %s\n", r.names[event.ID])
+		case TranscodeJobStatus_Completed, TranscodeJobStatus_Failed, TranscodeJobStatus_Cancelled, TranscodeJobStatus_Skipped:
+			if event.Result != nil {
+				r.results[event.ID] = withoutThumbnails(*event.Result)
+				fmt.Fprintf(r.out, "[%s] %s -> %s %s\n", event.Status, r.names[event.ID], event.Result.OutputPath, event.Result.Error)
+				for _, segment := range event.Result.Segments[min(1, len(event.Result.Segments)):] {
+					fmt.Fprintf(r.out, "           -> %s\n", segment.OutputPath)
+				}
+			} else {
+				fmt.Fprintf(r.out, "[%s] %s\n", event.Status, r.names[event.ID])
+			}
+		}
+	case TranscodeBatchStatus:
+		if event.Finished {
+			r.closeOnce.Do(func() { close(r.done) })
+		}
+	}
+}
+
+// orderedResults 按输入顺序返回结果
+func (r *cliReporter) orderedResults() []TranscodeResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	results := make([]TranscodeResult, len(r.order))
+	filled := make([]bool, len(r.order))
+	for id, result := range r.results {
+		results[r.order[id]] = result
+		filled[r.order[id]] = true
+	}
+	ordered := make([]TranscodeResult, 0, len(r.results))
+	for i, result := range results {
+		if filled[i] {
+			ordered = append(ordered, result)
+		}
+	}
+	return ordered
+}
+
+// runCLIHistory 查询任务历史，以JSON输出到标准输出
+func runCLIHistory(args []string) int {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	historyPath := fs.String("history", cliJobHistoryFile, "任务历史文件，查询界面模式的历史时使用 "+jobHistoryFile)
+	status := fs.String("status", "", "任务状态，多个用逗号分隔: pending、running、completed、failed、paused、cancelled、skipped")
+	query := fs.String("q", "", "输入文件路径包含的文字")
+	interrupted := fs.Bool("interrupted", false, "只显示被中断的任务")
+	since := fs.Duration("since", 0, "只显示最近一段时间内提交的任务，如 24h")
+	limit := fs.Int("limit", 0, "最多显示的数量，0为不限制")
+	if err := fs.Parse(args); err != nil {
+		return CLIExitUsage
+	}
+
+	initConf()
+	history, err := OpenJobHistory(*historyPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return CLIExitFailed
+	}
+	defer history.Close()
+
+	filter := JobHistoryFilter{Query: *query, Interrupted: *interrupted, Limit: *limit}
+	for _, s := range splitCLIList(*status) {
+		filter.Status = append(filter.Status, TranscodeJobStatus(s))
+	}
+	if *since > 0 {
+		filter.Since = time.Now().Add(-*since).Unix()
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(history.List(filter)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return CLIExitFailed
+	}
+	return CLIExitOK
+}
+
+// loadCLIParamsFile 从JSON文件读取转码参数，文件中没有的字段保留 base 中的值
+func loadCLIParamsFile(path string, base TranscodeParams) (TranscodeParams, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return base, err
+	}
+	if err := json.Unmarshal(content, &base); err != nil {
+		return base, err
+	}
+	return base, nil
+}
+
+// overrideCLIParams 用命令行中显式指定的参数覆盖 base 中的值
+func overrideCLIParams(fs *flag.FlagSet, flagParams, base TranscodeParams) TranscodeParams {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	overrides := []struct {
+		flag  string
+		apply func()
+	}{
+		{"vcodec", func() { base.VideoCodec = flagParams.VideoCodec }},
+		{"acodec", func() { base.AudioCodec = flagParams.AudioCodec }},
+		{"height", func() { base.VideoHeight = flagParams.VideoHeight }},
+		{"fps", func() { base.Fps = flagParams.Fps }},
+		{"vbitrate", func() { base.VideoBitrate = flagParams.VideoBitrate }},
+		{"abitrate", func() { base.AudioBitrate = flagParams.AudioBitrate }},
+		{"sample-rate", func() { base.SampleRate = flagParams.SampleRate }},
+		{"channels", func() { base.AudioChannels = flagParams.AudioChannels }},
+		{"mute", func() { base.MuteAudio = flagParams.MuteAudio }},
+		{"audio-tracks", func() { base.Streams.AudioTracks = flagParams.Streams.AudioTracks }},
+		{"languages", func() { base.Streams.Languages = flagParams.Streams.Languages }},
+		{"default-language", func() { base.Streams.DefaultLanguage = flagParams.Streams.DefaultLanguage }},
+		{"subtitles", func() { base.Streams.Subtitles = flagParams.Streams.Subtitles }},
+		{"attachments", func() { base.Streams.Attachments = flagParams.Streams.Attachments }},
+		{"watermark-text", func() { base.WatermarkContent = flagParams.WatermarkContent }},
+		{"watermark-image", func() { base.WatermarkImage = flagParams.WatermarkImage }},
+		{"watermark-placement", func() { base.WatermarkPlacement = flagParams.WatermarkPlacement }},
+		{"burn-subtitle", func() { base.BurnSubtitle.Source = flagParams.BurnSubtitle.Source }},
+		{"burn-subtitle-file", func() {
+			base.BurnSubtitle.File = flagParams.BurnSubtitle.File
+			if !explicit["burn-subtitle"] {
+				base.BurnSubtitle.Source = SubtitleSource_File
+			}
+		}},
+		{"burn-subtitle-stream", func() { base.BurnSubtitle.StreamIndex = flagParams.BurnSubtitle.StreamIndex }},
+		{"subtitle-font", func() { base.BurnSubtitle.Font = flagParams.BurnSubtitle.Font }},
+		{"subtitle-size", func() { base.BurnSubtitle.FontSize = flagParams.BurnSubtitle.FontSize }},
+		{"subtitle-color", func() { base.BurnSubtitle.Color = flagParams.BurnSubtitle.Color }},
+		{"subtitle-outline-color", func() { base.BurnSubtitle.OutlineColor = flagParams.BurnSubtitle.OutlineColor }},
+		{"subtitle-outline", func() { base.BurnSubtitle.Outline = flagParams.BurnSubtitle.Outline }},
+		{"subtitle-bold", func() { base.BurnSubtitle.Bold = flagParams.BurnSubtitle.Bold }},
+		{"subtitle-italic", func() { base.BurnSubtitle.Italic = flagParams.BurnSubtitle.Italic }},
+		{"subtitle-position", func() { base.BurnSubtitle.Position = flagParams.BurnSubtitle.Position }},
+		{"subtitle-margin", func() { base.BurnSubtitle.Margin = flagParams.BurnSubtitle.Margin }},
+		{"mux-sidecars", func() { base.SoftSubtitles.Sidecars = flagParams.SoftSubtitles.Sidecars }},
+		{"mux-subtitle", func() { base.SoftSubtitles.Tracks = flagParams.SoftSubtitles.Tracks }},
+		{"extract-subtitles", func() { base.SoftSubtitles.Extract = flagParams.SoftSubtitles.Extract }},
+		{"rotate", func() { base.Rotate = flagParams.Rotate }},
+		{"gpu", func() { base.UseGpu = flagParams.UseGpu }},
+		{"threads", func() { base.CpuThreads = flagParams.CpuThreads }},
+		{"rc", func() { base.RateControl = flagParams.RateControl }},
+		{"crf", func() { base.Quality = flagParams.Quality }},
+		{"maxrate", func() { base.MaxRate = flagParams.MaxRate }},
+		{"bufsize", func() { base.BufSize = flagParams.BufSize }},
+		{"encoder-preset", func() { base.EncoderPreset = flagParams.EncoderPreset }},
+		{"tune", func() { base.Tune = flagParams.Tune }},
+		{"profile", func() { base.Profile = flagParams.Profile }},
+		{"level", func() { base.Level = flagParams.Level }},
+		{"pix-fmt", func() { base.PixelFormat = flagParams.PixelFormat }},
+		{"gop", func() { base.GOP = flagParams.GOP }},
+		{"two-pass", func() { base.TwoPass = flagParams.TwoPass }},
+		{"target-size", func() { base.TargetSizeMB = flagParams.TargetSizeMB }},
+		{"container", func() { base.Container = flagParams.Container }},
+		{"start", func() { base.Trim.Start = flagParams.Trim.Start }},
+		{"end", func() { base.Trim.End = flagParams.Trim.End }},
+		{"duration", func() { base.Trim.Duration = flagParams.Trim.Duration }},
+		{"trim-mode", func() { base.Trim.Mode = flagParams.Trim.Mode }},
+		{"segment", func() { base.Segment.Mode = flagParams.Segment.Mode }},
+		{"segment-time", func() { base.Segment.Duration = flagParams.Segment.Duration }},
+		{"segment-size", func() { base.Segment.SizeMB = flagParams.Segment.SizeMB }},
+		{"scene-threshold", func() { base.Segment.SceneThreshold = flagParams.Segment.SceneThreshold }},
+		{"segment-pattern", func() { base.Segment.Pattern = flagParams.Segment.Pattern }},
+		{"loudnorm", func() { base.Loudness.Enabled = flagParams.Loudness.Enabled }},
+		{"loudnorm-i", func() { base.Loudness.Integrated = flagParams.Loudness.Integrated }},
+		{"loudnorm-tp", func() { base.Loudness.TruePeak = flagParams.Loudness.TruePeak }},
+		{"loudnorm-lra", func() { base.Loudness.LRA = flagParams.Loudness.LRA }},
+	}
+	for _, override := range overrides {
+		if explicit[override.flag] {
+			override.apply()
+		}
+	}
+	return base
+}
+
+// expandCLIInputs 展开输入中的通配符（Windows的命令行不会自动展开），并转换为绝对路径
+func expandCLIInputs(inputs []string) []string {
+	var paths []string
+	for _, input := range inputs {
+		matches := []string{input}
+		if strings.ContainsAny(input, "*?[") {
+			if globbed, err := filepath.Glob(input); err == nil && len(globbed) > 0 {
+				matches = globbed
+			}
+		}
+		for _, match := range matches {
+			if absPath, err := filepath.Abs(match); err == nil {
+				match = absPath
+			}
+			paths = append(paths, match)
+		}
+	}
+	return paths
+}
+
+func splitCLIList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseCLISubtitleTracks 解析 -mux-subtitle 的字幕文件列表，每项为 文件 或 语言=文件
+func parseCLISubtitleTracks(value string) []SubtitleTrack {
+	var tracks []SubtitleTrack
+	for _, item := range splitCLIList(value) {
+		var track SubtitleTrack
+		if language, file, ok := strings.Cut(item, "="); ok && isLanguageCode(language) {
+			track.Language, item = language, file
+		}
+		track.File = item
+		if absPath, err := filepath.Abs(item); err == nil {
+			track.File = absPath
+		}
+		tracks = append(tracks, track)
+	}
+	return tracks
+}
+
+// writeCLISummary 输出JSON汇总，未指定文件时输出到标准输出
+func writeCLISummary(path string, summary CLISummary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	if path == "" || path == "-" {
+		_, err = fmt.Fprintln(os.Stdout, string(data))
+		return err
+	}
+	return WriteStringToFile(path, string(data))
+}
+
+func formatCLIDuration(seconds float64) string {
+	d := time.Duration(seconds * float64(time.Second)).Round(time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
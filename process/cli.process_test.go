@@ -0,0 +1,121 @@
+package process
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// chdirTemp 切换到临时目录，RunCLI 会在当前目录读取或创建配置文件和任务历史
+func chdirTemp(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func TestOverrideCLIParams(t *testing.T) {
+	preset := TranscodeParams{VideoCodec: "h264", AudioCodec: "aac", VideoHeight: "720", Fps: "30", UseGpu: true, CpuThreads: 4}
+	tests := []struct {
+		name string
+		args []string
+		want TranscodeParams
+	}{
+		{name: "没有指定参数时使用预设", want: preset},
+		{
+			name: "只覆盖指定的参数",
+			args: []string{"-vcodec", "h265", "-threads", "2"},
+			want: TranscodeParams{VideoCodec: "h265", AudioCodec: "aac", VideoHeight: "720", Fps: "30", UseGpu: true, CpuThreads: 2},
+		},
+		{
+			name: "显式指定与默认值相同的参数也会覆盖",
+			args: []string{"-height", "copy", "-gpu=false"},
+			want: TranscodeParams{VideoCodec: "h264", AudioCodec: "aac", VideoHeight: "copy", Fps: "30", CpuThreads: 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			var flagParams TranscodeParams
+			fs.StringVar(&flagParams.VideoCodec, "vcodec", "copy", "")
+			fs.StringVar(&flagParams.AudioCodec, "acodec", "copy", "")
+			fs.StringVar(&flagParams.VideoHeight, "height", "copy", "")
+			fs.StringVar(&flagParams.Fps, "fps", "copy", "")
+			fs.BoolVar(&flagParams.UseGpu, "gpu", false, "")
+			fs.IntVar(&flagParams.CpuThreads, "threads", 0, "")
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			if got := overrideCLIParams(fs, flagParams, preset); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("overrideCLIParams() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadCLIParamsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "params.json")
+	if err := os.WriteFile(path, []byte(`{"video_codec": "h265", "video_height": "1080"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	base := TranscodeParams{VideoCodec: "h264", AudioCodec: "aac", VideoHeight: "720"}
+	got, err := loadCLIParamsFile(path, base)
+	if err != nil {
+		t.Fatalf("loadCLIParamsFile() error = %v", err)
+	}
+	if got.VideoCodec != "h265" || got.VideoHeight != "1080" || got.AudioCodec != "aac" {
+		t.Errorf("loadCLIParamsFile() = %+v, 文件中没有的字段应保留预设的值", got)
+	}
+	if _, err := loadCLIParamsFile(filepath.Join(t.TempDir(), "missing.json"), base); err == nil {
+		t.Error("文件不存在时应返回错误")
+	}
+}
+
+func TestRunCLIExitCodes(t *testing.T) {
+	dir := chdirTemp(t)
+	stderr := os.Stderr
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stderr = devNull
+	t.Cleanup(func() {
+		os.Stderr = stderr
+		devNull.Close()
+	})
+	invalidParams := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalidParams, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "未知参数", args: []string{"-unknown"}, want: CLIExitUsage},
+		{name: "没有输入文件", args: nil, want: CLIExitUsage},
+		{name: "无效的同名文件策略", args: []string{"-on-exists", "ask", "a.mp4"}, want: CLIExitUsage},
+		{name: "无效的文件名模板", args: []string{"-name-template", "{unknown}.{ext}", "a.mp4"}, want: CLIExitUsage},
+		{name: "无效的导入模式", args: []string{"-include", "[", "a.mp4"}, want: CLIExitUsage},
+		{name: "参数文件无效", args: []string{"-params", invalidParams, "a.mp4"}, want: CLIExitUsage},
+		{name: "预设不存在", args: []string{"-preset", "missing", "a.mp4"}, want: CLIExitUsage},
+		{name: "没有找到视频文件", args: []string{"-quiet", filepath.Join(dir, "missing.mp4")}, want: CLIExitNoInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RunCLI(tt.args); got != tt.want {
+				t.Errorf("RunCLI(%q) = %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+
+package process
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// 非Windows平台没有控制台窗口需要隐藏
+func hideCommandWindow(cmd *exec.Cmd) {}
+
+// 非Windows平台的命令行程序本身就连接着终端
+func attachParentConsole() {}
+
+// suspendProcess 暂停进程（SIGSTOP）
+func suspendProcess(p *os.Process) error {
+	return p.Signal(syscall.SIGSTOP)
+}
+
+// resumeProcess 恢复被暂停的进程（SIGCONT）
+func resumeProcess(p *os.Process) error {
+	return p.Signal(syscall.SIGCONT)
+}
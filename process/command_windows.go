@@ -0,0 +1,71 @@
+//go:build windows
+
+package process
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+const processSuspendResume = 0x0800 // PROCESS_SUSPEND_RESUME
+
+var (
+	ntdll             = syscall.NewLazyDLL("ntdll.dll")
+	ntSuspendProcess  = ntdll.NewProc("NtSuspendProcess")
+	ntResumeProcess   = ntdll.NewProc("NtResumeProcess")
+	kernel32          = syscall.NewLazyDLL("kernel32.dll")
+	procAttachConsole = kernel32.NewProc("AttachConsole")
+)
+
+// 在Windows上隐藏控制台窗口
+func hideCommandWindow(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+}
+
+// suspendProcess 暂停进程（NtSuspendProcess）
+func suspendProcess(p *os.Process) error {
+	return callProcessProc(ntSuspendProcess, p)
+}
+
+// resumeProcess 恢复被暂停的进程（NtResumeProcess）
+func resumeProcess(p *os.Process) error {
+	return callProcessProc(ntResumeProcess, p)
+}
+
+func callProcessProc(proc *syscall.LazyProc, p *os.Process) error {
+	if err := proc.Find(); err != nil {
+		return err
+	}
+	handle, err := syscall.OpenProcess(processSuspendResume, false, uint32(p.Pid))
+	if err != nil {
+		return err
+	}
+	defer syscall.CloseHandle(handle)
+	status, _, _ := proc.Call(uintptr(handle))
+	if status != 0 {
+		return fmt.Errorf("%s 返回状态 0x%x", proc.Name, status)
+	}
+	return nil
+}
+
+// attachParentConsole 连接到启动程序的控制台，窗口程序在命令行模式下才能输出到终端
+//
+// 标准输出已被重定向到文件或管道时保持不变
+func attachParentConsole() {
+	const attachParentProcess = ^uintptr(0) // ATTACH_PARENT_PROCESS (DWORD)-1
+	if r, _, _ := procAttachConsole.Call(attachParentProcess); r == 0 {
+		return
+	}
+	console, err := os.OpenFile("CONOUT$", os.O_RDWR, 0)
+	if err != nil {
+		return
+	}
+	if _, err := os.Stdout.Stat(); err != nil {
+		os.Stdout = console
+	}
+	if _, err := os.Stderr.Stat(); err != nil {
+		os.Stderr = console
+	}
+}
@@ -15,7 +15,15 @@ const configFile = "config.yaml"
 var Config *ConfigData
 
 type ConfigData struct {
-	OutputDirectory string `yaml:"outputDirectory" json:"outputDirectory"`
+	OutputDirectory       string            `yaml:"outputDirectory" json:"outputDirectory"`
+	MaxParallelJobs       int               `yaml:"maxParallelJobs" json:"maxParallelJobs"`
+	OutputCollisionPolicy string            `yaml:"outputCollisionPolicy" json:"outputCollisionPolicy"` // 输出文件同名时的处理策略: skip、overwrite、rename、fail
+	OutputNameTemplate    string            `yaml:"outputNameTemplate" json:"outputNameTemplate"`       // 输出文件名模板，如 {name}_{height}p_{codec}.{ext}
+	Import                ImportOptions     `yaml:"import" json:"import"`                               // 导入文件夹时的过滤选项
+	Presets               []TranscodePreset `yaml:"presets" json:"presets"`                             // 用户自定义的转码参数预设
+	DefaultPreset         string            `yaml:"defaultPreset" json:"defaultPreset"`                 // 默认预设名称
+	HardwareBackend       string            `yaml:"hardwareBackend" json:"hardwareBackend"`             // 使用GPU时的硬件编码器: nvenc、qsv、amf、vaapi、videotoolbox，为空时自动选择
+	VAAPIDevice           string            `yaml:"vaapiDevice" json:"vaapiDevice"`                     // VA-API设备，默认 /dev/dri/renderD128
 }
 
 func initConf() {
@@ -40,11 +48,22 @@ func initConf() {
 	if err != nil {
 		log.Fatalf("config Init Unmarshal: %v", err)
 	}
+	if c.OutputNameTemplate != "" {
+		if err := ValidateOutputNameTemplate(c.OutputNameTemplate); err != nil {
+			log.Printf("输出文件名模板无效: %v，将使用默认模板", err)
+			c.OutputNameTemplate = ""
+		}
+	}
+	sanitizeConfigPresets(c)
 	Config = c
 }
 func getDefaultConfig() *ConfigData {
 	return &ConfigData{
-		OutputDirectory: "",
+		OutputDirectory:       "",
+		MaxParallelJobs:       1,
+		OutputCollisionPolicy: string(OutputCollisionPolicy_Rename),
+		OutputNameTemplate:    defaultOutputNameTemplate,
+		Presets:               []TranscodePreset{},
 	}
 }
 
@@ -97,3 +116,15 @@ func GetOutputDirectory() string {
 	outputDirectory = filepath.Clean(outputDirectory)
 	return outputDirectory
 }
+
+// GetMaxParallelJobs 获取同时运行的转码任务数，最少为1，最多不超过CPU线程数
+func GetMaxParallelJobs() int {
+	maxParallelJobs := Config.MaxParallelJobs
+	if maxParallelJobs < 1 {
+		maxParallelJobs = 1
+	}
+	if cpuThread := GetCPUThreadCount(); maxParallelJobs > cpuThread {
+		maxParallelJobs = cpuThread
+	}
+	return maxParallelJobs
+}
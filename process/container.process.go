@@ -0,0 +1,274 @@
+package process
+
+import (
+	"fmt"
+	"strings"
+)
+
+type OutputContainer string
+
+const (
+	OutputContainer_Source OutputContainer = ""     // 与输入文件相同
+	OutputContainer_MP4    OutputContainer = "mp4"  // MP4
+	OutputContainer_MKV    OutputContainer = "mkv"  // Matroska
+	OutputContainer_MOV    OutputContainer = "mov"  // QuickTime
+	OutputContainer_WebM   OutputContainer = "webm" // WebM
+	OutputContainer_TS     OutputContainer = "ts"   // MPEG-TS
+	OutputContainer_M4A    OutputContainer = "m4a"  // 只输出音频 AAC/ALAC
+	OutputContainer_MP3    OutputContainer = "mp3"  // 只输出音频 MP3
+)
+
+// containerFormat 输出封装格式及其支持的编码
+type containerFormat struct {
+	Name         OutputContainer
+	Ext          string
+	Muxer        string   // FFmpeg的 -f 参数
+	AudioOnly    bool     // 只输出音频
+	VideoCodecs  []string // 支持的视频编码（ffprobe名称），为nil时不限制
+	AudioCodecs  []string // 支持的音频编码（ffprobe名称），为nil时不限制
+	DefaultVideo string   // 直接复制的视频流不兼容时改用的视频编码，为空时无法自动转换
+	DefaultAudio string   // 直接复制的音频流不兼容时改用的音频编码
+	Flags        []string // 封装参数
+
+	SubtitleCodecs  []string // 支持的字幕编码（ffprobe名称），为nil时不支持字幕
+	DefaultSubtitle string   // 文字字幕不兼容时转换成的字幕编码（FFmpeg编码器名称），为空时无法转换
+	Attachments     bool     // 支持附件（如字体）
+}
+
+// containerFormats 封装格式与编码的兼容表
+var containerFormats = []containerFormat{
+	{
+		Name:         OutputContainer_MP4,
+		Ext:          ".mp4",
+		Muxer:        "mp4",
+		VideoCodecs:  []string{"h264", "hevc", "av1", "vp9", "mpeg4", "mpeg2video"},
+		AudioCodecs:  []string{"aac", "mp3", "ac3", "eac3", "opus", "flac", "alac"},
+		DefaultVideo: "h264",
+		DefaultAudio: "aac",
+		// 把索引移到文件开头，网页可以边下载边播放
+		Flags:           []string{"-movflags", "+faststart"},
+		SubtitleCodecs:  []string{"mov_text"},
+		DefaultSubtitle: "mov_text",
+	},
+	{
+		Name:         OutputContainer_MKV,
+		Ext:          ".mkv",
+		Muxer:        "matroska",
+		DefaultVideo: "h264",
+		DefaultAudio: "aac",
+		// Matroska不支持MP4的mov_text字幕，需要转换为SRT
+		SubtitleCodecs:  []string{"subrip", "ass", "ssa", "webvtt", "hdmv_pgs_subtitle", "dvd_subtitle", "dvb_subtitle"},
+		DefaultSubtitle: "srt",
+		Attachments:     true,
+	},
+	{
+		Name:            OutputContainer_MOV,
+		Ext:             ".mov",
+		Muxer:           "mov",
+		VideoCodecs:     []string{"h264", "hevc", "prores", "mpeg4", "mjpeg"},
+		AudioCodecs:     []string{"aac", "alac", "mp3", "ac3", "pcm_s16le", "pcm_s24le"},
+		DefaultVideo:    "h264",
+		DefaultAudio:    "aac",
+		Flags:           []string{"-movflags", "+faststart"},
+		SubtitleCodecs:  []string{"mov_text"},
+		DefaultSubtitle: "mov_text",
+	},
+	{
+		Name:            OutputContainer_WebM,
+		Ext:             ".webm",
+		Muxer:           "webm",
+		VideoCodecs:     []string{"vp8", "vp9", "av1"},
+		AudioCodecs:     []string{"opus", "vorbis"},
+		DefaultVideo:    "vp9",
+		DefaultAudio:    "opus",
+		SubtitleCodecs:  []string{"webvtt"},
+		DefaultSubtitle: "webvtt",
+	},
+	{
+		Name:         OutputContainer_TS,
+		Ext:          ".ts",
+		Muxer:        "mpegts",
+		VideoCodecs:  []string{"h264", "hevc", "mpeg2video", "mpeg1video"},
+		AudioCodecs:  []string{"aac", "mp3", "mp2", "ac3", "eac3", "opus"},
+		DefaultVideo: "h264",
+		DefaultAudio: "aac",
+		// MPEG-TS只能封装DVB图形字幕，文字字幕无法转换
+		SubtitleCodecs: []string{"dvb_subtitle"},
+	},
+	{
+		Name:         OutputContainer_M4A,
+		Ext:          ".m4a",
+		Muxer:        "ipod",
+		AudioOnly:    true,
+		AudioCodecs:  []string{"aac", "alac"},
+		DefaultAudio: "aac",
+		Flags:        []string{"-movflags", "+faststart"},
+	},
+	{
+		Name:         OutputContainer_MP3,
+		Ext:          ".mp3",
+		Muxer:        "mp3",
+		AudioOnly:    true,
+		AudioCodecs:  []string{"mp3"},
+		DefaultAudio: "mp3",
+	},
+}
+
+// paramCodecNames 参数中的编码名称与ffprobe编码名称的对应关系
+var paramCodecNames = map[string]string{
+	"h264": "h264",
+	"h265": "hevc",
+	"av1":  "av1",
+	"vp9":  "vp9",
+	"aac":  "aac",
+	"mp3":  "mp3",
+	"opus": "opus",
+}
+
+// sourceVideoCodecs ffprobe视频编码名称对应的参数编码名称，用于按源视频的编码重新编码
+var sourceVideoCodecs = map[string]string{
+	"h264": "h264",
+	"hevc": "h265",
+	"av1":  "av1",
+	"vp9":  "vp9",
+}
+
+// getContainerFormat 获取封装格式，为空表示与输入文件相同
+func getContainerFormat(container OutputContainer) (containerFormat, bool) {
+	for _, format := range containerFormats {
+		if format.Name == container {
+			return format, true
+		}
+	}
+	return containerFormat{}, false
+}
+
+// outputContainerFormat 获取实际输出的封装格式，与输入文件相同时按输入文件的扩展名查找，未知扩展名时返回false
+func outputContainerFormat(container OutputContainer, inputFilePath string) (containerFormat, bool) {
+	if container != OutputContainer_Source {
+		return getContainerFormat(container)
+	}
+	ext := FileExt(inputFilePath)
+	for _, format := range containerFormats {
+		if format.Ext == ext {
+			return format, true
+		}
+	}
+	return containerFormat{}, false
+}
+
+// validateContainer 校验封装格式名称
+func validateContainer(container OutputContainer) error {
+	if container == OutputContainer_Source {
+		return nil
+	}
+	if _, ok := getContainerFormat(container); !ok {
+		names := make([]string, 0, len(containerFormats))
+		for _, format := range containerFormats {
+			names = append(names, string(format.Name))
+		}
+		return fmt.Errorf("无效的输出格式: %s，可用: %s", container, strings.Join(names, "、"))
+	}
+	return nil
+}
+
+// supportsCodec 判断封装格式是否支持该编码，未知编码时不限制
+func supportsCodec(codecs []string, codec string) bool {
+	return codecs == nil || codec == "" || containsString(codecs, codec)
+}
+
+// resolveContainerParams 在启动FFmpeg前检查编码与封装格式是否兼容
+//
+// 明确选择的编码不兼容时返回错误；直接复制的流不兼容时改为重新编码成该格式的默认编码。
+// 只输出音频的格式会忽略视频参数。
+//
+// 返回值:
+//
+//	TranscodeParams: 实际使用的参数
+//	[]string: 自动修改参数的说明
+//	error: 无法兼容时返回错误
+func resolveContainerParams(params TranscodeParams, inputInfo *VideoInfo) (TranscodeParams, []string, error) {
+	format, ok := getContainerFormat(params.Container)
+	if !ok {
+		return params, nil, nil
+	}
+	var warnings []string
+	name := strings.ToUpper(string(format.Name))
+
+	if format.AudioOnly {
+		if usesTwoPass(params) {
+			return params, nil, fmt.Errorf("%s 只包含音频，不支持两遍编码和目标大小", name)
+		}
+		if videoCodecName(params) != "" || params.VideoHeight != "copy" || params.Fps != "copy" || params.Rotate != VideoRotate_copy || params.BurnSubtitle.Enabled() {
+			warnings = append(warnings, fmt.Sprintf("%s 只包含音频，已忽略视频参数", name))
+		}
+		params.VideoCodec, params.VideoHeight, params.Fps, params.Rotate = "copy", "copy", "copy", VideoRotate_copy
+		params.WatermarkContent, params.WatermarkImage = "", ""
+		params.BurnSubtitle = BurnSubtitleOptions{}
+		params.UseGpu = false
+	} else if codec := videoCodecName(params); codec != "" {
+		if !supportsCodec(format.VideoCodecs, paramCodecNames[codec]) {
+			if params.VideoCodec != "copy" {
+				return params, nil, fmt.Errorf("%s 不支持 %s 视频编码，可用: %s", name, codec, strings.Join(format.VideoCodecs, "、"))
+			}
+			// 加水印时默认使用的H.264不兼容
+			if format.DefaultVideo == "" {
+				return params, nil, fmt.Errorf("%s 不支持 %s 视频编码", name, codec)
+			}
+			params.VideoCodec = format.DefaultVideo
+			warnings = append(warnings, fmt.Sprintf("%s 不支持 %s 视频编码，已改为 %s 编码", name, codec, format.DefaultVideo))
+		}
+	} else if inputInfo != nil && !supportsCodec(format.VideoCodecs, inputInfo.VideoCodec) {
+		if format.DefaultVideo == "" {
+			return params, nil, fmt.Errorf("%s 不支持源视频的 %s 编码，需要选择兼容的视频编码", name, inputInfo.VideoCodec)
+		}
+		params.VideoCodec = format.DefaultVideo
+		warnings = append(warnings, fmt.Sprintf("%s 不支持源视频的 %s 编码，已改为 %s 编码", name, inputInfo.VideoCodec, format.DefaultVideo))
+	}
+
+	if params.AudioCodec != "copy" {
+		if !supportsCodec(format.AudioCodecs, paramCodecNames[params.AudioCodec]) {
+			return params, nil, fmt.Errorf("%s 不支持 %s 音频编码，可用: %s", name, params.AudioCodec, strings.Join(format.AudioCodecs, "、"))
+		}
+	} else if inputInfo != nil && !supportsCodec(format.AudioCodecs, inputInfo.AudioCodec) {
+		params.AudioCodec = format.DefaultAudio
+		warnings = append(warnings, fmt.Sprintf("%s 不支持源视频的 %s 音频，已改为 %s 编码", name, inputInfo.AudioCodec, format.DefaultAudio))
+	}
+	if format.AudioOnly && inputInfo != nil && inputInfo.AudioCodec == "" {
+		return params, nil, fmt.Errorf("源文件没有音频，不能输出为 %s", name)
+	}
+	return params, warnings, nil
+}
+
+// reencodeAudioCodec 直接复制的音频需要重新编码时使用的编码，优先使用输出格式的默认编码，其次与源音频相同，否则使用AAC
+func reencodeAudioCodec(container OutputContainer, sourceCodec string) string {
+	if format, ok := getContainerFormat(container); ok {
+		return format.DefaultAudio
+	}
+	if sourceCodec == "mp3" || sourceCodec == "opus" {
+		return sourceCodec
+	}
+	return "aac"
+}
+
+// muxArgs 生成封装参数，第一遍编码不写入文件，不需要这些参数
+func (f containerFormat) muxArgs(encoders encoderSelection, inputInfo *VideoInfo) []string {
+	args := append([]string{}, f.Flags...)
+	args = append(args, f.tagArgs(encoders, inputInfo)...)
+	return append(args, "-f", f.Muxer)
+}
+
+// tagArgs 生成流的标签参数，Apple设备只能播放标记为hvc1的H.265
+func (f containerFormat) tagArgs(encoders encoderSelection, inputInfo *VideoInfo) []string {
+	if f.Name == OutputContainer_MP4 || f.Name == OutputContainer_MOV {
+		if isHEVCEncoder(encoders.Video) || (encoders.Video == "copy" && inputInfo != nil && inputInfo.VideoCodec == "hevc") {
+			return []string{"-tag:v", "hvc1"}
+		}
+	}
+	return nil
+}
+
+// isHEVCEncoder 判断是否为H.265编码器
+func isHEVCEncoder(encoder string) bool {
+	return encoder == "libx265" || strings.HasPrefix(encoder, "hevc_")
+}
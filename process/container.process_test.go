@@ -0,0 +1,169 @@
+package process
+
+import "testing"
+
+func TestResolveContainerParams(t *testing.T) {
+	copyParams := func(container OutputContainer) TranscodeParams {
+		return TranscodeParams{
+			Container: container, VideoCodec: "copy", AudioCodec: "copy",
+			VideoHeight: "copy", Fps: "copy", Rotate: VideoRotate_copy,
+		}
+	}
+	with := func(params TranscodeParams, change func(*TranscodeParams)) TranscodeParams {
+		change(&params)
+		return params
+	}
+	h264AAC := &VideoInfo{VideoCodec: "h264", AudioCodec: "aac"}
+	h264Opus := &VideoInfo{VideoCodec: "h264", AudioCodec: "opus"}
+	hevcAC3 := &VideoInfo{VideoCodec: "hevc", AudioCodec: "ac3"}
+
+	tests := []struct {
+		name         string
+		params       TranscodeParams
+		inputInfo    *VideoInfo
+		wantVideo    string
+		wantAudio    string
+		wantWarnings int
+		wantErr      bool
+	}{
+		{
+			name:      "与输入文件相同时不检查",
+			params:    with(copyParams(OutputContainer_Source), func(p *TranscodeParams) { p.AudioCodec = "opus" }),
+			inputInfo: h264AAC,
+			wantVideo: "copy",
+			wantAudio: "opus",
+		},
+		{
+			name:      "MP4支持Opus",
+			params:    with(copyParams(OutputContainer_MP4), func(p *TranscodeParams) { p.AudioCodec = "opus" }),
+			inputInfo: h264AAC,
+			wantVideo: "copy",
+			wantAudio: "opus",
+		},
+		{
+			name:      "MP4直接复制Opus",
+			params:    copyParams(OutputContainer_MP4),
+			inputInfo: h264Opus,
+			wantVideo: "copy",
+			wantAudio: "copy",
+		},
+		{
+			name:    "MOV不支持Opus",
+			params:  with(copyParams(OutputContainer_MOV), func(p *TranscodeParams) { p.AudioCodec = "opus" }),
+			wantErr: true,
+		},
+		{
+			name:         "MOV直接复制的Opus改为AAC",
+			params:       copyParams(OutputContainer_MOV),
+			inputInfo:    h264Opus,
+			wantVideo:    "copy",
+			wantAudio:    "aac",
+			wantWarnings: 1,
+		},
+		{
+			name:    "WebM不支持H.264",
+			params:  with(copyParams(OutputContainer_WebM), func(p *TranscodeParams) { p.VideoCodec = "h264" }),
+			wantErr: true,
+		},
+		{
+			name:         "WebM直接复制的H.264和AAC改为VP9和Opus",
+			params:       copyParams(OutputContainer_WebM),
+			inputInfo:    h264AAC,
+			wantVideo:    "vp9",
+			wantAudio:    "opus",
+			wantWarnings: 2,
+		},
+		{
+			name:         "WebM加水印时改用VP9",
+			params:       with(copyParams(OutputContainer_WebM), func(p *TranscodeParams) { p.WatermarkContent = "水印" }),
+			inputInfo:    h264Opus,
+			wantVideo:    "vp9",
+			wantAudio:    "copy",
+			wantWarnings: 1,
+		},
+		{
+			name:      "MKV直接复制所有编码",
+			params:    copyParams(OutputContainer_MKV),
+			inputInfo: hevcAC3,
+			wantVideo: "copy",
+			wantAudio: "copy",
+		},
+		{
+			name:      "MKV不限制编码",
+			params:    with(copyParams(OutputContainer_MKV), func(p *TranscodeParams) { p.VideoCodec, p.AudioCodec = "vp9", "mp3" }),
+			inputInfo: hevcAC3,
+			wantVideo: "vp9",
+			wantAudio: "mp3",
+		},
+		{
+			name:         "TS直接复制的VP9改为H.264",
+			params:       copyParams(OutputContainer_TS),
+			inputInfo:    &VideoInfo{VideoCodec: "vp9", AudioCodec: "opus"},
+			wantVideo:    "h264",
+			wantAudio:    "copy",
+			wantWarnings: 1,
+		},
+		{
+			name:         "M4A直接复制的AC3改为AAC",
+			params:       copyParams(OutputContainer_M4A),
+			inputInfo:    hevcAC3,
+			wantVideo:    "copy",
+			wantAudio:    "aac",
+			wantWarnings: 1,
+		},
+		{
+			name: "M4A忽略视频参数",
+			params: with(copyParams(OutputContainer_M4A), func(p *TranscodeParams) {
+				p.VideoCodec, p.VideoHeight, p.UseGpu, p.AudioCodec = "h265", "720", true, "aac"
+			}),
+			inputInfo:    h264AAC,
+			wantVideo:    "copy",
+			wantAudio:    "aac",
+			wantWarnings: 1,
+		},
+		{
+			name:    "MP3不支持AAC",
+			params:  with(copyParams(OutputContainer_MP3), func(p *TranscodeParams) { p.AudioCodec = "aac" }),
+			wantErr: true,
+		},
+		{
+			name:         "MP3直接复制的AAC改为MP3",
+			params:       copyParams(OutputContainer_MP3),
+			inputInfo:    h264AAC,
+			wantVideo:    "copy",
+			wantAudio:    "mp3",
+			wantWarnings: 1,
+		},
+		{
+			name:      "只输出音频时源文件没有音频",
+			params:    copyParams(OutputContainer_MP3),
+			inputInfo: &VideoInfo{VideoCodec: "h264"},
+			wantErr:   true,
+		},
+		{
+			name:    "只输出音频时不支持两遍编码",
+			params:  with(copyParams(OutputContainer_M4A), func(p *TranscodeParams) { p.TargetSizeMB = 10 }),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings, err := resolveContainerParams(tt.params, tt.inputInfo)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveContainerParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.VideoCodec != tt.wantVideo || got.AudioCodec != tt.wantAudio {
+				t.Errorf("编码 = %s, %s, want %s, %s", got.VideoCodec, got.AudioCodec, tt.wantVideo, tt.wantAudio)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("警告 = %q, want %d 条", warnings, tt.wantWarnings)
+			}
+			if format, _ := getContainerFormat(got.Container); format.AudioOnly && (got.VideoHeight != "copy" || got.UseGpu) {
+				t.Errorf("只输出音频时应忽略视频参数: %+v", got)
+			}
+		})
+	}
+}
@@ -58,6 +58,27 @@ func (p P_Dialog) OpenMultipleVideoFilesDialog(ctx context.Context) {
 	}
 }
 
+// OpenVideoDirectoryDialog 打开目录选择对话框，递归导入目录中的所有视频文件
+func (p P_Dialog) OpenVideoDirectoryDialog(ctx context.Context) {
+	directory, err := runtime.OpenDirectoryDialog(ctx, runtime.OpenDialogOptions{
+		Title:           "选择视频文件夹",
+		ShowHiddenFiles: false,
+	})
+	if err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("打开目录选择对话框失败: %v", err))
+		runtime.EventsEmit(ctx, "filesSelectedMultipleVideoFilesError", fmt.Sprintf("打开目录选择对话框失败: %v", err))
+		return
+	}
+	if directory == "" {
+		runtime.EventsEmit(ctx, "filesSelectedMultipleVideoFilesCancelled", "用户取消了文件夹选择")
+		return
+	}
+	ShowLoading(ctx)
+	defer HideLoading(ctx)
+	videoInfoSlc := loadVideoInfos(CollectVideoFiles([]string{directory}, GetImportOptions()))
+	runtime.EventsEmit(ctx, "filesSelectedMultipleVideoFilesSuccess", videoInfoSlc)
+}
+
 // OpenDirectoryDialogSetOutput 打开目录选择对话框，选择输出目录
 // 选择完成后，将选中的目录路径通过 events 发送到前端
 func (p P_Dialog) OpenDirectoryDialogSetOutput(ctx context.Context) {
@@ -109,3 +130,29 @@ func (p P_Dialog) OpenWatermarkImageDialog(ctx context.Context) {
 		runtime.EventsEmit(ctx, "fileSelectedWatermarkImageSuccess", file)
 	}
 }
+func (p P_Dialog) OpenSubtitleFileDialog(ctx context.Context) {
+	file, err := runtime.OpenFileDialog(ctx, runtime.OpenDialogOptions{
+		Title: "选择字幕文件",
+		Filters: []runtime.FileFilter{
+			{
+				DisplayName: "字幕文件 (*.srt;*.ass;*.ssa;*.vtt)",
+				Pattern:     "*.srt;*.ass;*.ssa;*.vtt",
+			},
+			{
+				DisplayName: "所有文件 (*.*)",
+				Pattern:     "*.*",
+			},
+		},
+		ShowHiddenFiles: false,
+	})
+	if err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("打开文件对话框失败: %v", err))
+		runtime.EventsEmit(ctx, "fileSelectedSubtitleError", fmt.Sprintf("打开文件对话框失败: %v", err))
+		return
+	}
+	if file == "" {
+		runtime.EventsEmit(ctx, "fileSelectedSubtitleCancelled", "用户取消了文件选择")
+		return
+	}
+	runtime.EventsEmit(ctx, "fileSelectedSubtitleSuccess", file)
+}
@@ -20,28 +20,16 @@ func removeDraggedFilesHandle(ctx context.Context) {
 	wailsRuntime.OnFileDropOff(ctx)
 }
 
-// 处理拖拽文件事件
+// 处理拖拽文件事件，拖入的文件夹会被递归遍历
 func DraggedFilesHandle(ctx context.Context, filepaths []string) {
 	// 过滤视频文件
-	videoFiles := []string{}
-	for _, path := range filepaths {
-		if IsVideoFile(path) {
-			videoFiles = append(videoFiles, path)
-		}
-	}
+	videoFiles := CollectVideoFiles(filepaths, GetImportOptions())
 
 	if len(videoFiles) > 0 {
 		// 获取视频信息并发送到前端
-		var videoInfoList []VideoInfo
-		for _, filePath := range videoFiles {
-			info, err := GetVideoInfo(filePath)
-			if err != nil {
-				continue
-			}
-			videoInfoList = append(videoInfoList, info)
-		}
+		videoInfoList := loadVideoInfos(videoFiles)
 
 		// 发送事件到前端
-		wailsRuntime.EventsEmit(ctx, "filesSelectedMultipleVideoFilesSuccess", videoInfoList)
+		emitEvent(ctx, "filesSelectedMultipleVideoFilesSuccess", videoInfoList)
 	}
 }
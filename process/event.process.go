@@ -0,0 +1,35 @@
+package process
+
+import (
+	"context"
+	"fmt"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// EventListener 接收后端事件的监听函数，命令行模式下用它代替前端
+type EventListener func(name string, data ...interface{})
+
+type eventListenerKey struct{}
+
+// WithEventListener 返回带有事件监听函数的上下文，事件将发送给监听函数而不是前端
+func WithEventListener(ctx context.Context, listener EventListener) context.Context {
+	return context.WithValue(ctx, eventListenerKey{}, listener)
+}
+
+// emitEvent 发送事件，上下文中有监听函数时交给监听函数处理，否则发送到前端
+func emitEvent(ctx context.Context, name string, data ...interface{}) {
+	if listener, ok := ctx.Value(eventListenerKey{}).(EventListener); ok {
+		listener(name, data...)
+		return
+	}
+	wailsRuntime.EventsEmit(ctx, name, data...)
+}
+
+// consolePrintf 输出调试信息到控制台，命令行模式下由监听函数负责输出，不再重复打印
+func consolePrintf(ctx context.Context, format string, a ...interface{}) {
+	if _, ok := ctx.Value(eventListenerKey{}).(EventListener); ok {
+		return
+	}
+	fmt.Printf(format, a...)
+}
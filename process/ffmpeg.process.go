@@ -7,27 +7,19 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
-	"strings"
-	"syscall"
 )
 
 // 创建一个通用的命令执行函数，自动处理Windows平台的窗口隐藏
 func createCommand(name string, arg ...string) *exec.Cmd {
 	cmd := exec.Command(name, arg...)
-	// 在Windows上隐藏控制台窗口
-	if runtime.GOOS == "windows" {
-		cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
-	}
+	hideCommandWindow(cmd)
 	return cmd
 }
 
 // 创建一个通用的带上下文的命令执行函数，自动处理Windows平台的窗口隐藏
 func createCommandContext(ctx context.Context, name string, arg ...string) *exec.Cmd {
 	cmd := exec.CommandContext(ctx, name, arg...)
-	// 在Windows上隐藏控制台窗口
-	if runtime.GOOS == "windows" {
-		cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
-	}
+	hideCommandWindow(cmd)
 	return cmd
 }
 
@@ -44,38 +36,13 @@ func GetCPUThreadCount() int {
 
 // IsGPUSupported 检查系统是否支持GPU加速
 //
-// 该函数会检查FFmpeg是否支持GPU加速功能，包括NVIDIA NVENC等
+// 该函数会检查NVENC、QSV、AMF、VA-API、VideoToolbox中是否有可以实际使用的硬件编码器
 //
 // 返回值:
 //
 //	bool: 如果GPU加速支持则返回true，否则返回false
 func IsGPUSupported() bool {
-	ffmpegPath, err := IsFFmpegAvailable()
-	if err != nil {
-		return false
-	}
-
-	// 检查FFmpeg编译配置是否包含GPU支持
-	cmd := createCommand(ffmpegPath, "-buildconf")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return false
-	}
-
-	outputStr := string(output)
-
-	// 检查常见的GPU加速支持
-	supportsGPU := strings.Contains(outputStr, "--enable-nvenc") || // NVIDIA NVENC
-		strings.Contains(outputStr, "--enable-cuda") || // CUDA
-		strings.Contains(outputStr, "--enable-cuvid") || // NVIDIA CUVID
-		strings.Contains(outputStr, "--enable-libnpp") || // NVIDIA Performance Primitives
-		strings.Contains(outputStr, "--enable-amf") || // AMD AMF
-		strings.Contains(outputStr, "--enable-vaapi") || // Video Acceleration API (Intel/Linux)
-		strings.Contains(outputStr, "--enable-vdpau") || // Video Decode and Presentation API (NVIDIA/Linux)
-		strings.Contains(outputStr, "--enable-libvda") || // Video Decode Acceleration (Apple)
-		strings.Contains(outputStr, "--enable-opencl") // OpenCL
-
-	return supportsGPU
+	return len(AvailableHardwareBackends()) > 0
 }
 
 // IsFFmpegAvailable 检查系统中FFmpeg是否可用
@@ -126,7 +93,8 @@ func isToolAvailable(toolName string) (string, error) {
 		}
 
 		// 如果本地没有，检查系统PATH中的可执行文件
-		toolPath, err := exec.LookPath(toolName + ".exe")
+		var err error
+		toolPath, err = exec.LookPath(toolName + ".exe")
 		if err != nil {
 			// 尝试不带.exe后缀的版本
 			toolPath, err = exec.LookPath(toolName)
@@ -0,0 +1,242 @@
+package process
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type HardwareBackend string
+
+const (
+	HardwareBackend_Auto         HardwareBackend = ""             // 自动选择
+	HardwareBackend_NVENC        HardwareBackend = "nvenc"        // NVIDIA
+	HardwareBackend_QSV          HardwareBackend = "qsv"          // Intel Quick Sync Video
+	HardwareBackend_AMF          HardwareBackend = "amf"          // AMD
+	HardwareBackend_VAAPI        HardwareBackend = "vaapi"        // Linux VA-API（Intel/AMD）
+	HardwareBackend_VideoToolbox HardwareBackend = "videotoolbox" // macOS
+)
+
+// defaultVAAPIDevice VA-API默认使用的设备
+const defaultVAAPIDevice = "/dev/dri/renderD128"
+
+// encoderBackend 一种硬件编码器，描述如何为该厂商的编码器生成FFmpeg参数
+type encoderBackend struct {
+	Name     HardwareBackend
+	Encoders map[string]string // 视频编码 h264/h265/av1/vp9 对应的编码器，不支持的编码不列出
+	HWAccel  string            // 需要FFmpeg支持的硬件加速方式，为空时不检查
+	GOOS     []string          // 支持的系统，为空时不限制
+}
+
+// encoderBackends 按优先级排列的硬件编码器
+var encoderBackends = []encoderBackend{
+	{
+		Name:     HardwareBackend_NVENC,
+		Encoders: map[string]string{"h264": "h264_nvenc", "h265": "hevc_nvenc", "av1": "av1_nvenc"},
+	},
+	{
+		Name:     HardwareBackend_QSV,
+		Encoders: map[string]string{"h264": "h264_qsv", "h265": "hevc_qsv", "av1": "av1_qsv", "vp9": "vp9_qsv"},
+		HWAccel:  "qsv",
+		GOOS:     []string{"windows", "linux"},
+	},
+	{
+		Name:     HardwareBackend_AMF,
+		Encoders: map[string]string{"h264": "h264_amf", "h265": "hevc_amf", "av1": "av1_amf"},
+		GOOS:     []string{"windows", "linux"},
+	},
+	{
+		Name:     HardwareBackend_VAAPI,
+		Encoders: map[string]string{"h264": "h264_vaapi", "h265": "hevc_vaapi", "av1": "av1_vaapi", "vp9": "vp9_vaapi"},
+		HWAccel:  "vaapi",
+		GOOS:     []string{"linux"},
+	},
+	{
+		Name:     HardwareBackend_VideoToolbox,
+		Encoders: map[string]string{"h264": "h264_videotoolbox", "h265": "hevc_videotoolbox"},
+		HWAccel:  "videotoolbox",
+		GOOS:     []string{"darwin"},
+	},
+}
+
+func getEncoderBackend(name HardwareBackend) (encoderBackend, bool) {
+	for _, backend := range encoderBackends {
+		if backend.Name == name {
+			return backend, true
+		}
+	}
+	return encoderBackend{}, false
+}
+
+func isValidHardwareBackend(name HardwareBackend) bool {
+	if name == HardwareBackend_Auto {
+		return true
+	}
+	_, ok := getEncoderBackend(name)
+	return ok
+}
+
+// supportsOS 判断该硬件编码器是否支持当前系统
+func (b encoderBackend) supportsOS(goos string) bool {
+	if len(b.GOOS) == 0 {
+		return true
+	}
+	for _, g := range b.GOOS {
+		if g == goos {
+			return true
+		}
+	}
+	return false
+}
+
+// inputArgs 放在 -i 之前的参数，用于初始化硬件设备
+func (b encoderBackend) inputArgs(device string) []string {
+	switch b.Name {
+	case HardwareBackend_VAAPI:
+		if device == "" {
+			device = defaultVAAPIDevice
+		}
+		return []string{"-vaapi_device", device}
+	}
+	return nil
+}
+
+// uploadFilter 放在滤镜链最后，将软件帧上传到显存，其他滤镜都在CPU上处理
+//
+// VA-API的像素格式需要在上传前设置，10bit使用p010，其他使用nv12
+func (b encoderBackend) uploadFilter(pixelFormat string) string {
+	switch b.Name {
+	case HardwareBackend_VAAPI:
+		format := "nv12"
+		if strings.Contains(pixelFormat, "10") {
+			format = "p010"
+		}
+		return "format=" + format + ",hwupload"
+	}
+	return ""
+}
+
+// qualityArgs 恒定质量参数，quality 与libx264的CRF含义相近，越小画质越好，各厂商编码器的参数名不同
+func (b encoderBackend) qualityArgs(quality string) []string {
+	switch b.Name {
+	case HardwareBackend_NVENC:
+		return []string{"-rc", "vbr", "-cq", quality, "-b:v", "0"}
+	case HardwareBackend_QSV:
+		return []string{"-global_quality", quality}
+	case HardwareBackend_AMF:
+		return []string{"-rc", "cqp", "-qp_i", quality, "-qp_p", quality}
+	case HardwareBackend_VAAPI:
+		return []string{"-qp", quality}
+	case HardwareBackend_VideoToolbox:
+		// VideoToolbox的质量值为1-100，越大画质越好，CRF 23 对应 65
+		q, _ := strconv.Atoi(quality)
+		return []string{"-q:v", strconv.Itoa(max(1, min(100, 111-2*q)))}
+	}
+	return nil
+}
+
+// hardwareTestArgs 用一帧测试画面检查硬件编码器能否实际使用的参数
+func hardwareTestArgs(backend encoderBackend, encoder, device string) []string {
+	args := []string{"-hide_banner", "-v", "error"}
+	args = append(args, backend.inputArgs(device)...)
+	args = append(args, "-f", "lavfi", "-i", "color=black:s=256x256:d=0.1", "-frames:v", "1")
+	if filter := backend.uploadFilter(""); filter != "" {
+		args = append(args, "-vf", filter)
+	}
+	return append(args, "-c:v", encoder, "-f", "null", "-")
+}
+
+var (
+	hardwareTestMu      sync.Mutex
+	hardwareTestResults = map[string]bool{}
+)
+
+// hardwareEncoderWorks 检查硬件编码器是否可用：FFmpeg包含该编码器、支持需要的硬件加速方式，并且能编码测试画面
+//
+// FFmpeg列出的编码器只说明编译时包含了该编码器，没有对应的显卡或驱动时仍会失败，所以需要实际编码一帧，结果会被缓存
+func hardwareEncoderWorks(caps *FFmpegCapabilities, backend encoderBackend, codec string) bool {
+	encoder, ok := backend.Encoders[codec]
+	if !ok || caps == nil || !backend.supportsOS(runtime.GOOS) || !caps.HasEncoder(encoder) {
+		return false
+	}
+	if backend.HWAccel != "" && !caps.HasHWAccel(backend.HWAccel) {
+		return false
+	}
+
+	device := GetVAAPIDevice()
+	key := encoder + "|" + device
+	hardwareTestMu.Lock()
+	defer hardwareTestMu.Unlock()
+	if works, ok := hardwareTestResults[key]; ok {
+		return works
+	}
+	works := false
+	if ffmpegPath, err := IsFFmpegAvailable(); err == nil {
+		works = createCommand(ffmpegPath, hardwareTestArgs(backend, encoder, device)...).Run() == nil
+	}
+	hardwareTestResults[key] = works
+	return works
+}
+
+// selectHardwareBackend 为视频编码选择硬件编码器，优先使用配置中指定的编码器，没有该编码硬件编码器的后端不会被选择
+func selectHardwareBackend(codec string, caps *FFmpegCapabilities) (encoderBackend, bool) {
+	if preferred, ok := getEncoderBackend(GetHardwareBackend()); ok && preferred.Encoders[codec] != "" {
+		// 无法探测FFmpeg能力时直接使用指定的编码器
+		if caps == nil || hardwareEncoderWorks(caps, preferred, codec) {
+			return preferred, true
+		}
+	}
+	if caps == nil {
+		// 无法探测时保持以前的行为，使用NVENC
+		backend, _ := getEncoderBackend(HardwareBackend_NVENC)
+		return backend, backend.Encoders[codec] != ""
+	}
+	for _, backend := range encoderBackends {
+		if hardwareEncoderWorks(caps, backend, codec) {
+			return backend, true
+		}
+	}
+	return encoderBackend{}, false
+}
+
+// AvailableHardwareBackends 获取当前电脑上可用的硬件编码器
+func AvailableHardwareBackends() []HardwareBackend {
+	caps, err := GetFFmpegCapabilities()
+	if err != nil {
+		return []HardwareBackend{}
+	}
+	backends := []HardwareBackend{}
+	for _, backend := range encoderBackends {
+		if hardwareEncoderWorks(caps, backend, "h264") {
+			backends = append(backends, backend.Name)
+		}
+	}
+	return backends
+}
+
+// GetHardwareBackend 获取配置中指定的硬件编码器，为空时自动选择
+func GetHardwareBackend() HardwareBackend {
+	backend := HardwareBackend(strings.ToLower(Config.HardwareBackend))
+	if !isValidHardwareBackend(backend) {
+		return HardwareBackend_Auto
+	}
+	return backend
+}
+
+// ValidateHardwareBackend 校验硬件编码器名称
+func ValidateHardwareBackend(backend HardwareBackend) error {
+	if !isValidHardwareBackend(backend) {
+		return fmt.Errorf("无效的硬件编码器: %s，可用: nvenc、qsv、amf、vaapi、videotoolbox", backend)
+	}
+	return nil
+}
+
+// GetVAAPIDevice 获取VA-API使用的设备
+func GetVAAPIDevice() string {
+	if Config.VAAPIDevice == "" {
+		return defaultVAAPIDevice
+	}
+	return Config.VAAPIDevice
+}
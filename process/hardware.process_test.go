@@ -0,0 +1,82 @@
+package process
+
+import (
+	"reflect"
+	"testing"
+)
+
+func mustEncoderBackend(t *testing.T, name HardwareBackend) encoderBackend {
+	t.Helper()
+	backend, ok := getEncoderBackend(name)
+	if !ok {
+		t.Fatalf("没有硬件编码器 %s", name)
+	}
+	return backend
+}
+
+func TestEncoderBackendInputArgs(t *testing.T) {
+	tests := []struct {
+		backend HardwareBackend
+		device  string
+		want    []string
+	}{
+		{HardwareBackend_NVENC, "", nil},
+		{HardwareBackend_QSV, "", nil},
+		{HardwareBackend_AMF, "", nil},
+		{HardwareBackend_VAAPI, "", []string{"-vaapi_device", defaultVAAPIDevice}},
+		{HardwareBackend_VAAPI, "/dev/dri/renderD129", []string{"-vaapi_device", "/dev/dri/renderD129"}},
+		{HardwareBackend_VideoToolbox, "", nil},
+	}
+	for _, tt := range tests {
+		got := mustEncoderBackend(t, tt.backend).inputArgs(tt.device)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s inputArgs(%q) = %q, want %q", tt.backend, tt.device, got, tt.want)
+		}
+	}
+}
+
+func TestEncoderBackendUploadFilter(t *testing.T) {
+	tests := []struct {
+		backend     HardwareBackend
+		pixelFormat string
+		want        string
+	}{
+		{HardwareBackend_NVENC, "", ""},
+		{HardwareBackend_QSV, "yuv420p", ""},
+		{HardwareBackend_AMF, "", ""},
+		{HardwareBackend_VAAPI, "", "format=nv12,hwupload"},
+		{HardwareBackend_VAAPI, "yuv420p", "format=nv12,hwupload"},
+		{HardwareBackend_VAAPI, "yuv420p10le", "format=p010,hwupload"},
+		{HardwareBackend_VideoToolbox, "", ""},
+	}
+	for _, tt := range tests {
+		got := mustEncoderBackend(t, tt.backend).uploadFilter(tt.pixelFormat)
+		if got != tt.want {
+			t.Errorf("%s uploadFilter(%q) = %q, want %q", tt.backend, tt.pixelFormat, got, tt.want)
+		}
+	}
+}
+
+func TestEncoderBackendQualityArgs(t *testing.T) {
+	tests := []struct {
+		backend HardwareBackend
+		quality string
+		want    []string
+	}{
+		{HardwareBackend_NVENC, "23", []string{"-rc", "vbr", "-cq", "23", "-b:v", "0"}},
+		{HardwareBackend_QSV, "23", []string{"-global_quality", "23"}},
+		{HardwareBackend_AMF, "23", []string{"-rc", "cqp", "-qp_i", "23", "-qp_p", "23"}},
+		{HardwareBackend_VAAPI, "23", []string{"-qp", "23"}},
+		{HardwareBackend_VideoToolbox, "23", []string{"-q:v", "65"}},
+		// VideoToolbox的质量值限制在1-100
+		{HardwareBackend_VideoToolbox, "0", []string{"-q:v", "100"}},
+		{HardwareBackend_VideoToolbox, "51", []string{"-q:v", "9"}},
+		{HardwareBackend_VideoToolbox, "63", []string{"-q:v", "1"}},
+	}
+	for _, tt := range tests {
+		got := mustEncoderBackend(t, tt.backend).qualityArgs(tt.quality)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s qualityArgs(%q) = %q, want %q", tt.backend, tt.quality, got, tt.want)
+		}
+	}
+}
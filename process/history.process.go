@@ -0,0 +1,432 @@
+package process
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	jobHistoryFile    = "jobs.jsonl"     // 界面模式的任务历史
+	cliJobHistoryFile = "jobs-cli.jsonl" // 命令行模式的任务历史，与界面分开，避免两个进程同时写入同一个文件
+)
+
+// maxJobHistoryRecords 历史记录的最大数量，超出时启动时删除最早的已结束任务
+const maxJobHistoryRecords = 5000
+
+// JobRecord 任务历史记录，每次状态变化都会追加写入日志文件
+type JobRecord struct {
+	ID          string              `json:"id"`
+	Type        TranscodeJobType    `json:"type,omitempty"`
+	Path        string              `json:"path"`
+	BaseDir     string              `json:"base_dir"`
+	Index       int                 `json:"index"`
+	Params      TranscodeParams     `json:"params"`
+	Trim        *TrimRange          `json:"trim,omitempty"`     // 单个文件的截取范围
+	Merge       *MergeOptions       `json:"merge,omitempty"`    // 合并任务的选项
+	Subtitle    *SubtitleJobOptions `json:"subtitle,omitempty"` // 字幕任务的选项
+	Status      TranscodeJobStatus  `json:"status"`
+	Attempts    int                 `json:"attempts"` // 提交次数，重新排队时加1
+	SubmittedAt time.Time           `json:"submitted_at"`
+	StartedAt   time.Time           `json:"started_at"`
+	FinishedAt  time.Time           `json:"finished_at"`
+	Result      *TranscodeResult    `json:"result"`
+}
+
+// Job 根据历史记录重建转码任务
+func (r JobRecord) Job() TranscodeJob {
+	return TranscodeJob{ID: r.ID, Type: r.Type, Path: r.Path, Params: r.Params, Index: r.Index, BaseDir: r.BaseDir, Trim: r.Trim, Merge: r.Merge, Subtitle: r.Subtitle}
+}
+
+// isUnfinished 任务是否尚未结束，程序启动时仍处于这些状态的任务是被中断的任务
+func (r JobRecord) isUnfinished() bool {
+	switch r.Status {
+	case TranscodeJobStatus_Pending, TranscodeJobStatus_Running, TranscodeJobStatus_Paused:
+		return true
+	}
+	return false
+}
+
+// JobHistoryFilter 查询历史任务的条件，为空的条件不过滤
+type JobHistoryFilter struct {
+	Status      []TranscodeJobStatus `json:"status"`
+	Query       string               `json:"query"`       // 输入文件路径包含的文字，不区分大小写
+	Interrupted bool                 `json:"interrupted"` // 只返回被中断的任务
+	Since       int64                `json:"since"`       // 只返回该时间（Unix秒）之后提交的任务
+	Limit       int                  `json:"limit"`       // 最多返回的数量，0为不限制
+}
+
+// JobHistory 任务历史，以JSON Lines日志的形式保存，每行是某个任务的最新状态，读取时以最后一行为准
+type JobHistory struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	records map[string]*JobRecord
+	order   []string
+}
+
+// OpenJobHistory 打开任务历史文件，读取已有记录并压缩日志
+func OpenJobHistory(path string) (*JobHistory, error) {
+	h := &JobHistory{path: path, records: map[string]*JobRecord{}}
+	if err := h.load(); err != nil {
+		return nil, err
+	}
+	h.prune()
+	if err := h.compact(); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打开任务历史文件失败: %v", err)
+	}
+	h.file = file
+	return h, nil
+}
+
+// load 读取日志文件，同一任务的多行记录以最后一行为准，无法解析的行（如崩溃时写了一半的行）会被忽略
+func (h *JobHistory) load() error {
+	file, err := os.Open(h.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取任务历史文件失败: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var record JobRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil || record.ID == "" {
+			continue
+		}
+		if _, ok := h.records[record.ID]; !ok {
+			h.order = append(h.order, record.ID)
+		}
+		h.records[record.ID] = &record
+	}
+	return scanner.Err()
+}
+
+// prune 记录过多时删除最早的已结束任务
+func (h *JobHistory) prune() {
+	excess := len(h.order) - maxJobHistoryRecords
+	if excess <= 0 {
+		return
+	}
+	order := h.order[:0]
+	for _, id := range h.order {
+		if excess > 0 && !h.records[id].isUnfinished() {
+			delete(h.records, id)
+			excess--
+			continue
+		}
+		order = append(order, id)
+	}
+	h.order = order
+}
+
+// compact 每个任务只保留最新的一行，先写入临时文件再替换
+func (h *JobHistory) compact() error {
+	if len(h.order) == 0 {
+		return nil
+	}
+	tempPath := h.path + ".tmp"
+	file, err := os.Create(tempPath)
+	if err != nil {
+		return fmt.Errorf("压缩任务历史文件失败: %v", err)
+	}
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, id := range h.order {
+		if err := encoder.Encode(h.records[id]); err != nil {
+			file.Close()
+			os.Remove(tempPath)
+			return fmt.Errorf("压缩任务历史文件失败: %v", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("压缩任务历史文件失败: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("压缩任务历史文件失败: %v", err)
+	}
+	return os.Rename(tempPath, h.path)
+}
+
+// Close 关闭日志文件
+func (h *JobHistory) Close() error {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.file == nil {
+		return nil
+	}
+	err := h.file.Close()
+	h.file = nil
+	return err
+}
+
+// update 记录任务的状态变化，h 为 nil 时不记录
+func (h *JobHistory) update(job TranscodeJob, status TranscodeJobStatus, result *TranscodeResult) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	record, ok := h.records[job.ID]
+	if !ok {
+		record = &JobRecord{ID: job.ID}
+		h.records[job.ID] = record
+		h.order = append(h.order, job.ID)
+	}
+	record.Type, record.Path, record.BaseDir, record.Index, record.Params = job.Type, job.Path, job.BaseDir, job.Index, job.Params
+	record.Trim, record.Merge, record.Subtitle = job.Trim, job.Merge, job.Subtitle
+	record.Status = status
+
+	now := time.Now()
+	switch status {
+	case TranscodeJobStatus_Pending:
+		record.Attempts++
+		record.SubmittedAt = now
+		record.StartedAt, record.FinishedAt = time.Time{}, time.Time{}
+		record.Result = nil
+	case TranscodeJobStatus_Running:
+		// 暂停后恢复时保留最初的开始时间
+		if record.StartedAt.IsZero() {
+			record.StartedAt = now
+		}
+	case TranscodeJobStatus_Paused:
+	default:
+		record.FinishedAt = now
+		if result != nil {
+			r := withoutThumbnails(*result)
+			record.Result = &r
+		}
+	}
+	h.append(record)
+}
+
+// append 追加一行记录并立即写入磁盘，保证崩溃后仍能恢复
+func (h *JobHistory) append(record *JobRecord) {
+	if h.file == nil {
+		return
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		log.Printf("序列化任务历史失败: %v", err)
+		return
+	}
+	if _, err := h.file.Write(append(line, '\n')); err != nil {
+		log.Printf("写入任务历史失败: %v", err)
+		return
+	}
+	h.file.Sync()
+}
+
+// RecoverInterrupted 将上次运行时未结束的任务标记为失败，返回这些任务以便重新排队
+func (h *JobHistory) RecoverInterrupted() []JobRecord {
+	if h == nil {
+		return nil
+	}
+	var interrupted []JobRecord
+	h.mu.Lock()
+	for _, id := range h.order {
+		record := h.records[id]
+		if !record.isUnfinished() {
+			continue
+		}
+		previous := record.Status
+		record.Status = TranscodeJobStatus_Failed
+		record.FinishedAt = time.Now()
+		record.Result = &TranscodeResult{
+			ID:        record.ID,
+			Status:    TranscodeResultStatus_Failed,
+			InputPath: record.Path,
+			ExitCode:  -1,
+			ErrorKind: TranscodeErrorKind_Interrupted,
+			Error:     fmt.Sprintf("程序退出时任务未完成（%s）", previous),
+		}
+		h.append(record)
+		interrupted = append(interrupted, *record)
+	}
+	h.mu.Unlock()
+
+	removeInterruptedTempFiles(interrupted)
+	return interrupted
+}
+
+// removeInterruptedTempFiles 删除被中断的任务在输出目录中留下的临时文件、两遍编码的统计文件、智能切割的片段和合并的文件列表
+func removeInterruptedTempFiles(records []JobRecord) {
+	if len(records) == 0 {
+		return
+	}
+	ids := map[string]bool{}
+	for _, record := range records {
+		ids[record.ID] = true
+		removeTempFiles(passLogPrefix(record.ID))
+		removeTempFiles(smartCutPrefix(record.ID))
+		removeTempFiles(mergeListPrefix(record.ID))
+	}
+	filepath.WalkDir(GetOutputDirectory(), func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		// 临时文件名格式见 tempOutputPath: .<name>.<id>.part<ext>
+		name := d.Name()
+		if !strings.HasPrefix(name, ".") || !strings.Contains(name, ".part") {
+			return nil
+		}
+		parts := strings.Split(strings.TrimSuffix(name[:strings.LastIndex(name, ".part")], "."), ".")
+		if ids[parts[len(parts)-1]] {
+			os.Remove(p)
+		}
+		return nil
+	})
+}
+
+// Get 获取单个任务的历史记录
+func (h *JobHistory) Get(id string) (JobRecord, bool) {
+	if h == nil {
+		return JobRecord{}, false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	record, ok := h.records[id]
+	if !ok {
+		return JobRecord{}, false
+	}
+	return *record, true
+}
+
+// List 按条件查询历史任务，最近提交的在前
+func (h *JobHistory) List(filter JobHistoryFilter) []JobRecord {
+	records := []JobRecord{}
+	if h == nil {
+		return records
+	}
+	query := strings.ToLower(strings.TrimSpace(filter.Query))
+	h.mu.Lock()
+	for _, id := range h.order {
+		record := h.records[id]
+		if len(filter.Status) > 0 && !containsJobStatus(filter.Status, record.Status) {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(record.Path), query) {
+			continue
+		}
+		if filter.Interrupted && (record.Result == nil || record.Result.ErrorKind != TranscodeErrorKind_Interrupted) {
+			continue
+		}
+		if filter.Since > 0 && record.SubmittedAt.Unix() < filter.Since {
+			continue
+		}
+		records = append(records, *record)
+	}
+	h.mu.Unlock()
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].SubmittedAt.After(records[j].SubmittedAt)
+	})
+	if filter.Limit > 0 && len(records) > filter.Limit {
+		records = records[:filter.Limit]
+	}
+	return records
+}
+
+// Clear 删除所有已结束的任务记录，未结束的任务保留
+func (h *JobHistory) Clear() error {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	order := h.order[:0]
+	for _, id := range h.order {
+		if h.records[id].isUnfinished() {
+			order = append(order, id)
+			continue
+		}
+		delete(h.records, id)
+	}
+	h.order = order
+
+	if h.file != nil {
+		h.file.Close()
+		h.file = nil
+	}
+	if len(h.order) == 0 {
+		if err := os.Remove(h.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("清空任务历史失败: %v", err)
+		}
+	} else if err := h.compact(); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(h.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("打开任务历史文件失败: %v", err)
+	}
+	h.file = file
+	return nil
+}
+
+// RequeueJobs 将历史任务重新提交到队列，保留原来的参数、序号和导入根目录
+//
+// 已在队列中的任务和输入文件已不存在的任务会被忽略，返回重新排队的任务的视频信息，ID与任务ID相同
+func (q *TranscodeQueue) RequeueJobs(ids []string) ([]VideoInfo, error) {
+	if q.history == nil {
+		return nil, fmt.Errorf("任务历史不可用")
+	}
+	var jobs []TranscodeJob
+	var files []importedFile
+	for _, id := range ids {
+		record, ok := q.history.Get(id)
+		if !ok || q.isQueued(id) || !FileExists(record.Path) {
+			continue
+		}
+		jobs = append(jobs, record.Job())
+		files = append(files, importedFile{Path: record.Path, BaseDir: record.BaseDir})
+	}
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("没有可以重新排队的任务")
+	}
+
+	// loadVideoInfos 会忽略获取失败的文件，按路径对应回任务ID
+	infos := loadVideoInfos(files)
+	idsByPath := map[string][]string{}
+	for _, job := range jobs {
+		idsByPath[job.Path] = append(idsByPath[job.Path], job.ID)
+	}
+	for i := range infos {
+		if pathIDs := idsByPath[infos[i].Path]; len(pathIDs) > 0 {
+			infos[i].ID = pathIDs[0]
+			idsByPath[infos[i].Path] = pathIDs[1:]
+		}
+	}
+	q.Submit(jobs)
+	return infos, nil
+}
+
+func containsJobStatus(statuses []TranscodeJobStatus, status TranscodeJobStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
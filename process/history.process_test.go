@@ -0,0 +1,165 @@
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func openTestJobHistory(t *testing.T, path string) *JobHistory {
+	t.Helper()
+	history, err := OpenJobHistory(path)
+	if err != nil {
+		t.Fatalf("OpenJobHistory() error = %v", err)
+	}
+	t.Cleanup(func() { history.Close() })
+	return history
+}
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(content), "\n")
+}
+
+func TestJobHistoryReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), jobHistoryFile)
+	history := openTestJobHistory(t, path)
+	a := TranscodeJob{ID: "a", Path: "/videos/a.mp4"}
+	b := TranscodeJob{ID: "b", Path: "/videos/b.mp4"}
+	history.update(a, TranscodeJobStatus_Pending, nil)
+	history.update(b, TranscodeJobStatus_Pending, nil)
+	history.update(a, TranscodeJobStatus_Running, nil)
+	history.update(a, TranscodeJobStatus_Completed, &TranscodeResult{ID: "a", Status: TranscodeResultStatus_Success})
+	history.update(b, TranscodeJobStatus_Cancelled, nil)
+	history.Close()
+	if lines := countLines(t, path); lines != 5 {
+		t.Fatalf("日志行数 = %d, want 5", lines)
+	}
+
+	reloaded := openTestJobHistory(t, path)
+	record, ok := reloaded.Get("a")
+	if !ok || record.Status != TranscodeJobStatus_Completed || record.Attempts != 1 || record.Result == nil {
+		t.Errorf("a = %+v, want 最后一行的已完成状态", record)
+	}
+	if record.StartedAt.IsZero() || record.FinishedAt.IsZero() {
+		t.Errorf("a 的开始和结束时间应保留: %v, %v", record.StartedAt, record.FinishedAt)
+	}
+	if record, _ := reloaded.Get("b"); record.Status != TranscodeJobStatus_Cancelled {
+		t.Errorf("b 的状态 = %s, want %s", record.Status, TranscodeJobStatus_Cancelled)
+	}
+	// 打开时压缩为每个任务一行
+	if lines := countLines(t, path); lines != 2 {
+		t.Errorf("压缩后的日志行数 = %d, want 2", lines)
+	}
+}
+
+func TestJobHistoryIgnoresTruncatedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), jobHistoryFile)
+	content := `{"id":"a","path":"/videos/a.mp4","status":"pending","attempts":1}
+{"id":"a","path":"/videos/a.mp4","status":"completed","attempts":1}
+{"id":"b","path":"/videos/b.mp4","status":"runn`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	history := openTestJobHistory(t, path)
+	if records := history.List(JobHistoryFilter{}); len(records) != 1 || records[0].ID != "a" || records[0].Status != TranscodeJobStatus_Completed {
+		t.Fatalf("List() = %+v, want 只有已完成的 a", records)
+	}
+	if _, ok := history.Get("b"); ok {
+		t.Error("写了一半的行不应被读取")
+	}
+	// 压缩后新记录从新的一行开始写入
+	history.update(TranscodeJob{ID: "c", Path: "/videos/c.mp4"}, TranscodeJobStatus_Pending, nil)
+	history.Close()
+	reloaded := openTestJobHistory(t, path)
+	if _, ok := reloaded.Get("c"); !ok {
+		t.Error("之后追加的记录应能读取")
+	}
+}
+
+func TestJobHistoryRecoverInterrupted(t *testing.T) {
+	dir := t.TempDir()
+	Config = &ConfigData{OutputDirectory: dir}
+	path := filepath.Join(dir, jobHistoryFile)
+	history := openTestJobHistory(t, path)
+	history.update(TranscodeJob{ID: "running", Path: "/videos/running.mp4"}, TranscodeJobStatus_Running, nil)
+	history.update(TranscodeJob{ID: "paused", Path: "/videos/paused.mp4"}, TranscodeJobStatus_Paused, nil)
+	history.update(TranscodeJob{ID: "done", Path: "/videos/done.mp4"}, TranscodeJobStatus_Completed, nil)
+	history.Close()
+
+	tempFile := tempOutputPath(filepath.Join(dir, "running.mp4"), "running")
+	otherFile := tempOutputPath(filepath.Join(dir, "done.mp4"), "done")
+	for _, file := range []string{tempFile, otherFile} {
+		if err := os.WriteFile(file, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history = openTestJobHistory(t, path)
+	interrupted := history.RecoverInterrupted()
+	if len(interrupted) != 2 {
+		t.Fatalf("被中断的任务 = %d 个, want 2", len(interrupted))
+	}
+	previous := map[string]TranscodeJobStatus{"running": TranscodeJobStatus_Running, "paused": TranscodeJobStatus_Paused}
+	for _, record := range interrupted {
+		if record.Status != TranscodeJobStatus_Failed || record.Result == nil || record.Result.ErrorKind != TranscodeErrorKind_Interrupted {
+			t.Errorf("%s = %+v, want 标记为中断的失败任务", record.ID, record)
+		}
+		if !strings.Contains(record.Result.Error, string(previous[record.ID])) {
+			t.Errorf("%s 的错误信息应包含中断前的状态: %s", record.ID, record.Result.Error)
+		}
+	}
+	if FileExists(tempFile) {
+		t.Error("被中断任务的临时文件应被删除")
+	}
+	if !FileExists(otherFile) {
+		t.Error("其他任务的临时文件不应被删除")
+	}
+	if again := history.RecoverInterrupted(); len(again) != 0 {
+		t.Errorf("再次恢复时不应有被中断的任务: %d 个", len(again))
+	}
+	history.Close()
+
+	reloaded := openTestJobHistory(t, path)
+	if records := reloaded.List(JobHistoryFilter{Interrupted: true}); len(records) != 2 {
+		t.Errorf("重新打开后被中断的任务 = %d 个, want 2", len(records))
+	}
+	if record, _ := reloaded.Get("done"); record.Status != TranscodeJobStatus_Completed {
+		t.Errorf("已完成的任务状态 = %s", record.Status)
+	}
+}
+
+func TestJobHistoryClearKeepsUnfinished(t *testing.T) {
+	path := filepath.Join(t.TempDir(), jobHistoryFile)
+	history := openTestJobHistory(t, path)
+	history.update(TranscodeJob{ID: "done", Path: "/videos/done.mp4"}, TranscodeJobStatus_Completed, nil)
+	history.update(TranscodeJob{ID: "failed", Path: "/videos/failed.mp4"}, TranscodeJobStatus_Failed, nil)
+	history.update(TranscodeJob{ID: "pending", Path: "/videos/pending.mp4"}, TranscodeJobStatus_Pending, nil)
+	history.update(TranscodeJob{ID: "running", Path: "/videos/running.mp4"}, TranscodeJobStatus_Running, nil)
+
+	if err := history.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if records := history.List(JobHistoryFilter{}); len(records) != 2 {
+		t.Fatalf("清空后的记录 = %d 个, want 2", len(records))
+	}
+	history.update(TranscodeJob{ID: "running", Path: "/videos/running.mp4"}, TranscodeJobStatus_Completed, nil)
+	history.Close()
+
+	reloaded := openTestJobHistory(t, path)
+	if _, ok := reloaded.Get("done"); ok {
+		t.Error("已结束的任务应被清除")
+	}
+	if record, ok := reloaded.Get("running"); !ok || record.Status != TranscodeJobStatus_Completed {
+		t.Errorf("清空后追加的记录 = %+v, %v", record, ok)
+	}
+	if _, ok := reloaded.Get("pending"); !ok {
+		t.Error("未结束的任务应保留")
+	}
+}
@@ -0,0 +1,170 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ImportOptions 导入文件夹时的过滤选项
+type ImportOptions struct {
+	IncludePatterns []string `yaml:"includePatterns" json:"includePatterns"` // 包含的文件，如 *.mp4、2023*/*，为空时包含所有视频文件
+	ExcludePatterns []string `yaml:"excludePatterns" json:"excludePatterns"` // 排除的文件或文件夹，如 *_proxy.*、.cache
+	FollowSymlinks  bool     `yaml:"followSymlinks" json:"followSymlinks"`   // 是否跟随符号链接
+}
+
+// importedFile 导入的视频文件
+type importedFile struct {
+	Path    string
+	BaseDir string // 导入文件夹时的根目录，直接导入的文件为空
+}
+
+// GetImportOptions 获取导入选项
+func GetImportOptions() ImportOptions {
+	return Config.Import
+}
+
+// ValidateImportOptions 校验导入选项中的匹配模式
+func ValidateImportOptions(options ImportOptions) error {
+	for _, pattern := range append(append([]string{}, options.IncludePatterns...), options.ExcludePatterns...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("无效的匹配模式 %s: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// CollectVideoFiles 展开拖入或选择的路径，文件夹会被递归遍历
+//
+// 文件夹中的文件会记录导入根目录（所选文件夹的上一级），转码时在输出目录中重建相同的目录结构；
+// 包含和排除的模式按相对所选文件夹的路径匹配，如所选文件夹中的 2023-01/a.mp4 匹配 2023*/*
+func CollectVideoFiles(paths []string, options ImportOptions) []importedFile {
+	var files []importedFile
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() {
+			if IsVideoFile(p) {
+				files = append(files, importedFile{Path: p})
+			}
+			continue
+		}
+		rootDir := filepath.Clean(p)
+		visited := map[string]bool{}
+		walkVideoDirectory(rootDir, rootDir, filepath.Dir(rootDir), options, visited, &files)
+	}
+	return files
+}
+
+// walkVideoDirectory 递归遍历文件夹，visited 记录已访问的真实路径，防止符号链接形成循环
+//
+// 匹配模式使用相对所选文件夹 rootDir 的路径，baseDir 为所选文件夹的上一级，用于在输出目录中重建目录结构
+func walkVideoDirectory(dir, rootDir, baseDir string, options ImportOptions, visited map[string]bool, files *[]importedFile) {
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil || visited[realDir] {
+		return
+	}
+	visited[realDir] = true
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		fullPath := filepath.Join(dir, entry.Name())
+		relPath := TrimBasePath(fullPath, rootDir)
+		if matchImportPattern(options.ExcludePatterns, entry.Name(), relPath) {
+			continue
+		}
+
+		isDir := entry.IsDir()
+		if entry.Type()&os.ModeSymlink != 0 {
+			target, err := os.Stat(fullPath)
+			if err != nil {
+				continue
+			}
+			isDir = target.IsDir()
+			// 不跟随符号链接时只跳过指向文件夹的链接，指向文件的链接仍然导入
+			if isDir && !options.FollowSymlinks {
+				continue
+			}
+		}
+
+		if isDir {
+			walkVideoDirectory(fullPath, rootDir, baseDir, options, visited, files)
+			continue
+		}
+		if !IsVideoFile(entry.Name()) {
+			continue
+		}
+		if len(options.IncludePatterns) > 0 && !matchImportPattern(options.IncludePatterns, entry.Name(), relPath) {
+			continue
+		}
+		*files = append(*files, importedFile{Path: fullPath, BaseDir: baseDir})
+	}
+}
+
+// matchImportPattern 判断文件名或相对路径是否匹配任一模式
+func matchImportPattern(patterns []string, name, relPath string) bool {
+	for _, pattern := range patterns {
+		pattern = filepath.ToSlash(pattern)
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, relPath); ok {
+			return true
+		}
+		// 模式匹配某一级文件夹时，其中的所有文件都匹配
+		if ok, _ := path.Match(pattern+"/*", relPath); ok || strings.HasPrefix(relPath, pattern+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// loadVideoInfos 并行获取视频信息，保持导入顺序，获取失败的文件会被忽略
+func loadVideoInfos(files []importedFile) []VideoInfo {
+	infos := make([]*VideoInfo, len(files))
+	sem := make(chan struct{}, GetCPUThreadCount())
+	var wg sync.WaitGroup
+	for i, file := range files {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, file importedFile) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			info, err := GetVideoInfo(file.Path)
+			if err != nil {
+				return
+			}
+			info.BaseDir = file.BaseDir
+			infos[i] = &info
+		}(i, file)
+	}
+	wg.Wait()
+
+	videoInfoList := make([]VideoInfo, 0, len(files))
+	for _, info := range infos {
+		if info != nil {
+			videoInfoList = append(videoInfoList, *info)
+		}
+	}
+	return videoInfoList
+}
+
+// getMirroredSubDirectory 获取文件相对导入根目录的子目录，用于在输出目录中重建目录结构
+func getMirroredSubDirectory(filePath, baseDir string) string {
+	if baseDir == "" {
+		return ""
+	}
+	relDir := path.Dir(TrimBasePath(filePath, baseDir))
+	if relDir == "." || path.IsAbs(relDir) || filepath.IsAbs(filepath.FromSlash(relDir)) {
+		return ""
+	}
+	return SanitizePath(filepath.FromSlash(relDir))
+}
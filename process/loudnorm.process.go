@@ -0,0 +1,224 @@
+package process
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EBU R128 的默认目标值
+const (
+	defaultLoudnessIntegrated = -23.0 // 目标响度（LUFS）
+	defaultLoudnessTruePeak   = -1.0  // 真峰值上限（dBTP）
+	defaultLoudnessRange      = 7.0   // 目标响度范围（LU）
+	// defaultLoudnormSampleRate loudnorm会把音频升采样到192kHz，源音频采样率未知时输出48kHz
+	defaultLoudnormSampleRate = 48000
+)
+
+// LoudnessOptions 响度标准化设置，先分析整个音频的响度，再按分析结果线性调整音量
+type LoudnessOptions struct {
+	Enabled    bool    `yaml:"enabled" json:"enabled"`       // 是否标准化响度
+	Integrated float64 `yaml:"integrated" json:"integrated"` // 目标响度 -70 到 -5 LUFS，为0时使用 -23
+	TruePeak   float64 `yaml:"truePeak" json:"true_peak"`    // 真峰值上限 -9 到 0 dBTP，为0时使用 -1
+	LRA        float64 `yaml:"lra" json:"lra"`               // 目标响度范围 1 到 50 LU，为0时使用 7
+}
+
+// targets 获取实际使用的目标响度、真峰值和响度范围
+func (o LoudnessOptions) targets() (float64, float64, float64) {
+	integrated, truePeak, lra := o.Integrated, o.TruePeak, o.LRA
+	if integrated == 0 {
+		integrated = defaultLoudnessIntegrated
+	}
+	if truePeak == 0 {
+		truePeak = defaultLoudnessTruePeak
+	}
+	if lra == 0 {
+		lra = defaultLoudnessRange
+	}
+	return integrated, truePeak, lra
+}
+
+// validateLoudness 校验响度标准化设置
+func validateLoudness(options LoudnessOptions) error {
+	if !options.Enabled {
+		return nil
+	}
+	integrated, truePeak, lra := options.targets()
+	if integrated < -70 || integrated > -5 {
+		return fmt.Errorf("目标响度必须在 -70 到 -5 LUFS 之间: %g", integrated)
+	}
+	if truePeak < -9 || truePeak > 0 {
+		return fmt.Errorf("真峰值必须在 -9 到 0 dBTP 之间: %g", truePeak)
+	}
+	if lra < 1 || lra > 50 {
+		return fmt.Errorf("响度范围必须在 1 到 50 LU 之间: %g", lra)
+	}
+	return nil
+}
+
+// resolveLoudnessParams 响度标准化需要重新编码音频，直接复制的音频改为重新编码，源视频没有音频时忽略
+func resolveLoudnessParams(params TranscodeParams, inputInfo *VideoInfo) (TranscodeParams, []string) {
+	if !params.Loudness.Enabled || params.MuteAudio {
+		return params, nil
+	}
+	if inputInfo != nil && inputInfo.AudioCodec == "" {
+		params.Loudness.Enabled = false
+		return params, []string{"源视频没有音频，已忽略响度标准化"}
+	}
+	if params.AudioCodec != "copy" {
+		return params, nil
+	}
+	sourceCodec := ""
+	if inputInfo != nil {
+		sourceCodec = inputInfo.AudioCodec
+	}
+	params.AudioCodec = reencodeAudioCodec(params.Container, sourceCodec)
+	return params, []string{fmt.Sprintf("响度标准化需要重新编码音频，已使用 %s 编码", params.AudioCodec)}
+}
+
+// loudnormStats 第一遍 loudnorm 输出的统计结果，FFmpeg以字符串输出数值
+type loudnormStats struct {
+	InputI       string `json:"input_i"`
+	InputTP      string `json:"input_tp"`
+	InputLRA     string `json:"input_lra"`
+	InputThresh  string `json:"input_thresh"`
+	TargetOffset string `json:"target_offset"`
+
+	lines   []string // 正在读取的JSON
+	reading bool
+	parsed  bool
+}
+
+// parseLine 从stderr中读取loudnorm滤镜输出的JSON，JSON在 [Parsed_loudnorm_0 @ ...] 之后单独输出
+func (s *loudnormStats) parseLine(line string) {
+	line = strings.TrimSpace(line)
+	switch {
+	case strings.Contains(line, "Parsed_loudnorm"):
+		s.lines, s.reading = nil, false
+	case line == "{":
+		s.lines, s.reading = []string{line}, true
+	case s.reading:
+		s.lines = append(s.lines, line)
+		if line == "}" {
+			s.reading = false
+			s.parsed = json.Unmarshal([]byte(strings.Join(s.lines, "\n")), s) == nil
+		}
+	}
+}
+
+// measured 获取分析结果，音频全部为静音时响度为 -inf，无法按分析结果调整
+func (s *loudnormStats) measured() (map[string]float64, error) {
+	if !s.parsed {
+		return nil, fmt.Errorf("没有读取到loudnorm的分析结果")
+	}
+	values := map[string]float64{}
+	for name, value := range map[string]string{
+		"measured_I":      s.InputI,
+		"measured_TP":     s.InputTP,
+		"measured_LRA":    s.InputLRA,
+		"measured_thresh": s.InputThresh,
+		"offset":          s.TargetOffset,
+	} {
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || math.IsInf(number, 0) || math.IsNaN(number) {
+			return nil, fmt.Errorf("音频响度无法测量（%s=%s），可能全部为静音", name, value)
+		}
+		values[name] = number
+	}
+	return values, nil
+}
+
+// loudnormFilter 生成 loudnorm 滤镜，stats 为nil时只分析响度并以JSON输出结果
+//
+// 第二遍使用分析结果线性调整音量，loudnorm会升采样到192kHz，之后重新采样回源音频的采样率
+func loudnormFilter(options LoudnessOptions, stats map[string]float64, sampleRate int) string {
+	integrated, truePeak, lra := options.targets()
+	filter := fmt.Sprintf("loudnorm=I=%s:TP=%s:LRA=%s", formatLoudness(integrated), formatLoudness(truePeak), formatLoudness(lra))
+	if stats == nil {
+		return filter + ":print_format=json"
+	}
+	for _, name := range []string{"measured_I", "measured_TP", "measured_LRA", "measured_thresh", "offset"} {
+		filter += fmt.Sprintf(":%s=%s", name, formatLoudness(stats[name]))
+	}
+	if sampleRate <= 0 {
+		sampleRate = defaultLoudnormSampleRate
+	}
+	return fmt.Sprintf("%s:linear=true:print_format=summary,aresample=%d", filter, sampleRate)
+}
+
+func formatLoudness(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// buildLoudnormMeasureArgs 生成分析响度的FFmpeg参数，只解码输出的第一个音轨，截取时只分析截取的范围
+func buildLoudnormMeasureArgs(inputFilePath string, params TranscodeParams, inputInfo *VideoInfo) []string {
+	args := []string{"-y"}
+	args = append(args, trimInputArgs(params.Trim)...)
+	args = append(args, "-i", inputFilePath)
+	if _, length, err := params.Trim.bounds(); err == nil && length > 0 {
+		args = append(args, "-t", formatSeconds(length))
+	}
+	args = append(args, "-map", firstAudioMap(inputFilePath, params, inputInfo), "-af", loudnormFilter(params.Loudness, nil, 0), "-vn", "-sn", "-dn")
+	return append(args, "-progress", "pipe:2", "-nostats", "-f", "null", os.DevNull)
+}
+
+// withAudioFilter 在输出文件之前加入音频滤镜，已有 -af 时追加到滤镜链末尾，不输出音频的命令保持不变
+func withAudioFilter(args []string, filter string) []string {
+	if containsString(args, "-an") {
+		return args
+	}
+	result := append([]string{}, args...)
+	for i := 0; i+1 < len(result); i++ {
+		if result[i] == "-af" {
+			result[i+1] += "," + filter
+			return result
+		}
+	}
+	index := len(result) - 1
+	for i, arg := range result {
+		if arg == "-progress" {
+			index = i
+			break
+		}
+	}
+	return append(result[:index], append([]string{"-af", filter}, result[index:]...)...)
+}
+
+// addLoudnormSteps 在转码步骤之前加入响度分析，之后输出音频的步骤在执行前按分析结果加入 loudnorm 滤镜
+//
+// 两遍的进度合并计算，分析命令失败时任务失败；没有读取到分析结果或音频无法测量时不调整音量，只在结果中提示
+func addLoudnormSteps(steps []transcodeStep, inputFilePath string, params TranscodeParams, inputInfo *VideoInfo, duration float64, result *TranscodeResult) []transcodeStep {
+	stats := &loudnormStats{}
+	measure := transcodeStep{
+		Name:     "响度分析",
+		Args:     buildLoudnormMeasureArgs(inputFilePath, params, inputInfo),
+		Duration: duration,
+		Output:   stats.parseLine,
+	}
+	sampleRate := 0
+	if inputInfo != nil {
+		sampleRate = inputInfo.SampleRate
+	}
+	warned := false
+	prepare := func(args []string) ([]string, error) {
+		measured, err := stats.measured()
+		if err != nil {
+			if !warned {
+				warned = true
+				result.Warnings = append(result.Warnings, fmt.Sprintf("%v，已跳过响度标准化", err))
+			}
+			return args, nil
+		}
+		return withAudioFilter(args, loudnormFilter(params.Loudness, measured, sampleRate)), nil
+	}
+	for i := range steps {
+		if steps[i].Name == "" {
+			steps[i].Name = "响度标准化"
+		}
+		steps[i].Prepare = prepare
+	}
+	return append([]transcodeStep{measure}, steps...)
+}
@@ -0,0 +1,82 @@
+package process
+
+import (
+	"strings"
+	"testing"
+)
+
+const loudnormOutput = `[out#0/null @ 0x55d] video:0kB audio:12345kB
+[Parsed_loudnorm_0 @ 0x55e]
+{
+	"input_i" : "-23.54",
+	"input_tp" : "-7.96",
+	"input_lra" : "9.30",
+	"input_thresh" : "-34.18",
+	"output_i" : "-16.02",
+	"output_tp" : "-1.50",
+	"output_lra" : "7.10",
+	"output_thresh" : "-26.55",
+	"normalization_type" : "dynamic",
+	"target_offset" : "0.02"
+}
+size=N/A time=00:01:00.00 bitrate=N/A speed=120x`
+
+func TestLoudnormStatsParseLine(t *testing.T) {
+	silent := strings.NewReplacer(`"-23.54"`, `"-inf"`, `"-7.96"`, `"-inf"`).Replace(loudnormOutput)
+	tests := []struct {
+		name       string
+		output     string
+		wantParsed bool
+		want       map[string]float64
+		wantErr    bool
+	}{
+		{
+			name:       "读取分析结果",
+			output:     loudnormOutput,
+			wantParsed: true,
+			want: map[string]float64{
+				"measured_I":      -23.54,
+				"measured_TP":     -7.96,
+				"measured_LRA":    9.30,
+				"measured_thresh": -34.18,
+				"offset":          0.02,
+			},
+		},
+		{
+			name:       "Windows换行",
+			output:     strings.ReplaceAll(loudnormOutput, "\n", "\r\n"),
+			wantParsed: true,
+			want: map[string]float64{
+				"measured_I":      -23.54,
+				"measured_TP":     -7.96,
+				"measured_LRA":    9.30,
+				"measured_thresh": -34.18,
+				"offset":          0.02,
+			},
+		},
+		{name: "全部为静音", output: silent, wantParsed: true, wantErr: true},
+		{name: "没有输出", output: "size=N/A time=00:01:00.00 bitrate=N/A speed=120x", wantErr: true},
+		{name: "JSON不完整", output: strings.SplitAfter(loudnormOutput, `"input_lra" : "9.30",`)[0], wantErr: true},
+		{name: "JSON无效", output: "[Parsed_loudnorm_0 @ 0x1]\n{\n\"input_i\" : -23.54,\n}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stats loudnormStats
+			for _, line := range strings.Split(tt.output, "\n") {
+				stats.parseLine(line)
+			}
+			if stats.parsed != tt.wantParsed {
+				t.Errorf("parsed = %v, want %v", stats.parsed, tt.wantParsed)
+			}
+			got, err := stats.measured()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("measured() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for name, value := range tt.want {
+				if got[name] != value {
+					t.Errorf("%s = %v, want %v", name, got[name], value)
+				}
+			}
+		})
+	}
+}
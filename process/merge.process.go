@@ -0,0 +1,477 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// mergeDefaultSampleRate 统一音频参数时，所有文件都没有音频采样率信息时使用的采样率
+	mergeDefaultSampleRate = 48000
+	// mergeDefaultFrameRate 统一帧率时，第一个文件没有帧率信息时使用的帧率
+	mergeDefaultFrameRate = "30"
+)
+
+// MergeOptions 合并任务的选项
+type MergeOptions struct {
+	Inputs     []string `json:"inputs"`     // 按顺序合并的文件，至少两个
+	Crossfade  float64  `json:"crossfade"`  // 相邻片段之间的转场时长（秒），为0时直接拼接
+	Transition string   `json:"transition"` // 转场效果，为空时使用 fade
+}
+
+// mergeTransitions 可用的转场效果，对应FFmpeg xfade滤镜的 transition 参数
+var mergeTransitions = []string{
+	"fade", "dissolve", "fadeblack", "fadewhite",
+	"wipeleft", "wiperight", "wipeup", "wipedown",
+	"slideleft", "slideright", "slideup", "slidedown",
+	"circleopen", "circleclose", "radial", "pixelize",
+}
+
+// transition 获取实际使用的转场效果
+func (o MergeOptions) transition() string {
+	if o.Transition == "" {
+		return "fade"
+	}
+	return o.Transition
+}
+
+// validateMergeJob 校验合并任务的选项和参数，两遍编码、截取、分段和响度标准化只适用于单个文件
+func validateMergeJob(job TranscodeJob) error {
+	if job.Merge == nil {
+		return fmt.Errorf("合并任务缺少合并选项")
+	}
+	if len(job.Merge.Inputs) < 2 {
+		return fmt.Errorf("合并至少需要两个文件")
+	}
+	if job.Merge.Crossfade < 0 {
+		return fmt.Errorf("转场时长不能为负数: %g", job.Merge.Crossfade)
+	}
+	if !containsString(mergeTransitions, job.Merge.transition()) {
+		return fmt.Errorf("无效的转场效果: %s，可用: %s", job.Merge.Transition, strings.Join(mergeTransitions, "、"))
+	}
+	if usesTwoPass(job.Params) {
+		return fmt.Errorf("合并不支持两遍编码和目标大小")
+	}
+	if !job.Params.Trim.IsZero() || (job.Trim != nil && !job.Trim.IsZero()) {
+		return fmt.Errorf("合并不支持截取")
+	}
+	if !job.Params.Segment.IsZero() {
+		return fmt.Errorf("合并不支持分段输出")
+	}
+	if job.Params.Loudness.Enabled {
+		return fmt.Errorf("合并不支持响度标准化")
+	}
+	if !job.Params.Streams.IsZero() {
+		return fmt.Errorf("合并不支持选择音轨和字幕")
+	}
+	if job.Params.BurnSubtitle.Enabled() {
+		return fmt.Errorf("合并不支持烧录字幕")
+	}
+	if options := job.Params.SoftSubtitles; options.muxes() || options.Extract != SubtitleFormat_None {
+		return fmt.Errorf("合并不支持封装和导出字幕")
+	}
+	return nil
+}
+
+// mergeStreamMismatch 比较所有文件的视频和音频参数，不一致时返回原因，一致时可以用concat分离器直接拼接
+func mergeStreamMismatch(infos []VideoInfo) string {
+	first := infos[0]
+	for _, info := range infos[1:] {
+		differs := func(property, a, b string) string {
+			return fmt.Sprintf("%s 的%s（%s）与 %s（%s）不同", info.Name, property, b, first.Name, a)
+		}
+		switch {
+		case info.VideoCodec != first.VideoCodec:
+			return differs("视频编码", first.VideoCodec, info.VideoCodec)
+		case info.Width != first.Width || info.Height != first.Height:
+			return differs("分辨率", fmt.Sprintf("%d×%d", first.Width, first.Height), fmt.Sprintf("%d×%d", info.Width, info.Height))
+		case info.FrameRate != first.FrameRate:
+			return differs("帧率", first.FrameRate, info.FrameRate)
+		case info.SAR != first.SAR:
+			return differs("像素宽高比", first.SAR, info.SAR)
+		case info.PixelFormat != first.PixelFormat:
+			return differs("像素格式", first.PixelFormat, info.PixelFormat)
+		case info.AudioCodec != first.AudioCodec:
+			return differs("音频编码", first.AudioCodec, info.AudioCodec)
+		case info.SampleRate != first.SampleRate:
+			return differs("采样率", strconv.Itoa(first.SampleRate), strconv.Itoa(info.SampleRate))
+		case info.Channels != first.Channels:
+			return differs("声道数", strconv.Itoa(first.Channels), strconv.Itoa(info.Channels))
+		}
+	}
+	return ""
+}
+
+// resolveMergeParams 使用concat滤镜时必须重新编码，直接复制的流改为与第一个文件相同的编码
+func resolveMergeParams(params TranscodeParams, infos []VideoInfo) (TranscodeParams, []string) {
+	var warnings []string
+	format, hasFormat := getContainerFormat(params.Container)
+	if videoCodecName(params) == "" && !(hasFormat && format.AudioOnly) {
+		params.VideoCodec = "h264"
+		if codec, ok := sourceVideoCodecs[infos[0].VideoCodec]; ok {
+			params.VideoCodec = codec
+		}
+		warnings = append(warnings, fmt.Sprintf("统一视频参数需要重新编码视频，已使用 %s 编码", params.VideoCodec))
+	}
+	if params.AudioCodec != "copy" {
+		return params, warnings
+	}
+	for _, info := range infos {
+		if info.AudioCodec == "" {
+			continue
+		}
+		params.AudioCodec = reencodeAudioCodec(params.Container, info.AudioCodec)
+		warnings = append(warnings, fmt.Sprintf("统一音频参数需要重新编码音频，已使用 %s 编码", params.AudioCodec))
+		break
+	}
+	return params, warnings
+}
+
+// mergeDuration 合并后的总时长，有转场时每个转场重叠一段
+func mergeDuration(infos []VideoInfo, crossfade float64) float64 {
+	var total float64
+	for _, info := range infos {
+		if info.Duration <= 0 {
+			return 0
+		}
+		total += info.Duration
+	}
+	return total - crossfade*float64(len(infos)-1)
+}
+
+// buildMergeDemuxerArgs 生成使用concat分离器合并的参数，所有文件的流参数一致时不需要解码
+func buildMergeDemuxerArgs(listPath string, inputInfo *VideoInfo, outputFilePath string, params TranscodeParams, encoders encoderSelection) []string {
+	args := []string{"-y"}
+	if encoders.Backend != nil {
+		args = append(args, encoders.Backend.inputArgs(encoders.Device)...)
+	}
+	args = append(args, "-f", "concat", "-safe", "0", "-i", listPath)
+	if params.CpuThreads > 0 {
+		args = append(args, "-threads", strconv.Itoa(params.CpuThreads))
+	}
+
+	format, hasFormat := getContainerFormat(params.Container)
+	if hasFormat && format.AudioOnly {
+		args = append(args, "-vn")
+	} else {
+		args = append(args, "-c:v", encoders.Video)
+	}
+	args = append(args, audioOutputArgs(params, encoders, encodePass{})...)
+	if !(hasFormat && format.AudioOnly) {
+		if videoFilters := videoFilterChain("", params, encoders); len(videoFilters) > 0 {
+			args = append(args, "-vf", strings.Join(videoFilters, ","))
+		}
+		if params.Fps != "copy" {
+			args = append(args, "-r", params.Fps)
+		}
+		encodeArgs, _ := videoEncodeArgs(params, encoders, encodePass{})
+		args = append(args, encodeArgs...)
+	}
+	if hasFormat {
+		args = append(args, format.muxArgs(encoders, inputInfo)...)
+	}
+	return append(args, "-progress", "pipe:2", "-nostats", outputFilePath)
+}
+
+// buildMergeFilterGraph 生成concat滤镜的滤镜图，把每个文件缩放到第一个文件的分辨率、帧率和音频参数
+//
+// 分辨率不同时保持比例缩放并加黑边；没有音频的文件补充静音，有转场时使用xfade和acrossfade依次叠加相邻片段。
+// 视频输出为 [vcat]，有音频时音频输出为 [acat]；只输出音频时 includeVideo 为false，不处理视频；去掉音频时 includeAudio 为false
+func buildMergeFilterGraph(infos []VideoInfo, options MergeOptions, includeVideo, includeAudio bool) (string, bool) {
+	first := infos[0]
+	frameRate := first.FrameRate
+	if frameRate == "" {
+		frameRate = mergeDefaultFrameRate
+	}
+	pixelFormat := first.PixelFormat
+	if pixelFormat == "" {
+		pixelFormat = "yuv420p"
+	}
+	sampleRate, channelLayout, hasAudio := 0, "stereo", false
+	for _, info := range infos {
+		if !includeAudio || info.AudioCodec == "" {
+			continue
+		}
+		if !hasAudio {
+			sampleRate = info.SampleRate
+			if info.Channels == 1 {
+				channelLayout = "mono"
+			}
+		}
+		hasAudio = true
+	}
+	if sampleRate <= 0 {
+		sampleRate = mergeDefaultSampleRate
+	}
+
+	var filters []string
+	for i, info := range infos {
+		if includeVideo {
+			filters = append(filters, fmt.Sprintf("[%d:v:0]scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=%s,format=%s,settb=AVTB,setpts=PTS-STARTPTS[v%d]",
+				i, first.Width, first.Height, first.Width, first.Height, frameRate, pixelFormat, i))
+		}
+		if !hasAudio {
+			continue
+		}
+		if info.AudioCodec != "" {
+			filters = append(filters, fmt.Sprintf("[%d:a:0]aresample=%d,aformat=sample_fmts=fltp:channel_layouts=%s,asetpts=PTS-STARTPTS[a%d]", i, sampleRate, channelLayout, i))
+		} else {
+			filters = append(filters, fmt.Sprintf("anullsrc=r=%d:cl=%s,atrim=duration=%s,aformat=sample_fmts=fltp[a%d]", sampleRate, channelLayout, formatSeconds(info.Duration), i))
+		}
+	}
+
+	if options.Crossfade <= 0 {
+		var inputs, outputs strings.Builder
+		videoCount, audioCount := 0, 0
+		for i := range infos {
+			if includeVideo {
+				fmt.Fprintf(&inputs, "[v%d]", i)
+			}
+			if hasAudio {
+				fmt.Fprintf(&inputs, "[a%d]", i)
+			}
+		}
+		if includeVideo {
+			videoCount = 1
+			outputs.WriteString("[vcat]")
+		}
+		if hasAudio {
+			audioCount = 1
+			outputs.WriteString("[acat]")
+		}
+		filters = append(filters, fmt.Sprintf("%sconcat=n=%d:v=%d:a=%d%s", inputs.String(), len(infos), videoCount, audioCount, outputs.String()))
+		return strings.Join(filters, ";"), hasAudio
+	}
+
+	// 第i个转场从前面所有片段的总时长减去i个转场时长处开始
+	videoLabel, audioLabel, offset := "v0", "a0", 0.0
+	for i := 1; i < len(infos); i++ {
+		offset += infos[i-1].Duration - options.Crossfade
+		nextVideo, nextAudio := fmt.Sprintf("vx%d", i), fmt.Sprintf("ax%d", i)
+		if i == len(infos)-1 {
+			nextVideo, nextAudio = "vcat", "acat"
+		}
+		if includeVideo {
+			filters = append(filters, fmt.Sprintf("[%s][v%d]xfade=transition=%s:duration=%s:offset=%s[%s]",
+				videoLabel, i, options.transition(), formatSeconds(options.Crossfade), formatSeconds(offset), nextVideo))
+		}
+		if hasAudio {
+			filters = append(filters, fmt.Sprintf("[%s][a%d]acrossfade=d=%s[%s]", audioLabel, i, formatSeconds(options.Crossfade), nextAudio))
+		}
+		videoLabel, audioLabel = nextVideo, nextAudio
+	}
+	return strings.Join(filters, ";"), hasAudio
+}
+
+// labelFilterChain 把 -vf 形式的滤镜链接到滤镜图的输入和输出标签上
+//
+// 图片水印的滤镜使用 [in] 表示视频输入，需要替换为实际的输入标签
+func labelFilterChain(filters []string, input, output string) string {
+	chain := strings.Join(filters, ",")
+	if strings.Contains(chain, "[in]") {
+		return strings.ReplaceAll(chain, "[in]", "["+input+"]") + "[" + output + "]"
+	}
+	return "[" + input + "]" + chain + "[" + output + "]"
+}
+
+// buildMergeFilterArgs 生成使用concat滤镜合并的参数，每个文件作为单独的输入
+func buildMergeFilterArgs(infos []VideoInfo, outputFilePath string, params TranscodeParams, encoders encoderSelection, options MergeOptions) []string {
+	args := []string{"-y"}
+	if encoders.Backend != nil {
+		args = append(args, encoders.Backend.inputArgs(encoders.Device)...)
+	}
+	for _, info := range infos {
+		args = append(args, "-i", info.Path)
+	}
+	if params.CpuThreads > 0 {
+		args = append(args, "-threads", strconv.Itoa(params.CpuThreads))
+	}
+
+	format, hasFormat := getContainerFormat(params.Container)
+	audioOnly := hasFormat && format.AudioOnly
+	graph, hasAudio := buildMergeFilterGraph(infos, options, !audioOnly, !params.MuteAudio)
+	videoOutput := "vcat"
+	if videoFilters := videoFilterChain("", params, encoders); len(videoFilters) > 0 && !audioOnly {
+		graph += ";" + labelFilterChain(videoFilters, "vcat", "vout")
+		videoOutput = "vout"
+	}
+	args = append(args, "-filter_complex", graph)
+	if !audioOnly {
+		args = append(args, "-map", "["+videoOutput+"]", "-c:v", encoders.Video)
+	}
+	if hasAudio {
+		args = append(args, "-map", "[acat]")
+		args = append(args, audioOutputArgs(params, encoders, encodePass{})...)
+	}
+	if !audioOnly {
+		if params.Fps != "copy" {
+			args = append(args, "-r", params.Fps)
+		}
+		encodeArgs, _ := videoEncodeArgs(params, encoders, encodePass{})
+		args = append(args, encodeArgs...)
+	}
+	if hasFormat {
+		args = append(args, format.muxArgs(encoders, &infos[0])...)
+	}
+	return append(args, "-progress", "pipe:2", "-nostats", outputFilePath)
+}
+
+// mergeListPrefix 获取合并任务的文件列表路径前缀，放在系统临时目录中
+func mergeListPrefix(id string) string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("cm_video_batch_process-%s-merge", id))
+}
+
+// VideoMergeProcessor 按顺序把多个文件合并为一个输出文件
+//
+// 所有文件的编码、分辨率、帧率、像素宽高比和音频参数一致且没有转场时，使用concat分离器直接拼接；
+// 否则使用concat滤镜统一参数后重新编码
+func VideoMergeProcessor(ctx context.Context, job TranscodeJob) TranscodeResult {
+	id, params := job.ID, job.Params
+	taskCtx, done := registerTranscodeTask(ctx, id)
+	defer done()
+
+	startTime := time.Now()
+	result := TranscodeResult{
+		ID:        id,
+		Status:    TranscodeResultStatus_Failed,
+		InputPath: job.Path,
+		ExitCode:  -1,
+	}
+	fail := func(kind TranscodeErrorKind, format string, a ...interface{}) TranscodeResult {
+		result.ErrorKind = kind
+		result.Error = fmt.Sprintf(format, a...)
+		result.ElapsedSeconds = time.Since(startTime).Seconds()
+		return result
+	}
+
+	if err := validateMergeJob(job); err != nil {
+		return fail(TranscodeErrorKind_InvalidParams, "参数无效: %v", err)
+	}
+	if err := validateTranscodeParams(params); err != nil {
+		return fail(TranscodeErrorKind_InvalidParams, "参数无效: %v", err)
+	}
+	options := *job.Merge
+	if job.Path == "" {
+		job.Path = options.Inputs[0]
+		result.InputPath = job.Path
+	}
+	result.InputPaths = options.Inputs
+
+	// 读取每个文件的流参数
+	infos := make([]VideoInfo, len(options.Inputs))
+	var totalSize int64
+	for i, input := range options.Inputs {
+		info, err := probeVideoInfo(input)
+		if err != nil {
+			return fail(TranscodeErrorKind_BadInput, "读取 %s 失败: %v", input, err)
+		}
+		info.ID, info.Path = id, input
+		infos[i] = info
+		totalSize += info.Size
+		// 中间的文件开头和结尾都有转场，两段转场不能重叠
+		minDuration := options.Crossfade
+		if i > 0 && i < len(options.Inputs)-1 {
+			minDuration *= 2
+		}
+		if options.Crossfade > 0 && info.Duration <= minDuration {
+			return fail(TranscodeErrorKind_InvalidParams, "参数无效: %s 的时长 %.1f 秒不足以添加 %g 秒的转场", info.Name, info.Duration, options.Crossfade)
+		}
+	}
+	result.InputInfo = &infos[0]
+
+	// 流参数一致且没有转场时直接拼接，否则统一参数后重新编码
+	useFilter := options.Crossfade > 0
+	if reason := mergeStreamMismatch(infos); reason != "" {
+		useFilter = true
+		result.Warnings = append(result.Warnings, "文件的参数不一致，需要统一参数后合并: "+reason)
+	}
+	if useFilter {
+		var mergeWarnings []string
+		params, mergeWarnings = resolveMergeParams(params, infos)
+		result.Warnings = append(result.Warnings, mergeWarnings...)
+	}
+
+	params, containerWarnings, err := resolveContainerParams(params, &infos[0])
+	if err != nil {
+		return fail(TranscodeErrorKind_InvalidParams, "参数无效: %v", err)
+	}
+	result.Warnings = append(result.Warnings, containerWarnings...)
+	params, audioWarnings, err := resolveAudioParams(params, &infos[0])
+	if err != nil {
+		return fail(TranscodeErrorKind_InvalidParams, "参数无效: %v", err)
+	}
+	result.Warnings = append(result.Warnings, audioWarnings...)
+	job.Params = params
+
+	outputFilePath, skip, kind, err := prepareOutputPath(job, result.InputInfo)
+	result.OutputPath = outputFilePath
+	if skip {
+		consolePrintf(ctx, "输出文件已存在，跳过: %s\n", outputFilePath)
+		result.Status = TranscodeResultStatus_Skipped
+		result.ElapsedSeconds = time.Since(startTime).Seconds()
+		return result
+	}
+	if err != nil {
+		return fail(kind, "%v", err)
+	}
+	defer releaseOutputPath(outputFilePath)
+
+	tempFilePath := tempOutputPath(outputFilePath, id)
+	defer os.Remove(tempFilePath)
+
+	caps, err := GetFFmpegCapabilities()
+	if err != nil {
+		consolePrintf(ctx, "警告: 无法探测FFmpeg支持的编码器: %v\n", err)
+	}
+	encoders, err := resolveEncoders(params, caps)
+	if err != nil {
+		return fail(TranscodeErrorKind_MissingEncoder, "%v", err)
+	}
+	if err := validateQuality(params, encoders); err != nil {
+		return fail(TranscodeErrorKind_InvalidParams, "参数无效: %v", err)
+	}
+	result.VideoEncoder, result.AudioEncoder = encoders.Video, encoders.Audio
+	result.Warnings = append(result.Warnings, encoders.Warnings...)
+	_, ignored := videoEncodeArgs(params, encoders, encodePass{})
+	result.Warnings = append(result.Warnings, ignored...)
+	result.Passes = 1
+
+	var args []string
+	if useFilter {
+		args = buildMergeFilterArgs(infos, tempFilePath, params, encoders, options)
+	} else {
+		prefix := mergeListPrefix(id)
+		defer removeTempFiles(prefix)
+		listPath := prefix + "-list.txt"
+		if err := writeConcatList(listPath, options.Inputs); err != nil {
+			return fail(classifyFileError(err), "创建临时文件失败: %v", err)
+		}
+		args = buildMergeDemuxerArgs(listPath, result.InputInfo, tempFilePath, params, encoders)
+	}
+	for _, warning := range result.Warnings {
+		consolePrintf(ctx, "警告: %s\n", warning)
+	}
+
+	duration := mergeDuration(infos, options.Crossfade)
+	steps := []transcodeStep{{Args: args, Duration: duration}}
+	if kind, err := runTranscodeSteps(ctx, taskCtx, id, steps, duration, &result); err != nil {
+		return fail(kind, "%v", err)
+	}
+	if err := commitTranscodeOutput(ctx, id, tempFilePath, outputFilePath, &result); err != nil {
+		return fail(classifyFileError(err), "保存输出文件失败: %v", err)
+	}
+	// 输出大小与所有输入文件的总大小比较
+	if result.OutputInfo != nil && totalSize > 0 {
+		result.SizeRatio = float64(result.OutputInfo.Size) / float64(totalSize)
+	}
+
+	consolePrintf(ctx, "合并视频成功: %s\n", outputFilePath)
+	result.Status = TranscodeResultStatus_Success
+	result.ElapsedSeconds = time.Since(startTime).Seconds()
+	return result
+}
@@ -0,0 +1,133 @@
+package process
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildMergeFilterGraph(t *testing.T) {
+	clip := func(duration float64, audioCodec string) VideoInfo {
+		return VideoInfo{
+			Width: 1920, Height: 1080, Duration: duration, FrameRate: "30000/1001", PixelFormat: "yuv420p",
+			AudioCodec: audioCodec, SampleRate: 44100, Channels: 2,
+		}
+	}
+	silent := clip(5, "")
+	noFormat := VideoInfo{Width: 1280, Height: 720, Duration: 4, AudioCodec: "aac", Channels: 1}
+
+	tests := []struct {
+		name         string
+		infos        []VideoInfo
+		options      MergeOptions
+		includeVideo bool
+		includeAudio bool
+		want         []string // 滤镜图中必须包含的滤镜
+		absent       []string
+		wantAudio    bool
+	}{
+		{
+			name:         "直接拼接",
+			infos:        []VideoInfo{clip(10, "aac"), clip(8, "aac")},
+			includeVideo: true,
+			includeAudio: true,
+			want: []string{
+				"[0:v:0]scale=1920:1080:force_original_aspect_ratio=decrease,pad=1920:1080:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=30000/1001,format=yuv420p,settb=AVTB,setpts=PTS-STARTPTS[v0]",
+				"[1:a:0]aresample=44100,aformat=sample_fmts=fltp:channel_layouts=stereo,asetpts=PTS-STARTPTS[a1]",
+				"[v0][a0][v1][a1]concat=n=2:v=1:a=1[vcat][acat]",
+			},
+			absent:    []string{"xfade"},
+			wantAudio: true,
+		},
+		{
+			name:         "没有音频的文件补充静音",
+			infos:        []VideoInfo{clip(10, "aac"), silent},
+			includeVideo: true,
+			includeAudio: true,
+			want:         []string{"anullsrc=r=44100:cl=stereo,atrim=duration=5.000,aformat=sample_fmts=fltp[a1]"},
+			wantAudio:    true,
+		},
+		{
+			name:         "所有文件都没有音频",
+			infos:        []VideoInfo{silent, silent},
+			includeVideo: true,
+			includeAudio: true,
+			want:         []string{"[v0][v1]concat=n=2:v=1:a=0[vcat]"},
+			absent:       []string{"anullsrc", "[acat]"},
+		},
+		{
+			name:         "去掉音频",
+			infos:        []VideoInfo{clip(10, "aac"), clip(8, "aac")},
+			includeVideo: true,
+			want:         []string{"[v0][v1]concat=n=2:v=1:a=0[vcat]"},
+			absent:       []string{"aresample"},
+		},
+		{
+			name:         "只输出音频",
+			infos:        []VideoInfo{clip(10, "aac"), clip(8, "aac")},
+			includeAudio: true,
+			want:         []string{"[a0][a1]concat=n=2:v=0:a=1[acat]"},
+			absent:       []string{"[0:v:0]", "[vcat]"},
+			wantAudio:    true,
+		},
+		{
+			name:         "缺少帧率和像素格式时使用默认值",
+			infos:        []VideoInfo{noFormat, clip(8, "aac")},
+			includeVideo: true,
+			includeAudio: true,
+			want: []string{
+				"[0:v:0]scale=1280:720:force_original_aspect_ratio=decrease,pad=1280:720:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=30,format=yuv420p",
+				"[1:a:0]aresample=48000,aformat=sample_fmts=fltp:channel_layouts=mono",
+			},
+			wantAudio: true,
+		},
+		{
+			name:         "转场",
+			infos:        []VideoInfo{clip(10, "aac"), clip(8, "aac"), clip(6, "aac")},
+			options:      MergeOptions{Crossfade: 1},
+			includeVideo: true,
+			includeAudio: true,
+			want: []string{
+				"[v0][v1]xfade=transition=fade:duration=1.000:offset=9.000[vx1]",
+				"[vx1][v2]xfade=transition=fade:duration=1.000:offset=16.000[vcat]",
+				"[a0][a1]acrossfade=d=1.000[ax1]",
+				"[ax1][a2]acrossfade=d=1.000[acat]",
+			},
+			absent:    []string{"concat="},
+			wantAudio: true,
+		},
+		{
+			name:         "指定转场效果",
+			infos:        []VideoInfo{clip(10, "aac"), silent},
+			options:      MergeOptions{Crossfade: 0.5, Transition: "wipeleft"},
+			includeVideo: true,
+			want:         []string{"[v0][v1]xfade=transition=wipeleft:duration=0.500:offset=9.500[vcat]"},
+			absent:       []string{"acrossfade"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph, hasAudio := buildMergeFilterGraph(tt.infos, tt.options, tt.includeVideo, tt.includeAudio)
+			if hasAudio != tt.wantAudio {
+				t.Errorf("hasAudio = %v, want %v", hasAudio, tt.wantAudio)
+			}
+			filters := strings.Split(graph, ";")
+			for _, want := range tt.want {
+				found := false
+				for _, filter := range filters {
+					if strings.HasPrefix(filter, want) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					t.Errorf("缺少滤镜 %s\n滤镜图: %s", want, graph)
+				}
+			}
+			for _, absent := range tt.absent {
+				if strings.Contains(graph, absent) {
+					t.Errorf("不应包含 %s\n滤镜图: %s", absent, graph)
+				}
+			}
+		})
+	}
+}
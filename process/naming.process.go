@@ -0,0 +1,156 @@
+package process
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultOutputNameTemplate 默认输出文件名模板，与输入文件同名
+const defaultOutputNameTemplate = "{name}.{ext}"
+
+// outputNameTokens 输出文件名模板支持的变量
+var outputNameTokens = []string{"name", "ext", "height", "codec", "fps", "date", "index", "preset", "parentdir"}
+
+var (
+	outputNameTokenRegex = regexp.MustCompile(`\{([^{}]*)\}`)
+	// Windows、macOS、Linux 文件名中都不能使用的字符
+	illegalFileNameChars = regexp.MustCompile(`[<>:"|?*\\\x00-\x1f]`)
+	// Windows 保留的设备名
+	reservedFileNames = regexp.MustCompile(`(?i)^(con|prn|aux|nul|com[0-9]|lpt[0-9])(\..*)?$`)
+)
+
+// GetOutputNameTemplate 获取输出文件名模板
+func GetOutputNameTemplate() string {
+	if Config.OutputNameTemplate == "" {
+		return defaultOutputNameTemplate
+	}
+	return Config.OutputNameTemplate
+}
+
+// ValidateOutputNameTemplate 校验输出文件名模板
+//
+// 模板中可以使用 / 分隔子目录，变量写作 {name}，
+// 除变量外的文字不能包含任何系统中的非法字符，也不能生成 .. 等跳出输出目录的路径
+func ValidateOutputNameTemplate(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return fmt.Errorf("文件名模板不能为空")
+	}
+	for _, match := range outputNameTokenRegex.FindAllStringSubmatch(template, -1) {
+		if !isOutputNameToken(match[1]) {
+			return fmt.Errorf("未知的模板变量: {%s}，可用变量: {%s}", match[1], strings.Join(outputNameTokens, "}、{"))
+		}
+	}
+	literal := outputNameTokenRegex.ReplaceAllString(template, "")
+	if strings.ContainsAny(literal, "{}") {
+		return fmt.Errorf("模板中的大括号不匹配")
+	}
+	if illegalFileNameChars.MatchString(literal) {
+		return fmt.Errorf(`模板中不能包含以下字符: < > : " | ? * \`)
+	}
+
+	// 使用示例值渲染后检查每一级路径
+	sample := map[string]string{}
+	for _, token := range outputNameTokens {
+		sample[token] = "x"
+	}
+	_, err := renderOutputName(template, sample)
+	return err
+}
+
+func isOutputNameToken(token string) bool {
+	for _, t := range outputNameTokens {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
+
+// renderOutputName 使用变量值渲染模板，返回相对于输出目录的路径（使用 / 分隔）
+//
+// 变量值中的非法字符会被替换为 _，模板中没有 {ext} 时自动追加扩展名
+func renderOutputName(template string, values map[string]string) (string, error) {
+	if !strings.Contains(template, "{ext}") && values["ext"] != "" {
+		template += ".{ext}"
+	}
+	rendered := outputNameTokenRegex.ReplaceAllStringFunc(template, func(token string) string {
+		value := values[strings.Trim(token, "{}")]
+		return illegalFileNameChars.ReplaceAllString(strings.ReplaceAll(value, "/", "_"), "_")
+	})
+
+	if strings.HasPrefix(rendered, "/") {
+		return "", fmt.Errorf("文件名模板不能以 / 开头")
+	}
+	segments := strings.Split(rendered, "/")
+	for _, segment := range segments {
+		switch {
+		case strings.TrimSpace(segment) == "":
+			return "", fmt.Errorf("文件名模板生成了空的文件夹或文件名: %s", rendered)
+		case segment == "." || segment == "..":
+			return "", fmt.Errorf("文件名模板不能包含 . 或 .. 路径: %s", rendered)
+		case strings.HasSuffix(segment, ".") || strings.HasSuffix(segment, " "):
+			return "", fmt.Errorf("文件或文件夹名不能以点或空格结尾: %s", segment)
+		case reservedFileNames.MatchString(segment):
+			return "", fmt.Errorf("文件或文件夹名是系统保留名称: %s", segment)
+		case len(segment) > 255:
+			return "", fmt.Errorf("文件或文件夹名过长: %s", segment)
+		}
+	}
+	return rendered, nil
+}
+
+// outputNameValues 获取任务对应的模板变量值
+func outputNameValues(job TranscodeJob, inputInfo *VideoInfo, ext string) map[string]string {
+	values := map[string]string{
+		"name":      GetFileNameFromPath(job.Path, false),
+		"ext":       strings.TrimPrefix(ext, "."),
+		"height":    job.Params.VideoHeight,
+		"codec":     job.Params.VideoCodec,
+		"fps":       job.Params.Fps,
+		"date":      time.Now().Format("20060102"),
+		"index":     fmt.Sprintf("%03d", job.Index),
+		"preset":    job.Params.Preset,
+		"parentdir": GetDirNameFromFilePath(job.Path),
+	}
+	// 合并任务以第一个文件命名，加上后缀避免与该文件单独转码的输出重名
+	if job.Type == TranscodeJobType_Merge {
+		values["name"] += "_merged"
+	}
+	// 参数为copy时使用源视频的值
+	if inputInfo != nil {
+		if values["height"] == "copy" || values["height"] == "" {
+			values["height"] = strconv.Itoa(inputInfo.Height)
+		}
+		if values["codec"] == "copy" || values["codec"] == "" {
+			values["codec"] = inputInfo.VideoCodec
+		}
+		if values["fps"] == "copy" || values["fps"] == "" {
+			values["fps"] = strconv.Itoa(inputInfo.FPS)
+		}
+	}
+	if values["preset"] == "" {
+		values["preset"] = "custom"
+	}
+	return values
+}
+
+// getOutputFilePath 根据文件名模板获取任务的输出文件路径，从文件夹导入的文件会保留相对的子目录
+//
+// 指定了输出格式时使用该格式的扩展名，否则与输入文件相同
+func getOutputFilePath(outputDirectory string, job TranscodeJob, inputInfo *VideoInfo) (string, error) {
+	ext := filepath.Ext(job.Path)
+	if format, ok := getContainerFormat(job.Params.Container); ok {
+		ext = format.Ext
+	}
+	values := outputNameValues(job, inputInfo, ext)
+	name, err := renderOutputName(GetOutputNameTemplate(), values)
+	if err != nil {
+		return "", err
+	}
+	subDirectory := getMirroredSubDirectory(job.Path, job.BaseDir)
+	return filepath.Join(outputDirectory, subDirectory, filepath.FromSlash(name)), nil
+}
@@ -0,0 +1,59 @@
+package process
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderOutputName(t *testing.T) {
+	values := map[string]string{
+		"name":      "video",
+		"ext":       "mp4",
+		"height":    "720",
+		"codec":     "h264",
+		"parentdir": "2023",
+		"preset":    "web-720p",
+	}
+	with := func(key, value string) map[string]string {
+		copied := map[string]string{}
+		for k, v := range values {
+			copied[k] = v
+		}
+		copied[key] = value
+		return copied
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		values   map[string]string
+		want     string
+		wantErr  bool
+	}{
+		{name: "默认模板", template: defaultOutputNameTemplate, values: values, want: "video.mp4"},
+		{name: "没有扩展名时自动追加", template: "{name}_{height}p", values: values, want: "video_720p.mp4"},
+		{name: "没有扩展名的值时不追加", template: "{name}", values: with("ext", ""), want: "video"},
+		{name: "子目录", template: "{parentdir}/{preset}/{name}.{ext}", values: values, want: "2023/web-720p/video.mp4"},
+		{name: "变量中的斜杠和非法字符被替换", template: "{name}.{ext}", values: with("name", `a/b:c?d`), want: "a_b_c_d.mp4"},
+		{name: "未知变量为空", template: "{name}{unknown}.{ext}", values: values, want: "video.mp4"},
+		{name: "以斜杠开头", template: "/{name}.{ext}", values: values, wantErr: true},
+		{name: "空的文件夹名", template: "{name}//{name}.{ext}", values: values, wantErr: true},
+		{name: "变量为空时生成空的文件夹名", template: "{preset}/{name}.{ext}", values: with("preset", ""), wantErr: true},
+		{name: "上级目录", template: "../{name}.{ext}", values: values, wantErr: true},
+		{name: "以点结尾", template: "{name}./{name}.{ext}", values: values, wantErr: true},
+		{name: "以空格结尾", template: "{name} /{name}.{ext}", values: values, wantErr: true},
+		{name: "系统保留名称", template: "{name}.{ext}", values: with("name", "CON"), wantErr: true},
+		{name: "文件名过长", template: "{name}.{ext}", values: with("name", strings.Repeat("a", 256)), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderOutputName(tt.template, tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderOutputName(%q) error = %v, wantErr %v", tt.template, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("renderOutputName(%q) = %q, want %q", tt.template, got, tt.want)
+			}
+		})
+	}
+}
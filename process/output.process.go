@@ -0,0 +1,129 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type OutputCollisionPolicy string
+
+const (
+	OutputCollisionPolicy_Skip      OutputCollisionPolicy = "skip"      // 跳过
+	OutputCollisionPolicy_Overwrite OutputCollisionPolicy = "overwrite" // 覆盖
+	OutputCollisionPolicy_Rename    OutputCollisionPolicy = "rename"    // 自动重命名
+	OutputCollisionPolicy_Fail      OutputCollisionPolicy = "fail"      // 报错
+)
+
+// errOutputExists 输出文件已存在且策略为报错
+var errOutputExists = fmt.Errorf("输出文件已存在")
+
+var (
+	reservedOutputsMu sync.Mutex
+	reservedOutputs   = map[string]bool{}
+)
+
+func isValidOutputCollisionPolicy(policy OutputCollisionPolicy) bool {
+	switch policy {
+	case OutputCollisionPolicy_Skip, OutputCollisionPolicy_Overwrite, OutputCollisionPolicy_Rename, OutputCollisionPolicy_Fail:
+		return true
+	}
+	return false
+}
+
+// GetOutputCollisionPolicy 获取输出文件同名时的处理策略，未配置时自动重命名
+func GetOutputCollisionPolicy() OutputCollisionPolicy {
+	policy := OutputCollisionPolicy(Config.OutputCollisionPolicy)
+	if !isValidOutputCollisionPolicy(policy) {
+		return OutputCollisionPolicy_Rename
+	}
+	return policy
+}
+
+// collisionPolicyFor 输出路径就是输入文件时无论策略如何都自动重命名，避免覆盖或跳过源文件
+func collisionPolicyFor(outputFilePath, inputFilePath string) OutputCollisionPolicy {
+	if inputFilePath != "" && outputPathKey(outputFilePath) == outputPathKey(inputFilePath) {
+		return OutputCollisionPolicy_Rename
+	}
+	return GetOutputCollisionPolicy()
+}
+
+// reserveOutputPath 按策略确定最终输出路径并占用该路径，避免同一批次中同名文件互相覆盖
+//
+// 返回值:
+//
+//	string: 最终输出路径
+//	bool: 是否跳过该任务
+//	error: 策略为报错且文件已存在时返回 errOutputExists
+func reserveOutputPath(outputFilePath string, policy OutputCollisionPolicy) (string, bool, error) {
+	return reserveOutputPathFunc(outputFilePath, policy, FileExists)
+}
+
+// reserveOutputPathFunc 与 reserveOutputPath 相同，使用 exists 判断磁盘上是否已有该路径的输出
+//
+// 分段输出时占用不带分段后缀的路径，按是否已有任何一段的文件判断
+func reserveOutputPathFunc(outputFilePath string, policy OutputCollisionPolicy, exists func(string) bool) (string, bool, error) {
+	reservedOutputsMu.Lock()
+	defer reservedOutputsMu.Unlock()
+
+	taken := func(p string) bool {
+		return reservedOutputs[outputPathKey(p)] || exists(p)
+	}
+
+	finalPath := outputFilePath
+	if taken(outputFilePath) {
+		switch policy {
+		case OutputCollisionPolicy_Skip:
+			return outputFilePath, true, nil
+		case OutputCollisionPolicy_Fail:
+			return outputFilePath, false, errOutputExists
+		case OutputCollisionPolicy_Overwrite:
+			// 同一批次中正在写入的文件不能覆盖，只覆盖磁盘上已有的文件
+			if reservedOutputs[outputPathKey(outputFilePath)] {
+				return outputFilePath, false, errOutputExists
+			}
+		default:
+			ext := filepath.Ext(outputFilePath)
+			base := strings.TrimSuffix(outputFilePath, ext)
+			for i := 1; ; i++ {
+				finalPath = fmt.Sprintf("%s_%d%s", base, i, ext)
+				if !taken(finalPath) {
+					break
+				}
+			}
+		}
+	}
+	reservedOutputs[outputPathKey(finalPath)] = true
+	return finalPath, false, nil
+}
+
+// releaseOutputPath 释放占用的输出路径
+func releaseOutputPath(outputFilePath string) {
+	reservedOutputsMu.Lock()
+	delete(reservedOutputs, outputPathKey(outputFilePath))
+	reservedOutputsMu.Unlock()
+}
+
+func outputPathKey(p string) string {
+	p = filepath.Clean(p)
+	if filepath.Separator == '\\' {
+		// Windows文件名不区分大小写
+		p = strings.ToLower(p)
+	}
+	return p
+}
+
+// tempOutputPath 获取转码时使用的临时文件路径，与最终文件在同一目录，保留扩展名以便FFmpeg识别封装格式
+func tempOutputPath(outputFilePath, id string) string {
+	dir := filepath.Dir(outputFilePath)
+	ext := filepath.Ext(outputFilePath)
+	name := strings.TrimSuffix(filepath.Base(outputFilePath), ext)
+	return filepath.Join(dir, fmt.Sprintf(".%s.%s.part%s", name, id, ext))
+}
+
+// commitOutputFile 转码成功后将临时文件重命名为最终文件
+func commitOutputFile(tempFilePath, outputFilePath string) error {
+	return os.Rename(tempFilePath, outputFilePath)
+}
@@ -0,0 +1,95 @@
+package process
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestReserveOutputPathFunc(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "video.mp4")
+	existing := func(paths ...string) func(string) bool {
+		return func(p string) bool {
+			for _, path := range paths {
+				if outputPathKey(path) == outputPathKey(p) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	tests := []struct {
+		name     string
+		policy   OutputCollisionPolicy
+		exists   func(string) bool
+		reserved []string // 同一批次中已经占用的路径
+		wantPath string
+		wantSkip bool
+		wantErr  bool
+	}{
+		{name: "不存在时直接使用", policy: OutputCollisionPolicy_Fail, exists: existing(), wantPath: out},
+		{name: "跳过", policy: OutputCollisionPolicy_Skip, exists: existing(out), wantPath: out, wantSkip: true},
+		{name: "报错", policy: OutputCollisionPolicy_Fail, exists: existing(out), wantPath: out, wantErr: true},
+		{name: "覆盖磁盘上的文件", policy: OutputCollisionPolicy_Overwrite, exists: existing(out), wantPath: out},
+		{name: "不覆盖同一批次正在写入的文件", policy: OutputCollisionPolicy_Overwrite, exists: existing(), reserved: []string{out}, wantPath: out, wantErr: true},
+		{name: "重命名", policy: OutputCollisionPolicy_Rename, exists: existing(out), wantPath: filepath.Join(dir, "video_1.mp4")},
+		{
+			name:     "重命名跳过已有和已占用的序号",
+			policy:   OutputCollisionPolicy_Rename,
+			exists:   existing(out, filepath.Join(dir, "video_1.mp4")),
+			reserved: []string{filepath.Join(dir, "video_2.mp4")},
+			wantPath: filepath.Join(dir, "video_3.mp4"),
+		},
+		{name: "同一批次的同名文件自动重命名", policy: OutputCollisionPolicy_Rename, exists: existing(), reserved: []string{out}, wantPath: filepath.Join(dir, "video_1.mp4")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, path := range tt.reserved {
+				reservedOutputs[outputPathKey(path)] = true
+			}
+			defer func() {
+				for _, path := range tt.reserved {
+					releaseOutputPath(path)
+				}
+			}()
+
+			path, skip, err := reserveOutputPathFunc(out, tt.policy, tt.exists)
+			if path != tt.wantPath || skip != tt.wantSkip || (err != nil) != tt.wantErr {
+				t.Fatalf("reserveOutputPathFunc = %q, %v, %v, want %q, %v, err %v", path, skip, err, tt.wantPath, tt.wantSkip, tt.wantErr)
+			}
+			if skip || err != nil {
+				if !containsString(tt.reserved, path) && reservedOutputs[outputPathKey(path)] {
+					t.Errorf("跳过或失败时不应占用路径: %s", path)
+				}
+				return
+			}
+			defer releaseOutputPath(path)
+			if !reservedOutputs[outputPathKey(path)] {
+				t.Errorf("没有占用输出路径: %s", path)
+			}
+		})
+	}
+}
+
+func TestCollisionPolicyFor(t *testing.T) {
+	Config = &ConfigData{OutputCollisionPolicy: string(OutputCollisionPolicy_Overwrite)}
+	defer func() { Config = nil }()
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "video.mp4")
+	tests := []struct {
+		output, input string
+		want          OutputCollisionPolicy
+	}{
+		{filepath.Join(dir, "out", "video.mp4"), input, OutputCollisionPolicy_Overwrite},
+		{input, input, OutputCollisionPolicy_Rename},
+		{filepath.Join(dir, ".", "video.mp4"), input, OutputCollisionPolicy_Rename},
+		{input, "", OutputCollisionPolicy_Overwrite},
+	}
+	for _, tt := range tests {
+		if got := collisionPolicyFor(tt.output, tt.input); got != tt.want {
+			t.Errorf("collisionPolicyFor(%q, %q) = %s, want %s", tt.output, tt.input, got, tt.want)
+		}
+	}
+}
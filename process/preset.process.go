@@ -0,0 +1,266 @@
+package process
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// TranscodePreset 命名的转码参数预设，保存在配置文件中，便于在多台电脑上使用相同的参数
+type TranscodePreset struct {
+	Name        string          `yaml:"name" json:"name"`
+	Description string          `yaml:"description" json:"description"`
+	Params      TranscodeParams `yaml:"params" json:"params"`
+	BuiltIn     bool            `yaml:"-" json:"builtIn"` // 内置预设，只读
+}
+
+// builtInPresets 内置的只读预设
+var builtInPresets = []TranscodePreset{
+	{
+		Name:        "web-720p",
+		Description: "网页播放: H.264 720p 30帧 CRF 23，峰值码率不超过2.5Mbps，AAC音频",
+		Params: TranscodeParams{
+			VideoCodec:         "h264",
+			AudioCodec:         "aac",
+			VideoHeight:        "720",
+			Fps:                "30",
+			VideoBitrate:       "copy",
+			WatermarkPlacement: WatermarkPlacement_TopRight,
+			Rotate:             VideoRotate_copy,
+			RateControl:        RateControl_CRF,
+			Quality:            intPtr(23),
+			MaxRate:            "2.5M",
+			EncoderPreset:      "medium",
+			Profile:            "high",
+			PixelFormat:        "yuv420p",
+			GOP:                60,
+		},
+	},
+	{
+		Name:        "web-av1",
+		Description: "网页播放: AV1 CRF 32，保留原始尺寸，AAC音频，MP4格式，同等画质下体积比H.264更小",
+		Params: TranscodeParams{
+			VideoCodec:         "av1",
+			AudioCodec:         "aac",
+			VideoHeight:        "copy",
+			Fps:                "copy",
+			VideoBitrate:       "copy",
+			WatermarkPlacement: WatermarkPlacement_TopRight,
+			Rotate:             VideoRotate_copy,
+			RateControl:        RateControl_CRF,
+			Quality:            intPtr(32),
+			EncoderPreset:      "medium",
+			PixelFormat:        "yuv420p",
+			Container:          OutputContainer_MP4,
+		},
+	},
+	{
+		Name:        "archive-h265",
+		Description: "归档: H.265 CRF 24 慢速编码，保留原始尺寸和帧率，音频直接复制",
+		Params: TranscodeParams{
+			VideoCodec:         "h265",
+			AudioCodec:         "copy",
+			VideoHeight:        "copy",
+			Fps:                "copy",
+			VideoBitrate:       "copy",
+			WatermarkPlacement: WatermarkPlacement_TopRight,
+			Rotate:             VideoRotate_copy,
+			RateControl:        RateControl_CRF,
+			Quality:            intPtr(24),
+			EncoderPreset:      "slow",
+		},
+	},
+	{
+		Name:        "phone-friendly",
+		Description: "手机: H.264 Main 480p 30帧 CRF 26，峰值码率不超过1Mbps，AAC音频，体积小兼容性好",
+		Params: TranscodeParams{
+			VideoCodec:         "h264",
+			AudioCodec:         "aac",
+			VideoHeight:        "480",
+			Fps:                "30",
+			VideoBitrate:       "copy",
+			WatermarkPlacement: WatermarkPlacement_TopRight,
+			Rotate:             VideoRotate_copy,
+			RateControl:        RateControl_CRF,
+			Quality:            intPtr(26),
+			MaxRate:            "1M",
+			EncoderPreset:      "medium",
+			Profile:            "main",
+			PixelFormat:        "yuv420p",
+			GOP:                60,
+		},
+	},
+}
+
+// intPtr 用于填写预设中可以不指定的数值参数
+func intPtr(v int) *int {
+	return &v
+}
+
+// ListTranscodePresets 获取所有预设，内置预设在前
+func ListTranscodePresets() []TranscodePreset {
+	presets := make([]TranscodePreset, 0, len(builtInPresets)+len(Config.Presets))
+	for _, preset := range builtInPresets {
+		preset.BuiltIn = true
+		presets = append(presets, withPresetName(preset))
+	}
+	for _, preset := range Config.Presets {
+		presets = append(presets, withPresetName(preset))
+	}
+	return presets
+}
+
+// GetTranscodePreset 按名称获取预设，名称不区分大小写
+func GetTranscodePreset(name string) (TranscodePreset, bool) {
+	for _, preset := range ListTranscodePresets() {
+		if strings.EqualFold(preset.Name, strings.TrimSpace(name)) {
+			return preset, true
+		}
+	}
+	return TranscodePreset{}, false
+}
+
+// GetDefaultPresetName 获取默认预设名称，未设置或预设已不存在时返回空
+func GetDefaultPresetName() string {
+	if Config.DefaultPreset == "" {
+		return ""
+	}
+	preset, ok := GetTranscodePreset(Config.DefaultPreset)
+	if !ok {
+		return ""
+	}
+	return preset.Name
+}
+
+// ValidateTranscodePreset 校验预设名称和转码参数，名称会用于输出文件名模板中的 {preset}
+func ValidateTranscodePreset(preset TranscodePreset) error {
+	name := strings.TrimSpace(preset.Name)
+	if name == "" {
+		return fmt.Errorf("预设名称不能为空")
+	}
+	if len(name) > 64 {
+		return fmt.Errorf("预设名称过长: %s", name)
+	}
+	if strings.Contains(name, "/") || illegalFileNameChars.MatchString(name) {
+		return fmt.Errorf(`预设名称中不能包含以下字符: / < > : " | ? * \`)
+	}
+	if err := validateTranscodeParams(preset.Params); err != nil {
+		return fmt.Errorf("预设 %s 的参数无效: %v", name, err)
+	}
+	return nil
+}
+
+// CreateTranscodePreset 新建预设并保存到配置文件
+func CreateTranscodePreset(preset TranscodePreset) error {
+	preset.Name = strings.TrimSpace(preset.Name)
+	if err := ValidateTranscodePreset(preset); err != nil {
+		return err
+	}
+	if _, ok := GetTranscodePreset(preset.Name); ok {
+		return fmt.Errorf("预设已存在: %s", preset.Name)
+	}
+	Config.Presets = append(Config.Presets, normalizePreset(preset))
+	return SaveConfig()
+}
+
+// UpdateTranscodePreset 修改名称为 name 的预设，可以同时重命名，内置预设不能修改
+func UpdateTranscodePreset(name string, preset TranscodePreset) error {
+	index, err := findUserPreset(name)
+	if err != nil {
+		return err
+	}
+	preset.Name = strings.TrimSpace(preset.Name)
+	if err := ValidateTranscodePreset(preset); err != nil {
+		return err
+	}
+	if existing, ok := GetTranscodePreset(preset.Name); ok && !strings.EqualFold(existing.Name, Config.Presets[index].Name) {
+		return fmt.Errorf("预设已存在: %s", preset.Name)
+	}
+	if strings.EqualFold(Config.DefaultPreset, Config.Presets[index].Name) {
+		Config.DefaultPreset = preset.Name
+	}
+	Config.Presets[index] = normalizePreset(preset)
+	return SaveConfig()
+}
+
+// DeleteTranscodePreset 删除预设，内置预设不能删除，删除默认预设时同时清除默认设置
+func DeleteTranscodePreset(name string) error {
+	index, err := findUserPreset(name)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(Config.DefaultPreset, Config.Presets[index].Name) {
+		Config.DefaultPreset = ""
+	}
+	Config.Presets = append(Config.Presets[:index], Config.Presets[index+1:]...)
+	return SaveConfig()
+}
+
+// SetDefaultTranscodePreset 设置默认预设，name 为空时清除默认预设
+func SetDefaultTranscodePreset(name string) error {
+	if strings.TrimSpace(name) == "" {
+		Config.DefaultPreset = ""
+		return SaveConfig()
+	}
+	preset, ok := GetTranscodePreset(name)
+	if !ok {
+		return fmt.Errorf("预设不存在: %s", name)
+	}
+	Config.DefaultPreset = preset.Name
+	return SaveConfig()
+}
+
+// findUserPreset 查找用户预设在配置中的位置
+func findUserPreset(name string) (int, error) {
+	for _, preset := range builtInPresets {
+		if strings.EqualFold(preset.Name, strings.TrimSpace(name)) {
+			return -1, fmt.Errorf("内置预设不能修改或删除: %s", preset.Name)
+		}
+	}
+	for i, preset := range Config.Presets {
+		if strings.EqualFold(preset.Name, strings.TrimSpace(name)) {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("预设不存在: %s", name)
+}
+
+// normalizePreset 保存前清理预设中不需要保存的字段
+func normalizePreset(preset TranscodePreset) TranscodePreset {
+	preset.BuiltIn = false
+	preset.Params.Preset = ""
+	return preset
+}
+
+// withPresetName 将预设名称填入参数，转码时用于输出文件名模板
+func withPresetName(preset TranscodePreset) TranscodePreset {
+	preset.Params.Preset = preset.Name
+	return preset
+}
+
+// sanitizeConfigPresets 移除配置文件中名称或参数无效、重名的预设
+func sanitizeConfigPresets(c *ConfigData) {
+	seen := map[string]bool{}
+	for _, preset := range builtInPresets {
+		seen[strings.ToLower(preset.Name)] = true
+	}
+	presets := c.Presets[:0]
+	for _, preset := range c.Presets {
+		preset.Name = strings.TrimSpace(preset.Name)
+		// 以前的版本用 quality: 0 表示编码器默认值，界面上只有CRF模式可以填写质量值
+		if preset.Params.Quality != nil && *preset.Params.Quality == 0 && preset.Params.RateControl != RateControl_CRF {
+			preset.Params.Quality = nil
+		}
+		if err := ValidateTranscodePreset(preset); err != nil {
+			log.Printf("忽略无效的预设: %v", err)
+			continue
+		}
+		if seen[strings.ToLower(preset.Name)] {
+			log.Printf("忽略重名的预设: %s", preset.Name)
+			continue
+		}
+		seen[strings.ToLower(preset.Name)] = true
+		presets = append(presets, preset)
+	}
+	c.Presets = presets
+}
@@ -0,0 +1,43 @@
+package process
+
+import "testing"
+
+func TestBuiltInPresetsValid(t *testing.T) {
+	for _, preset := range builtInPresets {
+		if err := ValidateTranscodePreset(preset); err != nil {
+			t.Errorf("内置预设无效: %v", err)
+		}
+	}
+}
+
+func TestSanitizeConfigPresets(t *testing.T) {
+	valid := builtInPresets[0].Params
+	invalidQuality := valid
+	invalidQuality.Quality = intPtr(70)
+	invalidTrim := valid
+	invalidTrim.Trim = TrimRange{Start: "30", End: "10"}
+	legacy := valid
+	legacy.RateControl, legacy.Quality = RateControl_Default, intPtr(0)
+
+	c := &ConfigData{Presets: []TranscodePreset{
+		{Name: " 我的预设 ", Params: valid},
+		{Name: "质量无效", Params: invalidQuality},
+		{Name: "截取无效", Params: invalidTrim},
+		{Name: "", Params: valid},
+		{Name: builtInPresets[0].Name, Params: valid},
+		{Name: "我的预设", Params: valid},
+		{Name: "旧版本", Params: legacy},
+	}}
+	sanitizeConfigPresets(c)
+
+	var names []string
+	for _, preset := range c.Presets {
+		names = append(names, preset.Name)
+	}
+	if len(names) != 2 || names[0] != "我的预设" || names[1] != "旧版本" {
+		t.Fatalf("保留的预设 = %q, want [我的预设 旧版本]", names)
+	}
+	if c.Presets[1].Params.Quality != nil {
+		t.Errorf("旧版本的 quality: 0 应视为未指定: %d", *c.Presets[1].Params.Quality)
+	}
+}
@@ -0,0 +1,126 @@
+package process
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// progressEmitInterval 进度事件的最小发送间隔
+const progressEmitInterval = 500 * time.Millisecond
+
+// TranscodeProgress 转码进度，由FFmpeg的 -progress 输出解析得到
+type TranscodeProgress struct {
+	ID             string  `json:"id"`
+	Percentage     float64 `json:"percentage"`
+	OutTime        string  `json:"out_time"`         // 已处理时间 HH:MM:SS.ms
+	OutTimeSeconds float64 `json:"out_time_seconds"` // 已处理时间（秒）
+	Duration       float64 `json:"duration"`         // 总时长（秒），未知时为0
+	Frame          int64   `json:"frame"`
+	Fps            float64 `json:"fps"`        // 编码帧率
+	Speed          float64 `json:"speed"`      // 编码速度，相对实时播放的倍数
+	Bitrate        string  `json:"bitrate"`    // 当前输出码率，如 "1024.0kbits/s"
+	TotalSize      int64   `json:"total_size"` // 当前输出文件大小（字节）
+	EtaSeconds     float64 `json:"eta_seconds"`
+	Pass           int     `json:"pass"`   // 当前是第几遍编码（智能切割时为第几步），从1开始
+	Passes         int     `json:"passes"` // 总遍数（步数），百分比按所有遍数合并计算
+	Completed      bool    `json:"completed"`
+}
+
+// progressParser 解析FFmpeg -progress 输出的 key=value 行
+//
+// FFmpeg每次输出一组key=value，以 progress=continue 或 progress=end 结束
+type progressParser struct {
+	current      TranscodeProgress
+	passDuration float64 // 当前这一步处理的时长，智能切割的每一步时长不同
+	startTime    time.Time
+	lastEmit     time.Time
+}
+
+func newProgressParser(id string, duration float64) *progressParser {
+	return &progressParser{
+		current:      TranscodeProgress{ID: id, Duration: duration, Pass: 1, Passes: 1},
+		passDuration: duration,
+		startTime:    time.Now(),
+	}
+}
+
+// startPass 开始新的一步，重新计算已处理时间和速度，duration 为这一步处理的时长
+func (p *progressParser) startPass(pass, passes int, duration float64) {
+	p.current.Pass, p.current.Passes = pass, passes
+	p.current.OutTimeSeconds, p.current.Speed = 0, 0
+	p.current.Completed = false
+	p.passDuration = duration
+	p.startTime = time.Now()
+}
+
+// parseLine 解析一行输出，当一组进度结束时返回true
+func (p *progressParser) parseLine(line string) bool {
+	key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+	if !ok {
+		return false
+	}
+	value = strings.TrimSpace(value)
+	switch key {
+	case "frame":
+		p.current.Frame, _ = strconv.ParseInt(value, 10, 64)
+	case "fps":
+		p.current.Fps, _ = strconv.ParseFloat(value, 64)
+	case "bitrate":
+		p.current.Bitrate = value
+	case "total_size":
+		p.current.TotalSize, _ = strconv.ParseInt(value, 10, 64)
+	case "out_time_us":
+		if us, err := strconv.ParseInt(value, 10, 64); err == nil && us >= 0 {
+			p.current.OutTimeSeconds = float64(us) / 1e6
+		}
+	case "out_time":
+		p.current.OutTime = value
+	case "speed":
+		p.current.Speed, _ = strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64)
+	case "progress":
+		// 只有最后一遍结束才算完成
+		p.current.Completed = value == "end" && p.current.Pass >= p.current.Passes
+		p.update()
+		return true
+	}
+	return false
+}
+
+// update 根据已处理时间计算百分比和剩余时间，多遍编码时每一遍占相同的比例
+func (p *progressParser) update() {
+	if p.current.Completed {
+		p.current.Percentage = 100
+		p.current.EtaSeconds = 0
+		return
+	}
+	if p.current.Duration <= 0 || p.passDuration <= 0 {
+		return
+	}
+	passes := max(p.current.Passes, 1)
+	processed := min(p.current.OutTimeSeconds, p.passDuration)
+	completedPasses := float64(p.current.Pass - 1)
+	p.current.Percentage = (completedPasses + processed/p.passDuration) / float64(passes) * 100
+
+	// 剩余时间包含后面还没有开始的遍数，按当前这一步的时长估算
+	remaining := p.passDuration - processed + float64(passes-p.current.Pass)*p.passDuration
+	speed := p.current.Speed
+	if speed <= 0 {
+		// FFmpeg尚未给出速度时，用实际耗时估算
+		if elapsed := time.Since(p.startTime).Seconds(); elapsed > 0 {
+			speed = p.current.OutTimeSeconds / elapsed
+		}
+	}
+	if speed > 0 {
+		p.current.EtaSeconds = remaining / speed
+	}
+}
+
+// shouldEmit 判断是否需要发送进度事件，结束时总是发送，其余按间隔限流
+func (p *progressParser) shouldEmit() bool {
+	if !p.current.Completed && time.Since(p.lastEmit) < progressEmitInterval {
+		return false
+	}
+	p.lastEmit = time.Now()
+	return true
+}
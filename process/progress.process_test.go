@@ -0,0 +1,157 @@
+package process
+
+import (
+	"math"
+	"testing"
+)
+
+// feedProgress 依次解析每一行，返回解析到的进度组数
+func feedProgress(p *progressParser, lines ...string) int {
+	groups := 0
+	for _, line := range lines {
+		if p.parseLine(line) {
+			groups++
+		}
+	}
+	return groups
+}
+
+func TestProgressParserParseLine(t *testing.T) {
+	p := newProgressParser("job", 100)
+	groups := feedProgress(p,
+		"frame=250",
+		"fps=50.5",
+		"bitrate=1024.0kbits/s",
+		"total_size=1048576",
+		"out_time_us=25000000",
+		"out_time=00:00:25.000000",
+		"speed=2.0x",
+		"ignored line",
+		"progress=continue",
+	)
+	if groups != 1 {
+		t.Fatalf("进度组数 = %d, want 1", groups)
+	}
+	got := p.current
+	if got.Frame != 250 || got.Fps != 50.5 || got.Bitrate != "1024.0kbits/s" || got.TotalSize != 1048576 {
+		t.Errorf("解析结果错误: %+v", got)
+	}
+	if got.OutTimeSeconds != 25 || got.OutTime != "00:00:25.000000" || got.Speed != 2 {
+		t.Errorf("时间或速度错误: %+v", got)
+	}
+	if got.Percentage != 25 || got.EtaSeconds != 37.5 || got.Completed {
+		t.Errorf("百分比 = %v, 剩余时间 = %v, 完成 = %v, want 25, 37.5, false", got.Percentage, got.EtaSeconds, got.Completed)
+	}
+}
+
+func TestProgressParserPercentage(t *testing.T) {
+	tests := []struct {
+		name           string
+		duration       float64
+		pass, passes   int
+		passDuration   float64
+		lines          []string
+		wantPercentage float64
+		wantCompleted  bool
+	}{
+		{
+			name:           "单遍进行中",
+			duration:       200,
+			pass:           1,
+			passes:         1,
+			passDuration:   200,
+			lines:          []string{"out_time_us=50000000", "progress=continue"},
+			wantPercentage: 25,
+		},
+		{
+			name:           "已处理时间超过时长时不超过100",
+			duration:       10,
+			pass:           1,
+			passes:         1,
+			passDuration:   10,
+			lines:          []string{"out_time_us=12000000", "progress=continue"},
+			wantPercentage: 100,
+		},
+		{
+			name:           "两遍编码的第二遍",
+			duration:       100,
+			pass:           2,
+			passes:         2,
+			passDuration:   100,
+			lines:          []string{"out_time_us=50000000", "progress=continue"},
+			wantPercentage: 75,
+		},
+		{
+			name:           "第一遍结束不算完成",
+			duration:       100,
+			pass:           1,
+			passes:         2,
+			passDuration:   100,
+			lines:          []string{"out_time_us=100000000", "progress=end"},
+			wantPercentage: 50,
+		},
+		{
+			name:           "最后一遍结束",
+			duration:       100,
+			pass:           2,
+			passes:         2,
+			passDuration:   100,
+			lines:          []string{"out_time_us=100000000", "progress=end"},
+			wantPercentage: 100,
+			wantCompleted:  true,
+		},
+		{
+			name:           "每一步时长不同",
+			duration:       100,
+			pass:           3,
+			passes:         4,
+			passDuration:   10,
+			lines:          []string{"out_time_us=5000000", "progress=continue"},
+			wantPercentage: 62.5,
+		},
+		{
+			name:           "时长未知时没有百分比",
+			duration:       0,
+			pass:           1,
+			passes:         1,
+			passDuration:   0,
+			lines:          []string{"out_time_us=5000000", "progress=continue"},
+			wantPercentage: 0,
+		},
+		{
+			name:           "负的已处理时间被忽略",
+			duration:       100,
+			pass:           1,
+			passes:         1,
+			passDuration:   100,
+			lines:          []string{"out_time_us=-9223372036854775807", "progress=continue"},
+			wantPercentage: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newProgressParser("job", tt.duration)
+			p.startPass(tt.pass, tt.passes, tt.passDuration)
+			feedProgress(p, tt.lines...)
+			if math.Abs(p.current.Percentage-tt.wantPercentage) > 1e-9 {
+				t.Errorf("百分比 = %v, want %v", p.current.Percentage, tt.wantPercentage)
+			}
+			if p.current.Completed != tt.wantCompleted {
+				t.Errorf("完成 = %v, want %v", p.current.Completed, tt.wantCompleted)
+			}
+		})
+	}
+}
+
+func TestProgressParserStartPassResets(t *testing.T) {
+	p := newProgressParser("job", 100)
+	p.startPass(1, 2, 100)
+	feedProgress(p, "out_time_us=100000000", "speed=4x", "progress=end")
+	p.startPass(2, 2, 100)
+	if p.current.OutTimeSeconds != 0 || p.current.Speed != 0 || p.current.Completed {
+		t.Errorf("新的一遍应重置已处理时间和速度: %+v", p.current)
+	}
+	if p.current.Pass != 2 || p.current.Passes != 2 {
+		t.Errorf("遍数 = %d/%d, want 2/2", p.current.Pass, p.current.Passes)
+	}
+}
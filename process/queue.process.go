@@ -0,0 +1,351 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type TranscodeJobStatus string
+
+const (
+	TranscodeJobStatus_Pending   TranscodeJobStatus = "pending"   // 等待中
+	TranscodeJobStatus_Running   TranscodeJobStatus = "running"   // 转码中
+	TranscodeJobStatus_Completed TranscodeJobStatus = "completed" // 已完成
+	TranscodeJobStatus_Failed    TranscodeJobStatus = "failed"    // 失败
+	TranscodeJobStatus_Paused    TranscodeJobStatus = "paused"    // 已暂停
+	TranscodeJobStatus_Cancelled TranscodeJobStatus = "cancelled" // 已取消
+	TranscodeJobStatus_Skipped   TranscodeJobStatus = "skipped"   // 已跳过
+)
+
+type TranscodeJobType string
+
+const (
+	TranscodeJobType_Transcode TranscodeJobType = ""         // 单个文件转码
+	TranscodeJobType_Merge     TranscodeJobType = "merge"    // 按顺序合并多个文件
+	TranscodeJobType_Subtitle  TranscodeJobType = "subtitle" // 转换字幕格式或导出内嵌字幕
+)
+
+// TranscodeJob 批量转码中的单个任务
+type TranscodeJob struct {
+	ID       string              `json:"id"`
+	Type     TranscodeJobType    `json:"type"` // 任务类型，为空时为单个文件转码
+	Path     string              `json:"path"` // 输入文件，合并任务为第一个文件
+	Params   TranscodeParams     `json:"params"`
+	Index    int                 `json:"index"`    // 任务在批次中的序号，从1开始，为0时提交时自动分配
+	BaseDir  string              `json:"base_dir"` // 导入文件夹时的根目录，输出时保留相对该目录的子目录
+	Trim     *TrimRange          `json:"trim"`     // 单个文件的截取范围，不为空时覆盖参数中的截取范围
+	Merge    *MergeOptions       `json:"merge"`    // 合并任务的选项
+	Subtitle *SubtitleJobOptions `json:"subtitle"` // 字幕任务的选项
+}
+
+// TranscodeJobEvent 单个任务状态变化时发送到前端的数据，任务结束时附带转码结果
+type TranscodeJobEvent struct {
+	ID      string             `json:"id"`
+	Path    string             `json:"path"`
+	Status  TranscodeJobStatus `json:"status"`
+	Message string             `json:"message"`
+	Result  *TranscodeResult   `json:"result"`
+}
+
+// TranscodeBatchStatus 整个批次的执行状态
+type TranscodeBatchStatus struct {
+	Total     int  `json:"total"`
+	Pending   int  `json:"pending"`
+	Running   int  `json:"running"`
+	Completed int  `json:"completed"`
+	Failed    int  `json:"failed"`
+	Cancelled int  `json:"cancelled"`
+	Skipped   int  `json:"skipped"`
+	Paused    bool `json:"paused"`
+	Finished  bool `json:"finished"`
+}
+
+// TranscodeQueue 转码任务队列，按配置的并行数同时运行多个FFmpeg进程
+type TranscodeQueue struct {
+	ctx     context.Context
+	mu      sync.Mutex
+	pending []TranscodeJob
+	running map[string]TranscodeJob
+	batch   TranscodeBatchStatus
+	history *JobHistory
+}
+
+// NewTranscodeQueue 创建转码队列，history 不为 nil 时记录每个任务的状态变化
+func NewTranscodeQueue(ctx context.Context, history *JobHistory) *TranscodeQueue {
+	return &TranscodeQueue{
+		ctx:     ctx,
+		running: map[string]TranscodeJob{},
+		batch:   TranscodeBatchStatus{Finished: true},
+		history: history,
+	}
+}
+
+// Submit 提交一批任务，如果当前批次仍在执行，则追加到当前批次
+func (q *TranscodeQueue) Submit(jobs []TranscodeJob) {
+	if len(jobs) == 0 {
+		return
+	}
+	q.mu.Lock()
+	if q.batch.Finished {
+		q.batch = TranscodeBatchStatus{}
+	}
+	for i := range jobs {
+		if jobs[i].Index == 0 {
+			jobs[i].Index = q.batch.Total + i + 1
+		}
+	}
+	q.pending = append(q.pending, jobs...)
+	q.batch.Total += len(jobs)
+	q.batch.Pending += len(jobs)
+	batch := q.batch
+	q.mu.Unlock()
+
+	for _, job := range jobs {
+		q.emitJob(job, TranscodeJobStatus_Pending, nil)
+	}
+	q.emitBatch(batch)
+	q.schedule()
+}
+
+// Status 获取当前批次状态
+func (q *TranscodeQueue) Status() TranscodeBatchStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.batch
+}
+
+// Cancel 取消单个任务，等待中的任务直接移出队列，运行中的任务会结束FFmpeg进程并删除未完成的输出文件
+func (q *TranscodeQueue) Cancel(id string) error {
+	q.mu.Lock()
+	for i, job := range q.pending {
+		if job.ID == id {
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			q.batch.Pending--
+			q.batch.Cancelled++
+			q.batch.Finished = q.batch.Running == 0 && len(q.pending) == 0
+			batch := q.batch
+			q.mu.Unlock()
+			q.emitJob(job, TranscodeJobStatus_Cancelled, nil)
+			q.emitBatch(batch)
+			return nil
+		}
+	}
+	q.mu.Unlock()
+	return CancelTranscodeTask(id)
+}
+
+// CancelAll 取消整个批次
+func (q *TranscodeQueue) CancelAll() {
+	q.mu.Lock()
+	pending := q.pending
+	q.pending = nil
+	q.batch.Pending = 0
+	q.batch.Cancelled += len(pending)
+	q.batch.Finished = q.batch.Running == 0
+	batch := q.batch
+	q.mu.Unlock()
+
+	for _, job := range pending {
+		q.emitJob(job, TranscodeJobStatus_Cancelled, nil)
+	}
+	q.emitBatch(batch)
+	for _, id := range runningTranscodeTaskIDs() {
+		CancelTranscodeTask(id)
+	}
+}
+
+// Pause 暂停单个正在运行的任务
+func (q *TranscodeQueue) Pause(id string) error {
+	if err := PauseTranscodeTask(id); err != nil {
+		return err
+	}
+	if job, ok := q.runningJob(id); ok {
+		q.emitJob(job, TranscodeJobStatus_Paused, nil)
+	}
+	return nil
+}
+
+// Resume 恢复单个被暂停的任务
+func (q *TranscodeQueue) Resume(id string) error {
+	if err := ResumeTranscodeTask(id); err != nil {
+		return err
+	}
+	if job, ok := q.runningJob(id); ok {
+		q.emitJob(job, TranscodeJobStatus_Running, nil)
+	}
+	return nil
+}
+
+// PauseAll 暂停所有正在运行的任务，并停止启动新的任务
+func (q *TranscodeQueue) PauseAll() {
+	q.mu.Lock()
+	q.batch.Paused = true
+	batch := q.batch
+	q.mu.Unlock()
+
+	for _, id := range runningTranscodeTaskIDs() {
+		q.Pause(id)
+	}
+	q.emitBatch(batch)
+}
+
+// ResumeAll 恢复所有被暂停的任务，并继续启动等待中的任务
+func (q *TranscodeQueue) ResumeAll() {
+	q.mu.Lock()
+	q.batch.Paused = false
+	batch := q.batch
+	q.mu.Unlock()
+
+	for _, id := range runningTranscodeTaskIDs() {
+		q.Resume(id)
+	}
+	q.emitBatch(batch)
+	q.schedule()
+}
+
+// SetMaxParallelJobs 修改同时运行的任务数，调大后立即启动等待中的任务，返回实际生效的值
+//
+// 在队列的锁内修改配置，避免与 schedule 同时读写
+func (q *TranscodeQueue) SetMaxParallelJobs(count int) int {
+	q.mu.Lock()
+	Config.MaxParallelJobs = count
+	limit := GetMaxParallelJobs()
+	q.mu.Unlock()
+
+	q.schedule()
+	return limit
+}
+
+// MaxParallelJobs 获取同时运行的任务数
+func (q *TranscodeQueue) MaxParallelJobs() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return GetMaxParallelJobs()
+}
+
+// isQueued 判断任务是否在队列中等待或运行
+func (q *TranscodeQueue) isQueued(id string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.running[id]; ok {
+		return true
+	}
+	for _, job := range q.pending {
+		if job.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *TranscodeQueue) runningJob(id string) (TranscodeJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.running[id]
+	return job, ok
+}
+
+// schedule 在并行数允许的范围内启动等待中的任务
+func (q *TranscodeQueue) schedule() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.batch.Paused {
+		return
+	}
+	limit := GetMaxParallelJobs()
+	for q.batch.Running < limit && len(q.pending) > 0 {
+		job := q.pending[0]
+		q.pending = q.pending[1:]
+		q.batch.Pending--
+		q.batch.Running++
+		q.running[job.ID] = job
+		// 启动前登记，使刚启动的任务也能被取消和暂停
+		reserveTranscodeTask(job.ID)
+		go q.run(job)
+	}
+}
+
+func (q *TranscodeQueue) run(job TranscodeJob) {
+	defer releaseTranscodeTask(job.ID)
+	q.emitJob(job, TranscodeJobStatus_Running, nil)
+	q.emitBatch(q.Status())
+
+	var result TranscodeResult
+	if isTranscodeTaskCancelled(job.ID) {
+		result = TranscodeResult{ID: job.ID, Status: TranscodeResultStatus_Cancelled, InputPath: job.Path, ExitCode: -1, ErrorKind: TranscodeErrorKind_Cancelled, Error: "转码已取消"}
+	} else {
+		result = processTranscodeJob(q.ctx, job)
+	}
+
+	var status TranscodeJobStatus
+	switch result.Status {
+	case TranscodeResultStatus_Success:
+		status = TranscodeJobStatus_Completed
+	case TranscodeResultStatus_Cancelled:
+		status = TranscodeJobStatus_Cancelled
+	case TranscodeResultStatus_Skipped:
+		status = TranscodeJobStatus_Skipped
+	default:
+		status = TranscodeJobStatus_Failed
+	}
+
+	q.mu.Lock()
+	delete(q.running, job.ID)
+	q.batch.Running--
+	switch status {
+	case TranscodeJobStatus_Completed:
+		q.batch.Completed++
+	case TranscodeJobStatus_Cancelled:
+		q.batch.Cancelled++
+	case TranscodeJobStatus_Skipped:
+		q.batch.Skipped++
+	default:
+		q.batch.Failed++
+	}
+	q.batch.Finished = q.batch.Running == 0 && len(q.pending) == 0
+	batch := q.batch
+	q.mu.Unlock()
+
+	q.emitJob(job, status, &result)
+	q.emitBatch(batch)
+	q.schedule()
+}
+
+// processTranscodeJob 按任务类型执行任务
+func processTranscodeJob(ctx context.Context, job TranscodeJob) TranscodeResult {
+	switch job.Type {
+	case TranscodeJobType_Transcode:
+		return VideoTranscodeProcessor(ctx, job)
+	case TranscodeJobType_Merge:
+		return VideoMergeProcessor(ctx, job)
+	case TranscodeJobType_Subtitle:
+		return SubtitleJobProcessor(ctx, job)
+	}
+	return TranscodeResult{
+		ID:        job.ID,
+		Status:    TranscodeResultStatus_Failed,
+		InputPath: job.Path,
+		ExitCode:  -1,
+		ErrorKind: TranscodeErrorKind_InvalidParams,
+		Error:     fmt.Sprintf("未知的任务类型: %s", job.Type),
+	}
+}
+
+func (q *TranscodeQueue) emitJob(job TranscodeJob, status TranscodeJobStatus, result *TranscodeResult) {
+	q.history.update(job, status, result)
+	event := TranscodeJobEvent{
+		ID:     job.ID,
+		Path:   job.Path,
+		Status: status,
+		Result: result,
+	}
+	if result != nil {
+		event.Message = result.Error
+	}
+	emitEvent(q.ctx, "videoTranscodeJobStatus", event)
+}
+
+func (q *TranscodeQueue) emitBatch(batch TranscodeBatchStatus) {
+	emitEvent(q.ctx, "videoTranscodeBatchStatus", batch)
+}
@@ -0,0 +1,422 @@
+package process
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type RateControlMode string
+
+const (
+	RateControl_Default RateControlMode = ""    // 指定码率时使用 -b:v，否则使用编码器的默认设置
+	RateControl_CRF     RateControlMode = "crf" // 恒定质量 CRF/CQ，指定最大码率时限制峰值码率
+	RateControl_VBR     RateControlMode = "vbr" // 受限的可变码率，平均码率 + 最大码率/缓冲区
+	RateControl_CBR     RateControlMode = "cbr" // 恒定码率
+	RateControl_ABR     RateControlMode = "abr" // 平均码率
+)
+
+// encoderPresets 编码速度预设，与libx264的名称相同，硬件编码器会映射到各自的预设
+var encoderPresets = []string{"ultrafast", "superfast", "veryfast", "faster", "fast", "medium", "slow", "slower", "veryslow"}
+
+// hasBitrate 判断码率参数是否有值，copy 表示不指定
+func hasBitrate(bitrate string) bool {
+	return bitrate != "" && bitrate != "copy"
+}
+
+// parseBitrate 解析码率，支持FFmpeg的 k、M、G 后缀，如 2M、2500k、2097152
+func parseBitrate(bitrate string) (int64, error) {
+	value := strings.TrimSpace(bitrate)
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(value, "k"), strings.HasSuffix(value, "K"):
+		multiplier = 1e3
+	case strings.HasSuffix(value, "M"), strings.HasSuffix(value, "m"):
+		multiplier = 1e6
+	case strings.HasSuffix(value, "G"), strings.HasSuffix(value, "g"):
+		multiplier = 1e9
+	}
+	if multiplier != 1 {
+		value = value[:len(value)-1]
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("无效的码率: %s", bitrate)
+	}
+	return int64(number * multiplier), nil
+}
+
+// defaultBufSize 未指定缓冲区大小时使用码率的两倍
+func defaultBufSize(bitrate string) string {
+	value, err := parseBitrate(bitrate)
+	if err != nil {
+		return bitrate
+	}
+	return strconv.FormatInt(value*2, 10)
+}
+
+// validateVideoEncodeParams 校验码率控制和编码器参数
+func validateVideoEncodeParams(params TranscodeParams) error {
+	switch params.RateControl {
+	case RateControl_Default, RateControl_CRF:
+	case RateControl_VBR:
+		if !hasBitrate(params.VideoBitrate) || !hasBitrate(params.MaxRate) {
+			return fmt.Errorf("VBR 需要同时指定视频码率和最大码率")
+		}
+	case RateControl_CBR, RateControl_ABR:
+		if !hasBitrate(params.VideoBitrate) {
+			return fmt.Errorf("%s 需要指定视频码率", strings.ToUpper(string(params.RateControl)))
+		}
+	default:
+		return fmt.Errorf("无效的码率控制方式: %s，可用: crf、vbr、cbr、abr", params.RateControl)
+	}
+	for _, bitrate := range []string{params.VideoBitrate, params.MaxRate, params.BufSize} {
+		if hasBitrate(bitrate) {
+			if _, err := parseBitrate(bitrate); err != nil {
+				return err
+			}
+		}
+	}
+	if params.Quality != nil && (*params.Quality < 0 || *params.Quality > 63) {
+		return fmt.Errorf("质量值应在 0-63 之间: %d", *params.Quality)
+	}
+	if params.EncoderPreset != "" && !containsString(encoderPresets, params.EncoderPreset) {
+		return fmt.Errorf("无效的编码预设: %s，可用: %s", params.EncoderPreset, strings.Join(encoderPresets, "、"))
+	}
+	if params.GOP < 0 {
+		return fmt.Errorf("GOP长度不能为负数: %d", params.GOP)
+	}
+	return nil
+}
+
+// videoEncoderFamily 获取编码器类型，硬件编码器返回硬件编码器名称，软件编码器返回编码器名称
+func videoEncoderFamily(encoders encoderSelection) string {
+	if encoders.Backend != nil {
+		return string(encoders.Backend.Name)
+	}
+	return encoders.Video
+}
+
+// qualityRange 各编码器质量值的有效范围
+func qualityRange(family string) (int, int) {
+	switch family {
+	case "libsvtav1":
+		return 1, 63
+	case "libaom-av1", "libvpx-vp9", "librav1e":
+		return 0, 63
+	case string(HardwareBackend_NVENC), string(HardwareBackend_QSV):
+		return 1, 51
+	}
+	return 0, 51
+}
+
+// validateQuality 按实际使用的编码器校验质量值，如libx264的CRF最大为51，直接复制视频流时不校验
+func validateQuality(params TranscodeParams, encoders encoderSelection) error {
+	if params.Quality == nil || encoders.Video == "copy" {
+		return nil
+	}
+	low, high := qualityRange(videoEncoderFamily(encoders))
+	if *params.Quality < low || *params.Quality > high {
+		return fmt.Errorf("%s 的质量值应在 %d-%d 之间: %d", encoders.Video, low, high, *params.Quality)
+	}
+	return nil
+}
+
+// defaultQuality 不指定质量值时的默认值，各编码器的值画质相近，如libx265的CRF 28与libx264的CRF 23
+func defaultQuality(family string) int {
+	switch family {
+	case "libx265":
+		return 28
+	case "libsvtav1":
+		return 35
+	case "libaom-av1":
+		return 30
+	case "libvpx-vp9":
+		return 31
+	case "librav1e":
+		return 25
+	}
+	return 23
+}
+
+// constantQualityArgs 生成软件编码器的恒定质量参数，maxRate 有值时限制峰值码率
+//
+// 返回值:
+//
+//	[]string: FFmpeg参数
+//	[]string: 不支持而被忽略的参数
+func constantQualityArgs(family string, quality int, maxRate string, vbv []string) ([]string, []string) {
+	q := strconv.Itoa(quality)
+	switch family {
+	case "libx264", "libx265", "libsvtav1":
+		return append([]string{"-crf", q}, vbv...), nil
+	case "libaom-av1", "libvpx-vp9":
+		// -b:v 0 为恒定质量，指定码率时为受限质量模式，码率作为上限
+		limit := "0"
+		if hasBitrate(maxRate) {
+			limit = maxRate
+		}
+		return []string{"-crf", q, "-b:v", limit}, nil
+	case "librav1e":
+		// rav1e的量化参数范围为0-255，按CRF的4倍换算
+		var ignored []string
+		if hasBitrate(maxRate) {
+			ignored = append(ignored, "CRF模式下的最大码率")
+		}
+		return []string{"-qp", strconv.Itoa(quality * 4)}, ignored
+	}
+	return vbv, []string{"CRF"}
+}
+
+// videoEncodeArgs 生成码率控制、编码预设、tune、profile、level、像素格式、GOP和两遍编码参数
+//
+// 返回值:
+//
+//	[]string: FFmpeg参数
+//	[]string: 当前编码器不支持而被忽略的参数说明
+func videoEncodeArgs(params TranscodeParams, encoders encoderSelection, pass encodePass) ([]string, []string) {
+	family := videoEncoderFamily(encoders)
+	if encoders.Video == "copy" {
+		// 直接复制视频流时编码参数无效，保留以前的 -b:v 参数
+		if hasBitrate(params.VideoBitrate) {
+			return []string{"-b:v", params.VideoBitrate}, nil
+		}
+		return nil, nil
+	}
+
+	var args, ignored, x26xParams []string
+	ignore := func(option string) {
+		ignored = append(ignored, fmt.Sprintf("%s 不支持 %s，已忽略", encoders.Video, option))
+	}
+
+	// 码率控制
+	rcArgs, rcParams, rcIgnored := rateControlArgs(family, params)
+	args = append(args, rcArgs...)
+	x26xParams = append(x26xParams, rcParams...)
+	for _, option := range rcIgnored {
+		ignore(option)
+	}
+
+	// 编码预设，libaom-av1和libvpx-vp9默认的速度非常慢，不指定时按 medium 处理
+	preset := params.EncoderPreset
+	if preset == "" && (family == "libaom-av1" || family == "libvpx-vp9") {
+		preset = "medium"
+	}
+	if preset != "" {
+		if presetArgs := encoderPresetArgs(family, preset); presetArgs != nil {
+			args = append(args, presetArgs...)
+		} else {
+			ignore("编码预设")
+		}
+	}
+
+	// VP9按行多线程编码，默认只按tile并行，速度慢很多
+	if family == "libvpx-vp9" {
+		args = append(args, "-row-mt", "1")
+	}
+
+	// tune
+	if params.Tune != "" {
+		switch {
+		case family == "libx264" || family == "libx265":
+			args = append(args, "-tune", params.Tune)
+		case family == string(HardwareBackend_NVENC) && params.Tune == "zerolatency":
+			args = append(args, "-zerolatency", "1")
+		default:
+			ignore("tune " + params.Tune)
+		}
+	}
+
+	// profile
+	if params.Profile != "" {
+		args = append(args, "-profile:v", params.Profile)
+	}
+
+	// level，libx265需要通过 -x265-params 设置
+	if params.Level != "" {
+		switch family {
+		case "libx265":
+			x26xParams = append(x26xParams, "level-idc="+params.Level)
+		case string(HardwareBackend_VideoToolbox), "libopenh264", "libvpx-vp9", "libaom-av1", "librav1e":
+			ignore("level")
+		default:
+			args = append(args, "-level", params.Level)
+		}
+	}
+
+	// 像素格式，VA-API在上传滤镜中设置
+	if params.PixelFormat != "" && family != string(HardwareBackend_VAAPI) {
+		args = append(args, "-pix_fmt", params.PixelFormat)
+	}
+
+	// GOP长度
+	if params.GOP > 0 {
+		args = append(args, "-g", strconv.Itoa(params.GOP))
+	}
+
+	// 两遍编码
+	passArgs, passParams := twoPassArgs(family, pass)
+	args = append(args, passArgs...)
+	x26xParams = append(x26xParams, passParams...)
+
+	if len(x26xParams) > 0 {
+		args = append(args, "-"+strings.TrimPrefix(family, "lib")+"-params", strings.Join(x26xParams, ":"))
+	}
+	return args, ignored
+}
+
+// rateControlArgs 生成各编码器的码率控制参数
+//
+// 返回值:
+//
+//	[]string: FFmpeg参数
+//	[]string: libx264/libx265 需要通过 -x264-params/-x265-params 设置的参数
+//	[]string: 不支持而被忽略的参数
+func rateControlArgs(family string, params TranscodeParams) ([]string, []string, []string) {
+	bitrate, maxRate, bufSize := params.VideoBitrate, params.MaxRate, params.BufSize
+	quality := defaultQuality(family)
+	if params.Quality != nil {
+		quality = *params.Quality
+	}
+	q := strconv.Itoa(quality)
+
+	// 最大码率和缓冲区，未指定缓冲区时使用最大码率的两倍
+	vbv := func(rate string) []string {
+		if !hasBitrate(rate) {
+			return nil
+		}
+		size := bufSize
+		if !hasBitrate(size) {
+			size = defaultBufSize(rate)
+		}
+		return []string{"-maxrate", rate, "-bufsize", size}
+	}
+
+	switch params.RateControl {
+	case RateControl_Default:
+		// 以前的行为: 指定码率时使用 -b:v，硬件编码器不指定码率时使用恒定质量
+		if hasBitrate(bitrate) {
+			return []string{"-b:v", bitrate}, nil, nil
+		}
+		if backend, ok := getEncoderBackend(HardwareBackend(family)); ok {
+			return backend.qualityArgs(q), nil, nil
+		}
+		// libaom-av1和libvpx-vp9默认使用很低的固定码率，改用恒定质量
+		if family == "libaom-av1" || family == "libvpx-vp9" {
+			args, ignored := constantQualityArgs(family, quality, "", nil)
+			return args, nil, ignored
+		}
+		return nil, nil, nil
+
+	case RateControl_CRF:
+		switch family {
+		case "libx264", "libx265", "libsvtav1", "libaom-av1", "libvpx-vp9", "librav1e", "libopenh264":
+			args, ignored := constantQualityArgs(family, quality, maxRate, vbv(maxRate))
+			return args, nil, ignored
+		case string(HardwareBackend_QSV), string(HardwareBackend_AMF), string(HardwareBackend_VAAPI):
+			// 这些硬件编码器的恒定质量模式不能限制峰值码率
+			var ignored []string
+			if hasBitrate(maxRate) {
+				ignored = append(ignored, "CRF模式下的最大码率")
+			}
+			backend, _ := getEncoderBackend(HardwareBackend(family))
+			return backend.qualityArgs(q), nil, ignored
+		default:
+			backend, _ := getEncoderBackend(HardwareBackend(family))
+			return append(backend.qualityArgs(q), vbv(maxRate)...), nil, nil
+		}
+
+	case RateControl_VBR:
+		args := append([]string{"-b:v", bitrate}, vbv(maxRate)...)
+		switch family {
+		case string(HardwareBackend_NVENC):
+			return append([]string{"-rc", "vbr"}, args...), nil, nil
+		case string(HardwareBackend_AMF):
+			return append([]string{"-rc", "vbr_peak"}, args...), nil, nil
+		}
+		return args, nil, nil
+
+	case RateControl_CBR:
+		// 最大码率与平均码率相同
+		args := append([]string{"-b:v", bitrate}, vbv(bitrate)...)
+		switch family {
+		case "libx264":
+			return append(args, "-minrate", bitrate), []string{"nal-hrd=cbr"}, nil
+		case "libx265":
+			return args, []string{"strict-cbr=1"}, nil
+		case "libvpx-vp9", "libaom-av1":
+			return append(args, "-minrate", bitrate), nil, nil
+		case string(HardwareBackend_NVENC), string(HardwareBackend_AMF):
+			return append([]string{"-rc", "cbr"}, args...), nil, nil
+		}
+		return args, nil, nil
+
+	case RateControl_ABR:
+		args := []string{"-b:v", bitrate}
+		switch family {
+		case string(HardwareBackend_NVENC):
+			return append([]string{"-rc", "vbr"}, args...), nil, nil
+		case string(HardwareBackend_AMF):
+			return append([]string{"-rc", "vbr_latency"}, args...), nil, nil
+		}
+		return args, nil, nil
+	}
+	return nil, nil, nil
+}
+
+// encoderPresetArgs 将编码速度预设映射到各编码器的参数，不支持时返回nil
+func encoderPresetArgs(family, preset string) []string {
+	index := 0
+	for i, p := range encoderPresets {
+		if p == preset {
+			index = i
+		}
+	}
+	switch family {
+	case "libx264", "libx265":
+		return []string{"-preset", preset}
+	case "libsvtav1":
+		// 0 最慢，13 最快
+		svtPresets := []int{12, 11, 10, 9, 8, 6, 5, 4, 2}
+		return []string{"-preset", strconv.Itoa(svtPresets[index])}
+	case "libaom-av1":
+		aomSpeeds := []int{8, 8, 7, 6, 5, 4, 3, 2, 1}
+		return []string{"-cpu-used", strconv.Itoa(aomSpeeds[index])}
+	case "libvpx-vp9":
+		// good 模式下 cpu-used 范围为 0-5
+		vpxSpeeds := []int{5, 5, 4, 4, 3, 2, 1, 1, 0}
+		return []string{"-deadline", "good", "-cpu-used", strconv.Itoa(vpxSpeeds[index])}
+	case "librav1e":
+		rav1eSpeeds := []int{10, 10, 9, 8, 7, 6, 4, 2, 1}
+		return []string{"-speed", strconv.Itoa(rav1eSpeeds[index])}
+	case string(HardwareBackend_NVENC):
+		// p1 最快，p7 画质最好
+		nvencPresets := []string{"p1", "p1", "p2", "p3", "p3", "p4", "p5", "p6", "p7"}
+		return []string{"-preset", nvencPresets[index]}
+	case string(HardwareBackend_QSV):
+		// QSV没有 ultrafast、superfast
+		if index < 2 {
+			preset = "veryfast"
+		}
+		return []string{"-preset", preset}
+	case string(HardwareBackend_AMF):
+		switch {
+		case index <= 3:
+			return []string{"-quality", "speed"}
+		case index <= 5:
+			return []string{"-quality", "balanced"}
+		default:
+			return []string{"-quality", "quality"}
+		}
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package process
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateVideoEncodeParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  TranscodeParams
+		wantErr bool
+	}{
+		{name: "默认", params: TranscodeParams{}},
+		{name: "CRF", params: TranscodeParams{RateControl: RateControl_CRF, EncoderPreset: "slow"}},
+		{name: "VBR需要最大码率", params: TranscodeParams{RateControl: RateControl_VBR, VideoBitrate: "2M"}, wantErr: true},
+		{name: "VBR", params: TranscodeParams{RateControl: RateControl_VBR, VideoBitrate: "2M", MaxRate: "4M"}},
+		{name: "CBR需要码率", params: TranscodeParams{RateControl: RateControl_CBR}, wantErr: true},
+		{name: "无效的码率", params: TranscodeParams{RateControl: RateControl_ABR, VideoBitrate: "2X"}, wantErr: true},
+		{name: "无效的码率控制方式", params: TranscodeParams{RateControl: "cq"}, wantErr: true},
+		{name: "无效的编码预设", params: TranscodeParams{EncoderPreset: "fastest"}, wantErr: true},
+		{name: "GOP为负数", params: TranscodeParams{GOP: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateVideoEncodeParams(tt.params); (err != nil) != tt.wantErr {
+				t.Errorf("validateVideoEncodeParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuality(t *testing.T) {
+	nvenc := mustEncoderBackend(t, HardwareBackend_NVENC)
+	qsv := mustEncoderBackend(t, HardwareBackend_QSV)
+	software := func(encoder string) encoderSelection { return encoderSelection{Video: encoder} }
+
+	tests := []struct {
+		name     string
+		quality  *int
+		encoders encoderSelection
+		wantErr  bool
+	}{
+		{name: "未指定质量值", encoders: software("libx264")},
+		{name: "直接复制时不校验", quality: intPtr(99), encoders: software("copy")},
+		{name: "x264无损", quality: intPtr(0), encoders: software("libx264")},
+		{name: "x264最大值", quality: intPtr(51), encoders: software("libx264")},
+		{name: "x264超出范围", quality: intPtr(52), encoders: software("libx264"), wantErr: true},
+		{name: "x265负数", quality: intPtr(-1), encoders: software("libx265"), wantErr: true},
+		{name: "NVENC不支持0", quality: intPtr(0), encoders: encoderSelection{Video: "h264_nvenc", Backend: &nvenc}, wantErr: true},
+		{name: "NVENC最大值", quality: intPtr(51), encoders: encoderSelection{Video: "hevc_nvenc", Backend: &nvenc}},
+		{name: "QSV超出范围", quality: intPtr(52), encoders: encoderSelection{Video: "h264_qsv", Backend: &qsv}, wantErr: true},
+		{name: "SVT-AV1不支持0", quality: intPtr(0), encoders: software("libsvtav1"), wantErr: true},
+		{name: "SVT-AV1最大值", quality: intPtr(63), encoders: software("libsvtav1")},
+		{name: "VP9超出范围", quality: intPtr(64), encoders: software("libvpx-vp9"), wantErr: true},
+		{name: "libaom-av1无损", quality: intPtr(0), encoders: software("libaom-av1")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateQuality(TranscodeParams{Quality: tt.quality}, tt.encoders)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateQuality() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVideoEncodeArgs(t *testing.T) {
+	nvenc := mustEncoderBackend(t, HardwareBackend_NVENC)
+	qsv := mustEncoderBackend(t, HardwareBackend_QSV)
+	software := func(encoder string) encoderSelection { return encoderSelection{Video: encoder} }
+
+	tests := []struct {
+		name        string
+		params      TranscodeParams
+		encoders    encoderSelection
+		want        []string
+		wantIgnored int
+	}{
+		{
+			name:     "直接复制时只保留码率",
+			params:   TranscodeParams{VideoBitrate: "2M", RateControl: RateControl_CRF, Quality: intPtr(20)},
+			encoders: software("copy"),
+			want:     []string{"-b:v", "2M"},
+		},
+		{
+			name:     "x264 CRF、预设、tune和profile",
+			params:   TranscodeParams{RateControl: RateControl_CRF, Quality: intPtr(20), EncoderPreset: "slow", Tune: "film", Profile: "high"},
+			encoders: software("libx264"),
+			want:     []string{"-crf", "20", "-preset", "slow", "-tune", "film", "-profile:v", "high"},
+		},
+		{
+			name:     "x264 CRF限制峰值码率",
+			params:   TranscodeParams{RateControl: RateControl_CRF, MaxRate: "4M", BufSize: "8M"},
+			encoders: software("libx264"),
+			want:     []string{"-crf", "23", "-maxrate", "4M", "-bufsize", "8M"},
+		},
+		{
+			name:     "x264 CBR",
+			params:   TranscodeParams{RateControl: RateControl_CBR, VideoBitrate: "2M"},
+			encoders: software("libx264"),
+			want:     []string{"-b:v", "2M", "-maxrate", "2M", "-bufsize", "4000000", "-minrate", "2M", "-x264-params", "nal-hrd=cbr"},
+		},
+		{
+			name:     "x265的level通过x265-params设置",
+			params:   TranscodeParams{RateControl: RateControl_CRF, Level: "4.1"},
+			encoders: software("libx265"),
+			want:     []string{"-crf", "28", "-x265-params", "level-idc=4.1"},
+		},
+		{
+			name:     "NVENC恒定质量和预设",
+			params:   TranscodeParams{RateControl: RateControl_CRF, Quality: intPtr(25), EncoderPreset: "slow", Tune: "zerolatency"},
+			encoders: encoderSelection{Video: "h264_nvenc", Backend: &nvenc},
+			want:     []string{"-rc", "vbr", "-cq", "25", "-b:v", "0", "-preset", "p5", "-zerolatency", "1"},
+		},
+		{
+			name:        "NVENC不支持tune film",
+			params:      TranscodeParams{RateControl: RateControl_VBR, VideoBitrate: "3M", MaxRate: "6M", Tune: "film"},
+			encoders:    encoderSelection{Video: "h264_nvenc", Backend: &nvenc},
+			want:        []string{"-rc", "vbr", "-b:v", "3M", "-maxrate", "6M", "-bufsize", "12000000"},
+			wantIgnored: 1,
+		},
+		{
+			name:        "QSV恒定质量不能限制峰值码率",
+			params:      TranscodeParams{RateControl: RateControl_CRF, Quality: intPtr(25), MaxRate: "4M", EncoderPreset: "ultrafast", Profile: "main"},
+			encoders:    encoderSelection{Video: "hevc_qsv", Backend: &qsv},
+			want:        []string{"-global_quality", "25", "-preset", "veryfast", "-profile:v", "main"},
+			wantIgnored: 1,
+		},
+		{
+			name:     "SVT-AV1 CRF和预设",
+			params:   TranscodeParams{RateControl: RateControl_CRF, EncoderPreset: "medium"},
+			encoders: software("libsvtav1"),
+			want:     []string{"-crf", "35", "-preset", "6"},
+		},
+		{
+			name:     "libaom-av1默认使用恒定质量",
+			params:   TranscodeParams{},
+			encoders: software("libaom-av1"),
+			want:     []string{"-crf", "30", "-b:v", "0", "-cpu-used", "4"},
+		},
+		{
+			name:     "libaom-av1受限质量",
+			params:   TranscodeParams{RateControl: RateControl_CRF, Quality: intPtr(30), MaxRate: "3M", EncoderPreset: "veryslow"},
+			encoders: software("libaom-av1"),
+			want:     []string{"-crf", "30", "-b:v", "3M", "-cpu-used", "1"},
+		},
+		{
+			name:     "VP9默认使用恒定质量和行多线程",
+			params:   TranscodeParams{},
+			encoders: software("libvpx-vp9"),
+			want:     []string{"-crf", "31", "-b:v", "0", "-deadline", "good", "-cpu-used", "2", "-row-mt", "1"},
+		},
+		{
+			name:     "VP9 CBR",
+			params:   TranscodeParams{RateControl: RateControl_CBR, VideoBitrate: "2M", BufSize: "4M", EncoderPreset: "ultrafast"},
+			encoders: software("libvpx-vp9"),
+			want:     []string{"-b:v", "2M", "-maxrate", "2M", "-bufsize", "4M", "-minrate", "2M", "-deadline", "good", "-cpu-used", "5", "-row-mt", "1"},
+		},
+		{
+			name:        "VP9不支持level",
+			params:      TranscodeParams{RateControl: RateControl_CRF, Quality: intPtr(33), Level: "4.1", EncoderPreset: "slow"},
+			encoders:    software("libvpx-vp9"),
+			want:        []string{"-crf", "33", "-b:v", "0", "-deadline", "good", "-cpu-used", "1", "-row-mt", "1"},
+			wantIgnored: 1,
+		},
+		{
+			name:        "rav1e量化参数和速度",
+			params:      TranscodeParams{RateControl: RateControl_CRF, Quality: intPtr(25), MaxRate: "3M", EncoderPreset: "fast"},
+			encoders:    software("librav1e"),
+			want:        []string{"-qp", "100", "-speed", "7"},
+			wantIgnored: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ignored := videoEncodeArgs(tt.params, tt.encoders, encodePass{})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("videoEncodeArgs() = %q, want %q", got, tt.want)
+			}
+			if len(ignored) != tt.wantIgnored {
+				t.Errorf("忽略的参数 = %q, want %d 条", ignored, tt.wantIgnored)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package process
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+type TranscodeResultStatus string
+
+const (
+	TranscodeResultStatus_Success   TranscodeResultStatus = "success"   // 成功
+	TranscodeResultStatus_Failed    TranscodeResultStatus = "failed"    // 失败
+	TranscodeResultStatus_Cancelled TranscodeResultStatus = "cancelled" // 已取消
+	TranscodeResultStatus_Skipped   TranscodeResultStatus = "skipped"   // 输出文件已存在，已跳过
+)
+
+type TranscodeErrorKind string
+
+const (
+	TranscodeErrorKind_None              TranscodeErrorKind = ""                   // 无错误
+	TranscodeErrorKind_FFmpegUnavailable TranscodeErrorKind = "ffmpeg_unavailable" // 未找到FFmpeg
+	TranscodeErrorKind_MissingEncoder    TranscodeErrorKind = "missing_encoder"    // 编码器不存在
+	TranscodeErrorKind_BadInput          TranscodeErrorKind = "bad_input"          // 输入文件无效
+	TranscodeErrorKind_DiskFull          TranscodeErrorKind = "disk_full"          // 磁盘空间不足
+	TranscodeErrorKind_PermissionDenied  TranscodeErrorKind = "permission_denied"  // 没有权限
+	TranscodeErrorKind_OutputExists      TranscodeErrorKind = "output_exists"      // 输出文件已存在
+	TranscodeErrorKind_Cancelled         TranscodeErrorKind = "cancelled"          // 已取消
+	TranscodeErrorKind_Interrupted       TranscodeErrorKind = "interrupted"        // 程序退出或崩溃时任务未完成
+	TranscodeErrorKind_InvalidParams     TranscodeErrorKind = "invalid_params"     // 转码参数无效
+	TranscodeErrorKind_Unknown           TranscodeErrorKind = "unknown"            // 未知错误
+)
+
+// stderrTailLines 结果中保留的FFmpeg stderr最后行数
+const stderrTailLines = 20
+
+// TranscodeResult 单个转码任务的结果
+type TranscodeResult struct {
+	ID                 string                `json:"id"`
+	Status             TranscodeResultStatus `json:"status"`
+	InputPath          string                `json:"input_path"`
+	InputPaths         []string              `json:"input_paths,omitempty"` // 合并任务按顺序合并的所有文件
+	OutputPath         string                `json:"output_path"`           // 分段输出时为第一段
+	ElapsedSeconds     float64               `json:"elapsed_seconds"`
+	InputInfo          *VideoInfo            `json:"input_info"`
+	OutputInfo         *VideoInfo            `json:"output_info"`          // 分段输出时为第一段
+	SizeRatio          float64               `json:"size_ratio"`           // 输出大小/输入大小，分段输出时按所有分段的总大小计算
+	Segments           []TranscodeResult     `json:"segments,omitempty"`   // 分段输出时每一段的结果
+	Subtitles          []SubtitleOutput      `json:"subtitles,omitempty"`  // 导出或转换得到的字幕文件
+	Args               []string              `json:"args"`                 // 完整的FFmpeg命令行，两遍编码时为第二遍的命令
+	VideoEncoder       string                `json:"video_encoder"`        // 实际使用的视频编码器
+	AudioEncoder       string                `json:"audio_encoder"`        // 实际使用的音频编码器
+	Passes             int                   `json:"passes"`               // 编码遍数
+	TargetVideoBitrate int64                 `json:"target_video_bitrate"` // 目标大小模式计算出的视频码率（bit/s）
+	Warnings           []string              `json:"warnings"`             // 编码器替换等提示
+	ExitCode           int                   `json:"exit_code"`
+	ErrorKind          TranscodeErrorKind    `json:"error_kind"`
+	Error              string                `json:"error"`
+	StderrTail         []string              `json:"stderr_tail"`
+}
+
+// stderrTail 保存FFmpeg stderr的最后若干行日志，-progress输出的key=value行不计入
+type stderrTail struct {
+	lines []string
+}
+
+var progressLineRegex = regexp.MustCompile(`^[a-z_0-9]+=\S*$`)
+
+func (t *stderrTail) add(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" || progressLineRegex.MatchString(line) {
+		return
+	}
+	t.lines = append(t.lines, line)
+	if len(t.lines) > stderrTailLines {
+		t.lines = t.lines[len(t.lines)-stderrTailLines:]
+	}
+}
+
+// classifyTranscodeError 根据FFmpeg的stderr输出判断错误类型
+func classifyTranscodeError(stderrLines []string) TranscodeErrorKind {
+	output := strings.ToLower(strings.Join(stderrLines, "\n"))
+	switch {
+	case strings.Contains(output, "unknown encoder"),
+		strings.Contains(output, "encoder not found"),
+		strings.Contains(output, "error selecting an encoder"),
+		strings.Contains(output, "error while opening encoder"),
+		strings.Contains(output, "cannot load"):
+		return TranscodeErrorKind_MissingEncoder
+	case strings.Contains(output, "no space left on device"),
+		strings.Contains(output, "disk full"):
+		return TranscodeErrorKind_DiskFull
+	case strings.Contains(output, "permission denied"),
+		strings.Contains(output, "access is denied"):
+		return TranscodeErrorKind_PermissionDenied
+	case strings.Contains(output, "invalid data found when processing input"),
+		strings.Contains(output, "no such file or directory"),
+		strings.Contains(output, "moov atom not found"),
+		strings.Contains(output, "could not find codec parameters"),
+		strings.Contains(output, "does not contain any stream"),
+		strings.Contains(output, "end of file"):
+		return TranscodeErrorKind_BadInput
+	default:
+		return TranscodeErrorKind_Unknown
+	}
+}
+
+// classifyFileError 判断文件操作错误的类型
+func classifyFileError(err error) TranscodeErrorKind {
+	if errors.Is(err, os.ErrPermission) {
+		return TranscodeErrorKind_PermissionDenied
+	}
+	return classifyTranscodeError([]string{err.Error()})
+}
+
+// exitCodeFromError 从命令错误中获取退出码，进程未启动时返回-1
+func exitCodeFromError(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// withoutThumbnails 去掉结果中的base64缩略图，避免汇总和历史记录文件过大
+func withoutThumbnails(result TranscodeResult) TranscodeResult {
+	if result.InputInfo != nil {
+		info := *result.InputInfo
+		info.Thumbnail = ""
+		result.InputInfo = &info
+	}
+	if result.OutputInfo != nil {
+		info := *result.OutputInfo
+		info.Thumbnail = ""
+		result.OutputInfo = &info
+	}
+	if len(result.Segments) > 0 {
+		segments := make([]TranscodeResult, len(result.Segments))
+		for i, segment := range result.Segments {
+			segments[i] = withoutThumbnails(segment)
+		}
+		result.Segments = segments
+	}
+	return result
+}
@@ -0,0 +1,435 @@
+package process
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type SegmentMode string
+
+const (
+	SegmentMode_None     SegmentMode = ""         // 不分段
+	SegmentMode_Duration SegmentMode = "duration" // 按时长分段
+	SegmentMode_Size     SegmentMode = "size"     // 按大小分段，根据输出码率估算每段的时长
+	SegmentMode_Scene    SegmentMode = "scene"    // 在场景切换处分段
+)
+
+const (
+	defaultSegmentPattern = "_part{part}" // 默认的分段文件名后缀
+	segmentPartToken      = "{part}"
+	// defaultSceneThreshold 默认的场景变化阈值，对应FFmpeg select滤镜的 scene 值
+	defaultSceneThreshold = 0.4
+	// segmentSizeMargin 按大小分段时只使用目标大小的90%，码率波动和关键帧对齐会使实际大小超出估算值
+	segmentSizeMargin = 0.9
+	// minSegmentSeconds 按大小分段时每段的最短时长
+	minSegmentSeconds = 1.0
+	// segmentUnlimited 没有切分点时的分段时长，只输出一段
+	segmentUnlimited = "999999"
+)
+
+// SegmentOptions 分段输出的设置，模式为空时不分段
+type SegmentOptions struct {
+	Mode           SegmentMode `yaml:"mode" json:"mode"`                      // 分段方式: duration、size、scene
+	Duration       string      `yaml:"duration" json:"duration"`              // 每段的最长时长，秒数或时间码；按场景分段时为两个切分点之间的最短时长
+	SizeMB         float64     `yaml:"sizeMB" json:"size_mb"`                 // 每段的大约最大大小（MB）
+	SceneThreshold float64     `yaml:"sceneThreshold" json:"scene_threshold"` // 场景变化阈值 0-1，越小切分点越多，为0时使用0.4
+	Pattern        string      `yaml:"pattern" json:"pattern"`                // 分段文件名后缀，{part} 为从001开始的序号，为空时使用 _part{part}
+}
+
+// IsZero 是否不分段
+func (o SegmentOptions) IsZero() bool {
+	return o.Mode == SegmentMode_None
+}
+
+// pattern 获取实际使用的分段文件名后缀
+func (o SegmentOptions) pattern() string {
+	if o.Pattern == "" {
+		return defaultSegmentPattern
+	}
+	return o.Pattern
+}
+
+// sceneThreshold 获取实际使用的场景变化阈值
+func (o SegmentOptions) sceneThreshold() float64 {
+	if o.SceneThreshold <= 0 {
+		return defaultSceneThreshold
+	}
+	return o.SceneThreshold
+}
+
+// minDuration 按场景分段时两个切分点之间的最短时长，未指定时为0
+func (o SegmentOptions) minDuration() float64 {
+	seconds, err := parseTrimTime(o.Duration)
+	if o.Duration == "" || err != nil {
+		return 0
+	}
+	return seconds
+}
+
+// validateSegment 校验分段设置
+func validateSegment(options SegmentOptions) error {
+	switch options.Mode {
+	case SegmentMode_None:
+		return nil
+	case SegmentMode_Duration:
+		if options.Duration == "" {
+			return fmt.Errorf("按时长分段需要指定每段的时长")
+		}
+		seconds, err := parseTrimTime(options.Duration)
+		if err != nil || seconds <= 0 {
+			return fmt.Errorf("无效的分段时长: %s", options.Duration)
+		}
+	case SegmentMode_Size:
+		if options.SizeMB <= 0 {
+			return fmt.Errorf("按大小分段需要指定每段的大小")
+		}
+	case SegmentMode_Scene:
+		if options.SceneThreshold < 0 || options.SceneThreshold > 1 {
+			return fmt.Errorf("场景变化阈值必须在0到1之间: %g", options.SceneThreshold)
+		}
+		if options.Duration != "" {
+			if _, err := parseTrimTime(options.Duration); err != nil {
+				return fmt.Errorf("无效的分段最短时长: %s", options.Duration)
+			}
+		}
+	default:
+		return fmt.Errorf("无效的分段方式: %s，可用: duration、size、scene", options.Mode)
+	}
+
+	pattern := options.pattern()
+	if strings.Count(pattern, segmentPartToken) != 1 {
+		return fmt.Errorf("分段文件名后缀必须包含一个 %s: %s", segmentPartToken, pattern)
+	}
+	if literal := strings.ReplaceAll(pattern, segmentPartToken, ""); illegalFileNameChars.MatchString(literal) || strings.Contains(literal, "/") {
+		return fmt.Errorf(`分段文件名后缀中不能包含以下字符: / < > : " | ? * \`)
+	}
+	return nil
+}
+
+// segmentPlan 分段的切分方式，Times 不为空时在指定的时间点切分，否则每 Interval 秒切分一次
+type segmentPlan struct {
+	Interval float64   // 每段的时长（秒）
+	Times    []float64 // 切分的时间点（秒），相对于截取后的开头
+	Pattern  string    // 分段文件名后缀
+}
+
+// resolveSegmentPlan 根据分段设置计算切分方式
+//
+// 按大小分段时根据输出码率估算每段的时长；按场景分段时先用FFmpeg检测场景切换的时间点，taskCtx 取消时结束检测。
+//
+// 返回值:
+//
+//	*segmentPlan: 切分方式
+//	[]string: 估算码率、没有检测到场景切换等提示
+//	error: 无法分段的原因
+func resolveSegmentPlan(taskCtx context.Context, id, inputFilePath string, params TranscodeParams, inputInfo *VideoInfo, encoders encoderSelection) (*segmentPlan, []string, error) {
+	options := params.Segment
+	plan := &segmentPlan{Pattern: options.pattern()}
+	var warnings []string
+	format, hasFormat := getContainerFormat(params.Container)
+	audioOnly := hasFormat && format.AudioOnly
+	if !audioOnly && encoders.Video == "copy" && inputInfo != nil && inputInfo.VideoCodec != "" {
+		warnings = append(warnings, "直接复制视频流时只能在关键帧处分段，每段的时长和大小可能超出设定值")
+	}
+
+	switch options.Mode {
+	case SegmentMode_Duration:
+		plan.Interval, _ = parseTrimTime(options.Duration)
+	case SegmentMode_Size:
+		bitrate, estimated := estimateOutputBitrate(params, inputInfo)
+		if bitrate <= 0 {
+			return nil, nil, fmt.Errorf("无法估算输出码率，不能按大小分段，请指定视频码率或改为按时长分段")
+		}
+		if estimated {
+			warnings = append(warnings, "没有指定码率，按源视频的码率估算分段时长，实际大小可能超出设定值")
+		}
+		plan.Interval = options.SizeMB * bytesPerMB * 8 * segmentSizeMargin / float64(bitrate)
+		if plan.Interval < minSegmentSeconds {
+			return nil, nil, fmt.Errorf("分段大小 %gMB 过小，按 %dkbps 的码率每段不足 %g 秒", options.SizeMB, bitrate/1000, minSegmentSeconds)
+		}
+	case SegmentMode_Scene:
+		times, err := detectSceneChanges(taskCtx, id, inputFilePath, params.Trim, options.sceneThreshold())
+		if err != nil {
+			return nil, nil, err
+		}
+		plan.Times = spaceSegmentTimes(times, options.minDuration())
+		if len(plan.Times) == 0 {
+			warnings = append(warnings, "没有检测到场景切换，只输出一段")
+		}
+	}
+	return plan, warnings, nil
+}
+
+// estimateOutputBitrate 估算输出文件的总码率（bit/s），返回true表示按源视频的码率估算
+//
+// 指定了最大码率时按最大码率估算，宁可每段偏小也不要超过设定的大小
+func estimateOutputBitrate(params TranscodeParams, inputInfo *VideoInfo) (int64, bool) {
+	var videoBitrate int64
+	estimated := false
+	format, hasFormat := getContainerFormat(params.Container)
+	if !hasFormat || !format.AudioOnly {
+		if videoCodecName(params) != "" {
+			if hasBitrate(params.MaxRate) {
+				videoBitrate, _ = parseBitrate(params.MaxRate)
+			} else if hasBitrate(params.VideoBitrate) {
+				videoBitrate, _ = parseBitrate(params.VideoBitrate)
+			}
+		}
+		if videoBitrate <= 0 && inputInfo != nil {
+			videoBitrate = int64(inputInfo.VideoBitrate)
+			if videoBitrate <= 0 {
+				videoBitrate = int64(inputInfo.Bitrate - inputInfo.AudioBitrate)
+			}
+			estimated = videoCodecName(params) != ""
+		}
+		if videoBitrate <= 0 {
+			return 0, false
+		}
+	}
+	return videoBitrate + targetAudioBitrate(params, inputInfo), estimated
+}
+
+var sceneChangeRegex = regexp.MustCompile(`pts_time:\s*([0-9.]+)`)
+
+// detectSceneChanges 使用select和showinfo滤镜检测场景切换的时间点（秒），截取时只检测截取的范围，进程登记到任务 id 以便暂停
+func detectSceneChanges(taskCtx context.Context, id, inputFilePath string, trim TrimRange, threshold float64) ([]float64, error) {
+	ffmpegPath, err := IsFFmpegAvailable()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg不可用: %v", err)
+	}
+	cmd := createCommandContext(taskCtx, ffmpegPath, buildSceneDetectArgs(inputFilePath, trim, threshold)...)
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("创建stderr管道失败: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("启动FFmpeg失败: %v", err)
+	}
+	attachTranscodeCommand(id, cmd)
+	times := parseSceneChanges(bufio.NewScanner(stderr))
+	err = cmd.Wait()
+	detachTranscodeCommand(id, cmd)
+	if err != nil {
+		if taskCtx.Err() != nil {
+			return nil, taskCtx.Err()
+		}
+		return nil, fmt.Errorf("检测场景切换失败: %v", err)
+	}
+	return times, nil
+}
+
+// buildSceneDetectArgs 生成检测场景切换的FFmpeg参数，只解码第一个视频流，结果输出到stderr
+func buildSceneDetectArgs(inputFilePath string, trim TrimRange, threshold float64) []string {
+	args := []string{"-hide_banner", "-nostats"}
+	args = append(args, trimInputArgs(trim)...)
+	args = append(args, "-i", inputFilePath)
+	if _, length, err := trim.bounds(); err == nil && length > 0 {
+		args = append(args, "-t", formatSeconds(length))
+	}
+	filter := fmt.Sprintf("select='gt(scene\\,%s)',showinfo", strconv.FormatFloat(threshold, 'f', -1, 64))
+	return append(args, "-map", "0:v:0", "-vf", filter, "-an", "-sn", "-dn", "-f", "null", os.DevNull)
+}
+
+// parseSceneChanges 从showinfo的输出中读取每个选中帧的时间
+func parseSceneChanges(scanner *bufio.Scanner) []float64 {
+	var times []float64
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.Contains(line, "Parsed_showinfo") {
+			continue
+		}
+		match := sceneChangeRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		if seconds, err := strconv.ParseFloat(match[1], 64); err == nil && seconds > 0 {
+			times = append(times, seconds)
+		}
+	}
+	return times
+}
+
+// spaceSegmentTimes 去掉与上一个切分点距离小于最短时长的时间点
+func spaceSegmentTimes(times []float64, minDuration float64) []float64 {
+	var spaced []float64
+	last := 0.0
+	for _, t := range times {
+		if t-last < max(minDuration, minSegmentSeconds) {
+			continue
+		}
+		spaced = append(spaced, t)
+		last = t
+	}
+	return spaced
+}
+
+// segmentKeyframeArgs 重新编码时在切分点强制插入关键帧，使每段都从切分点开始
+func segmentKeyframeArgs(plan segmentPlan) []string {
+	if len(plan.Times) > 0 {
+		return []string{"-force_key_frames", joinSegmentTimes(plan.Times)}
+	}
+	if plan.Interval > 0 {
+		return []string{"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%s)", formatSeconds(plan.Interval))}
+	}
+	return nil
+}
+
+// segmentMuxArgs 生成segment封装参数，代替封装格式的 -f 参数，封装格式的参数通过 -segment_format_options 传递
+func segmentMuxArgs(plan segmentPlan, format containerFormat, hasFormat bool, encoders encoderSelection, inputInfo *VideoInfo) []string {
+	var args []string
+	if hasFormat {
+		args = append(args, format.tagArgs(encoders, inputInfo)...)
+	}
+	args = append(args, "-f", "segment")
+	if hasFormat {
+		args = append(args, "-segment_format", format.Muxer)
+		var options []string
+		for i := 0; i+1 < len(format.Flags); i += 2 {
+			options = append(options, strings.TrimPrefix(format.Flags[i], "-")+"="+format.Flags[i+1])
+		}
+		if len(options) > 0 {
+			args = append(args, "-segment_format_options", strings.Join(options, ":"))
+		}
+	}
+	switch {
+	case len(plan.Times) > 0:
+		args = append(args, "-segment_times", joinSegmentTimes(plan.Times))
+	case plan.Interval > 0:
+		args = append(args, "-segment_time", formatSeconds(plan.Interval))
+	default:
+		args = append(args, "-segment_time", segmentUnlimited)
+	}
+	return append(args, "-reset_timestamps", "1", "-segment_start_number", "1")
+}
+
+func joinSegmentTimes(times []float64) string {
+	values := make([]string, len(times))
+	for i, t := range times {
+		values[i] = formatSeconds(t)
+	}
+	return strings.Join(values, ",")
+}
+
+// segmentPartPath 获取第 part 段的输出文件路径，在输出文件名和扩展名之间加上分段后缀
+func segmentPartPath(outputFilePath, pattern string, part int) string {
+	ext := filepath.Ext(outputFilePath)
+	suffix := strings.ReplaceAll(pattern, segmentPartToken, fmt.Sprintf("%03d", part))
+	return strings.TrimSuffix(outputFilePath, ext) + suffix + ext
+}
+
+// existingSegmentParts 获取磁盘上已有的分段文件的序号，用于判断输出文件是否已存在和覆盖时删除多余的旧分段
+func existingSegmentParts(outputFilePath, pattern string) []int {
+	ext := filepath.Ext(outputFilePath)
+	before, after, _ := strings.Cut(pattern, segmentPartToken)
+	prefix := outputPathKey(strings.TrimSuffix(outputFilePath, ext) + before)
+	suffix := outputPathKey(after + ext)
+	entries, err := os.ReadDir(filepath.Dir(outputFilePath))
+	if err != nil {
+		return nil
+	}
+	var parts []int
+	for _, entry := range entries {
+		name := outputPathKey(filepath.Join(filepath.Dir(outputFilePath), entry.Name()))
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) || len(name) < len(prefix)+len(suffix)+3 {
+			continue
+		}
+		digits := name[len(prefix) : len(name)-len(suffix)]
+		if part, err := strconv.Atoi(digits); err == nil && part > 0 && strings.Trim(digits, "0123456789") == "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// tempSegmentPath 获取第 part 段的临时文件路径，格式与 tempOutputPath 相同，序号加在 .part 之后
+func tempSegmentPath(tempFilePath string, part int) string {
+	ext := filepath.Ext(tempFilePath)
+	return fmt.Sprintf("%s%03d%s", strings.TrimSuffix(tempFilePath, ext), part, ext)
+}
+
+// tempSegmentPattern 获取传给segment封装的临时文件名格式，路径中的 % 需要转义
+func tempSegmentPattern(tempFilePath string) string {
+	ext := filepath.Ext(tempFilePath)
+	escaped := strings.ReplaceAll(strings.TrimSuffix(tempFilePath, ext), "%", "%%")
+	return escaped + "%03d" + strings.ReplaceAll(ext, "%", "%%")
+}
+
+// commitSegmentOutputs 将每一段的临时文件重命名为输出文件，并分别获取视频信息作为单独的结果
+//
+// 每一段都按同名文件策略检查，只有覆盖时才替换已有的分段，并删除以前输出的序号更大的分段。
+// 主结果的输出路径和视频信息为第一段，压缩比按所有分段的总大小计算
+func commitSegmentOutputs(ctx context.Context, id, tempFilePath, outputFilePath, pattern string, result *TranscodeResult) error {
+	var count int
+	for FileExists(tempSegmentPath(tempFilePath, count+1)) {
+		count++
+	}
+	if count == 0 {
+		return fmt.Errorf("FFmpeg没有输出任何分段")
+	}
+	for part := 1; part <= count; part++ {
+		partPath := segmentPartPath(outputFilePath, pattern, part)
+		if FileExists(partPath) && collisionPolicyFor(partPath, result.InputPath) != OutputCollisionPolicy_Overwrite {
+			for part := 1; part <= count; part++ {
+				os.Remove(tempSegmentPath(tempFilePath, part))
+			}
+			return fmt.Errorf("%v: %s", errOutputExists, partPath)
+		}
+	}
+
+	var totalSize int64
+	for part := 1; part <= count; part++ {
+		tempPath := tempSegmentPath(tempFilePath, part)
+		partPath := segmentPartPath(outputFilePath, pattern, part)
+		if err := commitOutputFile(tempPath, partPath); err != nil {
+			return err
+		}
+		partResult := TranscodeResult{
+			ID:           fmt.Sprintf("%s-part%03d", id, part),
+			Status:       TranscodeResultStatus_Success,
+			InputPath:    result.InputPath,
+			OutputPath:   partPath,
+			VideoEncoder: result.VideoEncoder,
+			AudioEncoder: result.AudioEncoder,
+		}
+		if videoInfo, err := GetVideoInfo(partPath); err == nil {
+			videoInfo.ID = partResult.ID
+			partResult.OutputInfo = &videoInfo
+			totalSize += videoInfo.Size
+			if result.InputInfo != nil && result.InputInfo.Size > 0 {
+				partResult.SizeRatio = float64(videoInfo.Size) / float64(result.InputInfo.Size)
+			}
+		} else {
+			partResult.Warnings = append(partResult.Warnings, fmt.Sprintf("无法获取分段的视频信息: %v", err))
+		}
+		result.Segments = append(result.Segments, partResult)
+	}
+	for _, part := range existingSegmentParts(outputFilePath, pattern) {
+		partPath := segmentPartPath(outputFilePath, pattern, part)
+		if part <= count || collisionPolicyFor(partPath, result.InputPath) != OutputCollisionPolicy_Overwrite {
+			continue
+		}
+		if err := os.Remove(partPath); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("删除以前输出的分段失败: %v", err))
+		}
+	}
+
+	first := result.Segments[0]
+	result.OutputPath = first.OutputPath
+	if first.OutputInfo != nil {
+		videoInfo := *first.OutputInfo
+		videoInfo.ID = id
+		result.OutputInfo = &videoInfo
+		emitEvent(ctx, "videoTranscodeSuccess", videoInfo)
+	}
+	if result.InputInfo != nil && result.InputInfo.Size > 0 {
+		result.SizeRatio = float64(totalSize) / float64(result.InputInfo.Size)
+	}
+	consolePrintf(ctx, "共输出 %d 段\n", len(result.Segments))
+	return nil
+}
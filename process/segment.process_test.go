@@ -0,0 +1,198 @@
+package process
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestResolveSegmentPlan(t *testing.T) {
+	source := &VideoInfo{VideoCodec: "h264", AudioCodec: "aac", Bitrate: 5_128_000, AudioBitrate: 128_000}
+	reencode := encoderSelection{Video: "libx264", Audio: "aac"}
+
+	tests := []struct {
+		name         string
+		params       TranscodeParams
+		inputInfo    *VideoInfo
+		encoders     encoderSelection
+		wantInterval float64
+		wantWarnings int
+		wantErr      bool
+	}{
+		{
+			name:         "按时长分段",
+			params:       TranscodeParams{VideoCodec: "h264", AudioCodec: "copy", Segment: SegmentOptions{Mode: SegmentMode_Duration, Duration: "10:00"}},
+			inputInfo:    source,
+			encoders:     reencode,
+			wantInterval: 600,
+		},
+		{
+			name:         "直接复制视频流时提示只能在关键帧分段",
+			params:       TranscodeParams{VideoCodec: "copy", AudioCodec: "copy", Segment: SegmentOptions{Mode: SegmentMode_Duration, Duration: "90"}},
+			inputInfo:    source,
+			encoders:     encoderSelection{Video: "copy", Audio: "copy"},
+			wantInterval: 90,
+			wantWarnings: 1,
+		},
+		{
+			name: "按指定的码率计算每段时长",
+			params: TranscodeParams{
+				VideoCodec: "h264", VideoBitrate: "2M", AudioCodec: "aac", AudioBitrate: "128k",
+				Segment: SegmentOptions{Mode: SegmentMode_Size, SizeMB: 100},
+			},
+			inputInfo:    source,
+			encoders:     reencode,
+			wantInterval: 100 * bytesPerMB * 8 * segmentSizeMargin / 2_128_000,
+		},
+		{
+			name: "按最大码率计算每段时长",
+			params: TranscodeParams{
+				VideoCodec: "h264", VideoBitrate: "2M", MaxRate: "4M", AudioCodec: "aac", AudioBitrate: "128k",
+				Segment: SegmentOptions{Mode: SegmentMode_Size, SizeMB: 100},
+			},
+			inputInfo:    source,
+			encoders:     reencode,
+			wantInterval: 100 * bytesPerMB * 8 * segmentSizeMargin / 4_128_000,
+		},
+		{
+			name:         "直接复制时按源文件的码率计算",
+			params:       TranscodeParams{VideoCodec: "copy", AudioCodec: "copy", Segment: SegmentOptions{Mode: SegmentMode_Size, SizeMB: 100}},
+			inputInfo:    source,
+			encoders:     encoderSelection{Video: "copy", Audio: "copy"},
+			wantInterval: 100 * bytesPerMB * 8 * segmentSizeMargin / 5_128_000,
+			wantWarnings: 1,
+		},
+		{
+			name:         "重新编码但没有指定码率时按源视频估算",
+			params:       TranscodeParams{VideoCodec: "h265", AudioCodec: "copy", Segment: SegmentOptions{Mode: SegmentMode_Size, SizeMB: 100}},
+			inputInfo:    source,
+			encoders:     encoderSelection{Video: "libx265", Audio: "copy"},
+			wantInterval: 100 * bytesPerMB * 8 * segmentSizeMargin / 5_128_000,
+			wantWarnings: 1,
+		},
+		{
+			name:      "无法估算码率",
+			params:    TranscodeParams{VideoCodec: "h264", AudioCodec: "aac", Segment: SegmentOptions{Mode: SegmentMode_Size, SizeMB: 100}},
+			inputInfo: &VideoInfo{VideoCodec: "h264", AudioCodec: "aac"},
+			encoders:  reencode,
+			wantErr:   true,
+		},
+		{
+			name: "分段大小过小",
+			params: TranscodeParams{
+				VideoCodec: "h264", VideoBitrate: "50M", AudioCodec: "aac",
+				Segment: SegmentOptions{Mode: SegmentMode_Size, SizeMB: 1},
+			},
+			inputInfo: source,
+			encoders:  reencode,
+			wantErr:   true,
+		},
+		{
+			name: "只输出音频时按音频码率计算",
+			params: TranscodeParams{
+				VideoCodec: "copy", AudioCodec: "mp3", AudioBitrate: "320k", Container: OutputContainer_MP3,
+				Segment: SegmentOptions{Mode: SegmentMode_Size, SizeMB: 10},
+			},
+			inputInfo:    source,
+			encoders:     encoderSelection{Video: "copy", Audio: "libmp3lame"},
+			wantInterval: 10 * bytesPerMB * 8 * segmentSizeMargin / 320_000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, warnings, err := resolveSegmentPlan(context.Background(), "test", "input.mp4", tt.params, tt.inputInfo, tt.encoders)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveSegmentPlan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if math.Abs(plan.Interval-tt.wantInterval) > 1e-6 {
+				t.Errorf("Interval = %v, want %v", plan.Interval, tt.wantInterval)
+			}
+			if plan.Pattern != defaultSegmentPattern {
+				t.Errorf("Pattern = %q, want %q", plan.Pattern, defaultSegmentPattern)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("警告 = %q, want %d 条", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestSegmentPartPath(t *testing.T) {
+	dir := filepath.Join("out", "videos")
+	tests := []struct {
+		output  string
+		pattern string
+		part    int
+		want    string
+	}{
+		{filepath.Join(dir, "movie.mp4"), defaultSegmentPattern, 1, filepath.Join(dir, "movie_part001.mp4")},
+		{filepath.Join(dir, "movie.mp4"), defaultSegmentPattern, 12, filepath.Join(dir, "movie_part012.mp4")},
+		{filepath.Join(dir, "movie.mp4"), " - {part}", 1000, filepath.Join(dir, "movie - 1000.mp4")},
+		{filepath.Join(dir, "movie.final.mkv"), "-{part}-of-n", 2, filepath.Join(dir, "movie.final-002-of-n.mkv")},
+	}
+	for _, tt := range tests {
+		if got := segmentPartPath(tt.output, tt.pattern, tt.part); got != tt.want {
+			t.Errorf("segmentPartPath(%q, %q, %d) = %q, want %q", tt.output, tt.pattern, tt.part, got, tt.want)
+		}
+	}
+}
+
+func TestTempSegmentPaths(t *testing.T) {
+	temp := filepath.Join("out", "movie.abc123.part.mp4")
+	if got, want := tempSegmentPath(temp, 3), filepath.Join("out", "movie.abc123.part003.mp4"); got != want {
+		t.Errorf("tempSegmentPath() = %q, want %q", got, want)
+	}
+	if got, want := tempSegmentPattern(filepath.Join("100%", "movie.part.mp4")), filepath.Join("100%%", "movie.part%03d.mp4"); got != want {
+		t.Errorf("tempSegmentPattern() = %q, want %q", got, want)
+	}
+}
+
+func TestValidateSegment(t *testing.T) {
+	tests := []struct {
+		name    string
+		options SegmentOptions
+		wantErr bool
+	}{
+		{name: "不分段", options: SegmentOptions{}},
+		{name: "按时长", options: SegmentOptions{Mode: SegmentMode_Duration, Duration: "00:10:00"}},
+		{name: "缺少时长", options: SegmentOptions{Mode: SegmentMode_Duration}, wantErr: true},
+		{name: "时长为0", options: SegmentOptions{Mode: SegmentMode_Duration, Duration: "0"}, wantErr: true},
+		{name: "缺少大小", options: SegmentOptions{Mode: SegmentMode_Size}, wantErr: true},
+		{name: "场景阈值超出范围", options: SegmentOptions{Mode: SegmentMode_Scene, SceneThreshold: 1.5}, wantErr: true},
+		{name: "后缀缺少序号", options: SegmentOptions{Mode: SegmentMode_Size, SizeMB: 100, Pattern: "_part"}, wantErr: true},
+		{name: "后缀包含两个序号", options: SegmentOptions{Mode: SegmentMode_Size, SizeMB: 100, Pattern: "{part}_{part}"}, wantErr: true},
+		{name: "后缀包含非法字符", options: SegmentOptions{Mode: SegmentMode_Size, SizeMB: 100, Pattern: "/{part}"}, wantErr: true},
+		{name: "无效的分段方式", options: SegmentOptions{Mode: "chapter"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateSegment(tt.options); (err != nil) != tt.wantErr {
+				t.Errorf("validateSegment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExistingSegmentParts(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"movie_part001.mp4", "movie_part002.mp4", "movie_part1000.mp4", "movie_part01.mp4", "movie_partx01.mp4", "movie_part003.mkv", "other_part004.mp4"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := existingSegmentParts(filepath.Join(dir, "movie.mp4"), defaultSegmentPattern)
+	sort.Ints(got)
+	if want := []int{1, 2, 1000}; !reflect.DeepEqual(got, want) {
+		t.Errorf("existingSegmentParts() = %v, want %v", got, want)
+	}
+	if got := existingSegmentParts(filepath.Join(dir, "missing", "movie.mp4"), defaultSegmentPattern); got != nil {
+		t.Errorf("目录不存在时 existingSegmentParts() = %v, want nil", got)
+	}
+}
@@ -0,0 +1,444 @@
+package process
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// smartCutQualityOffset 边界片段的CRF比编码器默认值低一些，画质接近直接复制的中间部分
+	smartCutQualityOffset = 5
+	// keyframeEpsilon 比较时间时允许的误差，小于该值视为同一时刻
+	keyframeEpsilon = 0.001
+	// keyframeProbeMargin 探测关键帧时在截取范围前后多读取的秒数，兼容起始时间不为0的文件
+	keyframeProbeMargin = 1.0
+)
+
+// smartCutSource 源视频的编码参数，边界片段按这些参数重新编码，拼接处才能连续播放
+type smartCutSource struct {
+	Codec          string // 参数编码名称 h264/h265/av1/vp9
+	Profile        string
+	Level          string
+	PixelFormat    string
+	ColorRange     string
+	ColorSpace     string
+	ColorTransfer  string
+	ColorPrimaries string
+}
+
+// smartCutPlan 智能切割的分段方案
+//
+// 开始时间到之后的第一个关键帧、结束时间之前的最后一个关键帧到结束时间这两段重新编码，
+// 中间完整的GOP直接复制，最后按顺序拼接
+type smartCutPlan struct {
+	Start         float64 // 截取的开始时间
+	End           float64 // 截取的结束时间，为0表示到结尾
+	FirstKeyframe float64 // 开始时间之后（含）的第一个关键帧
+	LastKeyframe  float64 // 结束时间之前（含）的最后一个关键帧，截取到结尾时不使用
+	Source        smartCutSource
+}
+
+// hasHead 开始时间不在关键帧上，需要重新编码开头片段
+func (p smartCutPlan) hasHead() bool {
+	return p.FirstKeyframe-p.Start > keyframeEpsilon
+}
+
+// hasTail 结束时间不在关键帧上，需要重新编码结尾片段
+func (p smartCutPlan) hasTail() bool {
+	return p.End > 0 && p.End-p.LastKeyframe > keyframeEpsilon
+}
+
+// smartCutUnsupportedReason 判断参数是否可以智能切割，不可以时返回原因
+//
+// 智能切割只在直接复制视频流时有意义，缩放、水印等滤镜和重新编码都需要处理整个视频
+func smartCutUnsupportedReason(params TranscodeParams, inputInfo *VideoInfo) string {
+	if codec := videoCodecName(params); codec != "" {
+		return fmt.Sprintf("已选择 %s 重新编码视频", codec)
+	}
+	if params.VideoHeight != "copy" || params.Fps != "copy" || params.Rotate != VideoRotate_copy ||
+		params.WatermarkContent != "" || params.WatermarkImage != "" || params.BurnSubtitle.Enabled() {
+		return "使用了视频滤镜"
+	}
+	if !params.Segment.IsZero() {
+		return "智能切割不能与分段输出同时使用"
+	}
+	if !params.Streams.IsZero() {
+		return "智能切割不支持选择音轨和字幕"
+	}
+	if params.SoftSubtitles.muxes() {
+		return "智能切割不支持封装外部字幕"
+	}
+	if inputInfo == nil || inputInfo.VideoCodec == "" {
+		return "无法获取源视频的编码"
+	}
+	if _, ok := sourceVideoCodecs[inputInfo.VideoCodec]; !ok {
+		return fmt.Sprintf("不支持按 %s 编码重新编码边界片段", inputInfo.VideoCodec)
+	}
+	if format, ok := getContainerFormat(params.Container); ok {
+		if format.AudioOnly {
+			return fmt.Sprintf("%s 只包含音频", strings.ToUpper(string(format.Name)))
+		}
+		if !supportsCodec(format.VideoCodecs, inputInfo.VideoCodec) {
+			return fmt.Sprintf("%s 不支持源视频的 %s 编码", strings.ToUpper(string(format.Name)), inputInfo.VideoCodec)
+		}
+	}
+	return ""
+}
+
+// planSmartCut 探测截取范围附近的关键帧和源视频的编码参数，生成分段方案
+//
+// 返回值:
+//
+//	*smartCutPlan: 分段方案
+//	error: 无法智能切割的原因，调用方应改为逐帧精确切割
+func planSmartCut(inputFilePath string, params TranscodeParams, inputInfo *VideoInfo) (*smartCutPlan, error) {
+	if reason := smartCutUnsupportedReason(params, inputInfo); reason != "" {
+		return nil, fmt.Errorf("%s", reason)
+	}
+	start, length, err := params.Trim.bounds()
+	if err != nil {
+		return nil, err
+	}
+	plan := &smartCutPlan{Start: start}
+	if length > 0 && (inputInfo.Duration <= 0 || start+length < inputInfo.Duration-keyframeEpsilon) {
+		plan.End = start + length
+	}
+
+	keyframes, err := probeKeyframes(inputFilePath, start, plan.End)
+	if err != nil {
+		return nil, err
+	}
+	if err := plan.locateKeyframes(keyframes); err != nil {
+		return nil, err
+	}
+	if plan.Source, err = probeSmartCutSource(inputFilePath); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// resolveSmartCut 智能切割时生成分段方案，无法智能切割时改为逐帧精确切割
+func resolveSmartCut(inputFilePath string, params TranscodeParams, inputInfo *VideoInfo) (TranscodeParams, *smartCutPlan, []string) {
+	if params.Trim.IsZero() || params.Trim.Mode != TrimMode_Smart {
+		return params, nil, nil
+	}
+	plan, err := planSmartCut(inputFilePath, params, inputInfo)
+	if err != nil {
+		params.Trim.Mode = TrimMode_Accurate
+		return params, nil, []string{fmt.Sprintf("无法智能切割（%v），已改为逐帧精确切割", err)}
+	}
+	return params, plan, nil
+}
+
+// locateKeyframes 在关键帧列表中找到第一个和最后一个可以直接复制的关键帧
+func (p *smartCutPlan) locateKeyframes(keyframes []float64) error {
+	first, last := -1.0, -1.0
+	for _, keyframe := range keyframes {
+		if p.End > 0 && keyframe > p.End+keyframeEpsilon {
+			continue
+		}
+		if keyframe >= p.Start-keyframeEpsilon {
+			if first < 0 || keyframe < first {
+				first = keyframe
+			}
+			last = max(last, keyframe)
+		}
+	}
+	if first < 0 {
+		return fmt.Errorf("截取范围内没有关键帧")
+	}
+	if p.End > 0 && last-first <= keyframeEpsilon {
+		return fmt.Errorf("截取范围内没有完整的GOP")
+	}
+	// 关键帧与开始时间几乎相同时直接从关键帧复制
+	p.FirstKeyframe, p.LastKeyframe = max(first, p.Start), last
+	if p.End > 0 {
+		p.LastKeyframe = min(last, p.End)
+	}
+	return nil
+}
+
+// probeKeyframes 使用ffprobe读取截取范围内视频关键帧的时间，只读取数据包不解码，速度很快
+//
+// 返回的时间已减去文件的起始时间，与 -ss 参数的时间一致
+func probeKeyframes(path string, start, end float64) ([]float64, error) {
+	ffprobePath, err := IsFFprobeAvailable()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe不可用: %v", err)
+	}
+	interval := formatSeconds(max(start-keyframeProbeMargin, 0)) + "%"
+	if end > 0 {
+		interval += formatSeconds(end + keyframeProbeMargin)
+	}
+	cmd := createCommand(ffprobePath,
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-read_intervals", interval,
+		"-show_entries", "format=start_time:packet=pts_time,flags",
+		"-of", "csv",
+		path,
+	)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("无法读取关键帧: %v", err)
+	}
+	return parseKeyframes(string(output)), nil
+}
+
+// parseKeyframes 解析ffprobe的csv输出，格式为 packet,<pts_time>,<flags> 和 format,<start_time>
+func parseKeyframes(output string) []float64 {
+	var startTime float64
+	var keyframes []float64
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), ",")
+		switch {
+		case len(fields) == 2 && fields[0] == "format":
+			startTime, _ = strconv.ParseFloat(fields[1], 64)
+		case len(fields) == 3 && fields[0] == "packet" && strings.Contains(fields[2], "K"):
+			if pts, err := strconv.ParseFloat(fields[1], 64); err == nil {
+				keyframes = append(keyframes, pts)
+			}
+		}
+	}
+	for i := range keyframes {
+		keyframes[i] -= startTime
+	}
+	return keyframes
+}
+
+// probeSmartCutSource 读取源视频的profile、level、像素格式和色彩参数
+func probeSmartCutSource(path string) (smartCutSource, error) {
+	var source smartCutSource
+	ffprobePath, err := IsFFprobeAvailable()
+	if err != nil {
+		return source, fmt.Errorf("ffprobe不可用: %v", err)
+	}
+	cmd := createCommand(ffprobePath,
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=codec_name,profile,level,pix_fmt,color_range,color_space,color_transfer,color_primaries",
+		"-of", "json",
+		path,
+	)
+	output, err := cmd.Output()
+	if err != nil {
+		return source, fmt.Errorf("无法获取视频编码参数: %v", err)
+	}
+	var data struct {
+		Streams []struct {
+			CodecName      string `json:"codec_name"`
+			Profile        string `json:"profile"`
+			Level          int    `json:"level"`
+			PixFmt         string `json:"pix_fmt"`
+			ColorRange     string `json:"color_range"`
+			ColorSpace     string `json:"color_space"`
+			ColorTransfer  string `json:"color_transfer"`
+			ColorPrimaries string `json:"color_primaries"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(output, &data); err != nil {
+		return source, fmt.Errorf("无法解析视频编码参数: %v", err)
+	}
+	if len(data.Streams) == 0 {
+		return source, fmt.Errorf("没有视频流")
+	}
+	stream := data.Streams[0]
+	source.Codec = sourceVideoCodecs[stream.CodecName]
+	source.Profile = encoderProfile(source.Codec, stream.Profile)
+	source.Level = encoderLevel(source.Codec, stream.Level)
+	source.PixelFormat = stream.PixFmt
+	source.ColorRange = knownValue(stream.ColorRange)
+	source.ColorSpace = knownValue(stream.ColorSpace)
+	source.ColorTransfer = knownValue(stream.ColorTransfer)
+	source.ColorPrimaries = knownValue(stream.ColorPrimaries)
+	return source, nil
+}
+
+// encoderProfile 将ffprobe的profile名称转换为编码器参数，AV1、VP9和无法对应的profile返回空
+func encoderProfile(codec, profile string) string {
+	name := strings.ToLower(strings.ReplaceAll(profile, " ", ""))
+	switch codec {
+	case "h264":
+		switch name {
+		case "baseline", "constrainedbaseline":
+			return "baseline"
+		case "main", "high", "high10":
+			return name
+		case "high4:2:2":
+			return "high422"
+		case "high4:4:4predictive":
+			return "high444"
+		}
+	case "h265":
+		switch name {
+		case "main", "main10", "main12":
+			return name
+		}
+	}
+	return ""
+}
+
+// encoderLevel 将ffprobe的level转换为编码器参数，H.264为level×10，H.265为level×30
+func encoderLevel(codec string, level int) string {
+	if level <= 0 {
+		return ""
+	}
+	switch codec {
+	case "h264":
+		return strconv.FormatFloat(float64(level)/10, 'f', 1, 64)
+	case "h265":
+		return strconv.FormatFloat(float64(level)/30, 'f', 1, 64)
+	}
+	return ""
+}
+
+// knownValue ffprobe无法确定时输出 unknown，按未设置处理
+func knownValue(value string) string {
+	if value == "unknown" {
+		return ""
+	}
+	return value
+}
+
+// smartCutPrefix 获取任务的智能切割片段文件前缀，放在系统临时目录中
+func smartCutPrefix(id string) string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("cm_video_batch_process-%s-smartcut", id))
+}
+
+// smartCutSegmentExt 中间片段的封装格式，H.264/H.265使用MPEG-TS，参数集随数据保存，拼接时不依赖文件头
+func smartCutSegmentExt(codec string) string {
+	if codec == "h264" || codec == "h265" {
+		return ".ts"
+	}
+	return ".mkv"
+}
+
+// smartCutEncodeParams 边界片段的编码参数，与源视频的编码、profile、level和像素格式一致
+func smartCutEncodeParams(source smartCutSource, params TranscodeParams) TranscodeParams {
+	return TranscodeParams{
+		VideoCodec:    source.Codec,
+		CpuThreads:    params.CpuThreads,
+		RateControl:   RateControl_CRF,
+		EncoderPreset: params.EncoderPreset,
+		Profile:       source.Profile,
+		Level:         source.Level,
+		PixelFormat:   source.PixelFormat,
+	}
+}
+
+// smartCutColorArgs 边界片段写入与源视频相同的色彩参数
+func smartCutColorArgs(source smartCutSource) []string {
+	var args []string
+	for _, option := range []struct{ name, value string }{
+		{"-color_range", source.ColorRange},
+		{"-colorspace", source.ColorSpace},
+		{"-color_trc", source.ColorTransfer},
+		{"-color_primaries", source.ColorPrimaries},
+	} {
+		if option.value != "" {
+			args = append(args, option.name, option.value)
+		}
+	}
+	return args
+}
+
+// buildSmartCutSteps 生成智能切割的FFmpeg步骤：重新编码开头片段、复制中间片段、重新编码结尾片段，最后拼接并加入音频
+//
+// 参数:
+//
+//	boundaryEncoder: 边界片段使用的软件编码器，与源视频的编码相同
+//	prefix: 片段文件的路径前缀
+//	duration: 截取后的时长，用于计算拼接步骤的进度
+//
+// 返回值:
+//
+//	[]transcodeStep: 依次执行的步骤
+//	[]string: 需要写入拼接列表的片段文件
+//	[]string: 边界片段编码器忽略的参数
+func buildSmartCutSteps(inputFilePath string, inputInfo *VideoInfo, outputFilePath string, params TranscodeParams, encoders encoderSelection, boundaryEncoder string, plan smartCutPlan, prefix string, duration float64) ([]transcodeStep, []string, []string) {
+	ext := smartCutSegmentExt(plan.Source.Codec)
+	boundaryParams := smartCutEncodeParams(plan.Source, params)
+	boundaryEncoders := encoderSelection{Video: boundaryEncoder}
+	quality := max(defaultQuality(videoEncoderFamily(boundaryEncoders))-smartCutQualityOffset, 1)
+	boundaryParams.Quality = &quality
+	encodeArgs, ignored := videoEncodeArgs(boundaryParams, boundaryEncoders, encodePass{})
+	encodeArgs = append(encodeArgs, smartCutColorArgs(plan.Source)...)
+
+	var steps []transcodeStep
+	var segments []string
+	// segment 截取 [start, start+length) 的视频，length为0表示到结尾
+	segment := func(name string, start, length float64, videoArgs []string) {
+		segmentPath := fmt.Sprintf("%s-%d%s", prefix, len(segments), ext)
+		args := []string{"-y", "-ss", exactSeconds(start), "-i", inputFilePath}
+		if length > 0 {
+			args = append(args, "-t", exactSeconds(length))
+		}
+		if params.CpuThreads > 0 {
+			args = append(args, "-threads", strconv.Itoa(params.CpuThreads))
+		}
+		args = append(args, "-map", "0:v:0", "-an", "-sn", "-dn")
+		args = append(args, videoArgs...)
+		args = append(args, "-progress", "pipe:2", "-nostats", segmentPath)
+
+		stepDuration := length
+		if length == 0 {
+			stepDuration = max(duration-(start-plan.Start), 0)
+		}
+		steps = append(steps, transcodeStep{Name: name, Args: args, Duration: stepDuration})
+		segments = append(segments, segmentPath)
+	}
+
+	if plan.hasHead() {
+		segment("编码开头片段", plan.Start, plan.FirstKeyframe-plan.Start, append([]string{"-c:v", boundaryEncoder}, encodeArgs...))
+	}
+	copyArgs := []string{"-c:v", "copy", "-avoid_negative_ts", "make_zero"}
+	if plan.hasTail() {
+		segment("复制中间片段", plan.FirstKeyframe, plan.LastKeyframe-plan.FirstKeyframe, copyArgs)
+		segment("编码结尾片段", plan.LastKeyframe, plan.End-plan.LastKeyframe, append([]string{"-c:v", boundaryEncoder}, encodeArgs...))
+	} else if plan.End > 0 {
+		segment("复制中间片段", plan.FirstKeyframe, plan.End-plan.FirstKeyframe, copyArgs)
+	} else {
+		segment("复制中间片段", plan.FirstKeyframe, 0, copyArgs)
+	}
+
+	// 拼接视频片段，音频从源视频的截取范围中读取
+	args := []string{"-y", "-f", "concat", "-safe", "0", "-i", smartCutListPath(prefix), "-ss", exactSeconds(plan.Start)}
+	if plan.End > 0 {
+		args = append(args, "-t", exactSeconds(plan.End-plan.Start))
+	}
+	args = append(args, "-i", inputFilePath, "-map", "0:v:0", "-map", "1:a?", "-c:v", "copy")
+	args = append(args, audioOutputArgs(params, encoders, encodePass{})...)
+	if format, ok := getContainerFormat(params.Container); ok {
+		args = append(args, format.muxArgs(encoders, inputInfo)...)
+	}
+	args = append(args, "-progress", "pipe:2", "-nostats", outputFilePath)
+	steps = append(steps, transcodeStep{Name: "合并片段", Args: args, Duration: duration})
+	return steps, segments, ignored
+}
+
+// smartCutListPath 拼接列表文件的路径
+func smartCutListPath(prefix string) string {
+	return prefix + "-list.txt"
+}
+
+// writeConcatList 写入concat分离器的文件列表，路径中的单引号需要转义
+func writeConcatList(listPath string, segments []string) error {
+	var builder strings.Builder
+	for _, segment := range segments {
+		builder.WriteString("file '" + strings.ReplaceAll(filepath.ToSlash(segment), "'", `'\''`) + "'\n")
+	}
+	return os.WriteFile(listPath, []byte(builder.String()), 0644)
+}
+
+// exactSeconds 格式化关键帧时间，精确到FFmpeg内部的微秒，不能像 formatSeconds 那样舍入到毫秒，否则可能跳到前一个关键帧
+func exactSeconds(seconds float64) string {
+	value := strings.TrimRight(strconv.FormatFloat(seconds, 'f', 6, 64), "0")
+	if strings.HasSuffix(value, ".") {
+		value += "0"
+	}
+	return value
+}
@@ -0,0 +1,96 @@
+package process
+
+import (
+	"math"
+	"testing"
+)
+
+func floatsAlmostEqual(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestParseKeyframes(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []float64
+	}{
+		{name: "空输出", output: "", want: nil},
+		{
+			name:   "只保留关键帧",
+			output: "packet,0.000000,K_\npacket,0.040000,__\npacket,2.000000,K_\npacket,2.040000,__\nformat,0.000000\n",
+			want:   []float64{0, 2},
+		},
+		{
+			name:   "减去文件的起始时间",
+			output: "packet,1.400000,K_\npacket,3.400000,K_\nformat,1.400000\n",
+			want:   []float64{0, 2},
+		},
+		{
+			name:   "Windows换行和丢弃的数据包",
+			output: "packet,0.000000,K_\r\npacket,5.000000,KD\r\npacket,N/A,K_\r\nformat,0.000000\r\n",
+			want:   []float64{0, 5},
+		},
+		{
+			name:   "没有起始时间",
+			output: "packet,10.000000,K_\npacket,20.000000,K_\n",
+			want:   []float64{10, 20},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseKeyframes(tt.output); !floatsAlmostEqual(got, tt.want) {
+				t.Errorf("parseKeyframes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSmartCutPlanLocateKeyframes(t *testing.T) {
+	keyframes := []float64{0, 2, 4, 6, 8, 10}
+	tests := []struct {
+		name      string
+		start     float64
+		end       float64
+		keyframes []float64
+		wantFirst float64
+		wantLast  float64
+		wantHead  bool
+		wantTail  bool
+		wantErr   bool
+	}{
+		{name: "开头和结尾都不在关键帧上", start: 1, end: 7, keyframes: keyframes, wantFirst: 2, wantLast: 6, wantHead: true, wantTail: true},
+		{name: "从关键帧开始", start: 2, end: 7, keyframes: keyframes, wantFirst: 2, wantLast: 6, wantTail: true},
+		{name: "开始时间略早于关键帧", start: 1.9995, end: 8, keyframes: keyframes, wantFirst: 2, wantLast: 8},
+		{name: "截取到结尾", start: 3, keyframes: keyframes, wantFirst: 4, wantLast: 10, wantHead: true},
+		{name: "关键帧无序", start: 1, end: 9, keyframes: []float64{8, 2, 6, 4}, wantFirst: 2, wantLast: 8, wantHead: true, wantTail: true},
+		{name: "范围内没有关键帧", start: 10.5, keyframes: keyframes, wantErr: true},
+		{name: "范围内没有完整的GOP", start: 1, end: 3, keyframes: keyframes, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := smartCutPlan{Start: tt.start, End: tt.end}
+			err := plan.locateKeyframes(tt.keyframes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("locateKeyframes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if plan.FirstKeyframe != tt.wantFirst || plan.LastKeyframe != tt.wantLast {
+				t.Errorf("关键帧 = %v, %v, want %v, %v", plan.FirstKeyframe, plan.LastKeyframe, tt.wantFirst, tt.wantLast)
+			}
+			if plan.hasHead() != tt.wantHead || plan.hasTail() != tt.wantTail {
+				t.Errorf("hasHead = %v, hasTail = %v, want %v, %v", plan.hasHead(), plan.hasTail(), tt.wantHead, tt.wantTail)
+			}
+		})
+	}
+}
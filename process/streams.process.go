@@ -0,0 +1,442 @@
+package process
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type AudioTrackMode string
+
+const (
+	AudioTrackMode_Default  AudioTrackMode = ""         // 只保留一个音轨，优先使用默认音轨
+	AudioTrackMode_All      AudioTrackMode = "all"      // 保留所有音轨
+	AudioTrackMode_Language AudioTrackMode = "language" // 只保留指定语言的音轨，按语言的顺序排列
+)
+
+// StreamMapping 输出哪些流，为空时使用FFmpeg默认的选择（一个视频流和一个音频流）
+type StreamMapping struct {
+	AudioTracks     AudioTrackMode `yaml:"audioTracks" json:"audio_tracks"`         // 音轨选择方式: all、language，为空时只保留一个音轨
+	Languages       []string       `yaml:"languages,omitempty" json:"languages"`    // 保留的语言，如 chi、eng，不为空时字幕也只保留这些语言
+	DefaultLanguage string         `yaml:"defaultLanguage" json:"default_language"` // 标记为默认音轨的语言，只保留一个音轨时选择该语言的音轨
+	Subtitles       bool           `yaml:"subtitles" json:"subtitles"`              // 保留字幕，输出格式不支持的文字字幕会转换格式
+	Attachments     bool           `yaml:"attachments" json:"attachments"`          // 保留附件（如ASS字幕使用的字体），只有MKV支持
+}
+
+// IsZero 是否未设置流的选择
+func (m StreamMapping) IsZero() bool {
+	return m.AudioTracks == AudioTrackMode_Default && len(m.Languages) == 0 && m.DefaultLanguage == "" && !m.Subtitles && !m.Attachments
+}
+
+// languageAliases 常用的两字母语言代码和 ISO 639-2/T 代码对应的 ISO 639-2/B 代码，MKV和MP4的语言标签通常使用后者
+var languageAliases = map[string]string{
+	"zh":  "chi",
+	"zho": "chi",
+	"en":  "eng",
+	"ja":  "jpn",
+	"ko":  "kor",
+	"fr":  "fre",
+	"fra": "fre",
+	"de":  "ger",
+	"deu": "ger",
+	"es":  "spa",
+	"ru":  "rus",
+	"it":  "ita",
+	"pt":  "por",
+}
+
+// normalizeLanguage 统一语言代码的写法，便于比较 zh、zho 和 chi
+func normalizeLanguage(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if alias, ok := languageAliases[language]; ok {
+		return alias
+	}
+	return language
+}
+
+// isLanguageCode 判断是否为两位或三位字母的语言代码
+func isLanguageCode(language string) bool {
+	if len(language) < 2 || len(language) > 3 {
+		return false
+	}
+	for _, c := range language {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// validateStreamMapping 校验流的选择
+func validateStreamMapping(mapping StreamMapping) error {
+	switch mapping.AudioTracks {
+	case AudioTrackMode_Default, AudioTrackMode_All:
+	case AudioTrackMode_Language:
+		if len(mapping.Languages) == 0 {
+			return fmt.Errorf("按语言选择音轨时需要指定语言")
+		}
+	default:
+		return fmt.Errorf("无效的音轨选择方式: %s，可用: all、language", mapping.AudioTracks)
+	}
+	for _, language := range append([]string{mapping.DefaultLanguage}, mapping.Languages...) {
+		if language != "" && !isLanguageCode(strings.TrimSpace(language)) {
+			return fmt.Errorf("无效的语言代码: %s，应为两位或三位字母，如 zh、chi、eng", language)
+		}
+	}
+	return nil
+}
+
+// selectedSubtitle 输出的字幕流
+type selectedSubtitle struct {
+	StreamInfo
+	Encoder string         // copy 或转换成的字幕编码
+	Input   int            // 所在的输入文件，0为主输入文件，外部字幕从1开始
+	Track   *SubtitleTrack // 外部字幕的设置，内嵌字幕为nil
+}
+
+// streamSelection 按映射规则选择的输出流
+type streamSelection struct {
+	Video        *StreamInfo
+	Audio        []StreamInfo
+	DefaultAudio int // Audio 中标记为默认的音轨，为-1时保留源文件的标记
+	Subtitles    []selectedSubtitle
+	Attachments  bool
+}
+
+// textSubtitleCodecs 可以转换格式的文字字幕，图形字幕只能直接复制
+var textSubtitleCodecs = []string{"subrip", "ass", "ssa", "webvtt", "mov_text", "text"}
+
+// streamsOfType 获取指定类型的流，封面图片不算视频流
+func streamsOfType(inputInfo *VideoInfo, streamType string) []StreamInfo {
+	var streams []StreamInfo
+	for _, stream := range inputInfo.Streams {
+		if stream.Type == streamType && !stream.AttachedPic {
+			streams = append(streams, stream)
+		}
+	}
+	return streams
+}
+
+// selectStreams 按映射规则从输入文件的流中选择输出的流，format 为实际输出的封装格式，hasFormat 为false时不检查兼容性
+//
+// 返回值:
+//
+//	streamSelection: 选择的流
+//	[]string: 没有找到指定语言或输出格式不支持时的说明
+func selectStreams(params TranscodeParams, inputInfo *VideoInfo, format containerFormat, hasFormat bool) (streamSelection, []string) {
+	mapping := params.Streams
+	selection := streamSelection{DefaultAudio: -1}
+	var warnings []string
+	name := strings.ToUpper(string(format.Name))
+
+	if !hasFormat || !format.AudioOnly {
+		if videos := streamsOfType(inputInfo, "video"); len(videos) > 0 {
+			selection.Video = &videos[0]
+		}
+	}
+
+	audios := streamsOfType(inputInfo, "audio")
+	defaultLanguage := normalizeLanguage(mapping.DefaultLanguage)
+	if !params.MuteAudio && len(audios) > 0 {
+		switch mapping.AudioTracks {
+		case AudioTrackMode_All:
+			selection.Audio = audios
+		case AudioTrackMode_Language:
+			// zh 和 chi 等别名是同一种语言，同一个音轨只保留一次
+			selected := map[int]bool{}
+			for _, language := range mapping.Languages {
+				for _, audio := range audios {
+					if !selected[audio.Index] && normalizeLanguage(audio.Language) == normalizeLanguage(language) {
+						selected[audio.Index] = true
+						selection.Audio = append(selection.Audio, audio)
+					}
+				}
+			}
+			if len(selection.Audio) == 0 {
+				warnings = append(warnings, fmt.Sprintf("没有语言为 %s 的音轨，已保留默认音轨", strings.Join(mapping.Languages, "、")))
+			} else if defaultLanguage == "" {
+				// 第一个语言优先
+				selection.DefaultAudio = 0
+			}
+		}
+		if len(selection.Audio) == 0 {
+			selection.Audio = []StreamInfo{defaultAudioStream(audios, defaultLanguage)}
+		}
+		if defaultLanguage != "" {
+			for i, audio := range selection.Audio {
+				if normalizeLanguage(audio.Language) == defaultLanguage {
+					selection.DefaultAudio = i
+					break
+				}
+			}
+			if selection.DefaultAudio < 0 {
+				warnings = append(warnings, fmt.Sprintf("没有语言为 %s 的音轨，默认音轨保持不变", mapping.DefaultLanguage))
+			}
+		}
+	}
+
+	if mapping.Subtitles {
+		var subtitles []StreamInfo
+		for _, subtitle := range streamsOfType(inputInfo, "subtitle") {
+			if len(mapping.Languages) == 0 || containsLanguage(mapping.Languages, subtitle.Language) {
+				subtitles = append(subtitles, subtitle)
+			}
+		}
+		switch {
+		case len(subtitles) == 0:
+		case !hasFormat:
+			for _, subtitle := range subtitles {
+				selection.Subtitles = append(selection.Subtitles, selectedSubtitle{StreamInfo: subtitle, Encoder: "copy"})
+			}
+		case format.SubtitleCodecs == nil:
+			warnings = append(warnings, fmt.Sprintf("%s 不支持字幕，已忽略字幕", name))
+		default:
+			for _, subtitle := range subtitles {
+				switch {
+				case containsString(format.SubtitleCodecs, subtitle.Codec):
+					selection.Subtitles = append(selection.Subtitles, selectedSubtitle{StreamInfo: subtitle, Encoder: "copy"})
+				case format.DefaultSubtitle != "" && containsString(textSubtitleCodecs, subtitle.Codec):
+					selection.Subtitles = append(selection.Subtitles, selectedSubtitle{StreamInfo: subtitle, Encoder: format.DefaultSubtitle})
+				default:
+					warnings = append(warnings, fmt.Sprintf("%s 不支持 %s 字幕，已忽略字幕流 #%d", name, subtitle.Codec, subtitle.Index))
+				}
+			}
+		}
+	}
+
+	externals, externalWarnings := selectExternalSubtitles(params, format, hasFormat)
+	selection.Subtitles = append(selection.Subtitles, externals...)
+	warnings = append(warnings, externalWarnings...)
+
+	if mapping.Attachments && len(streamsOfType(inputInfo, "attachment")) > 0 {
+		if hasFormat && !format.Attachments {
+			warnings = append(warnings, fmt.Sprintf("%s 不支持附件，已忽略附件", name))
+		} else {
+			selection.Attachments = true
+		}
+	}
+	return selection, warnings
+}
+
+// selectExternalSubtitles 选择封装的外部字幕，排在内嵌字幕之后，输出格式不支持时转换为默认的字幕编码
+func selectExternalSubtitles(params TranscodeParams, format containerFormat, hasFormat bool) ([]selectedSubtitle, []string) {
+	var subtitles []selectedSubtitle
+	var warnings []string
+	name := strings.ToUpper(string(format.Name))
+	for i := range params.SoftSubtitles.Tracks {
+		track := &params.SoftSubtitles.Tracks[i]
+		subtitle := selectedSubtitle{
+			StreamInfo: StreamInfo{Type: "subtitle", Codec: subtitleFileCodecs[FileExt(track.File)], Language: track.Language, Title: track.Title, Default: track.Default},
+			Encoder:    "copy",
+			Input:      i + 1,
+			Track:      track,
+		}
+		switch {
+		case !hasFormat || containsString(format.SubtitleCodecs, subtitle.Codec):
+		case format.SubtitleCodecs == nil:
+			warnings = append(warnings, fmt.Sprintf("%s 不支持字幕，已忽略字幕文件 %s", name, filepath.Base(track.File)))
+			continue
+		case format.DefaultSubtitle != "":
+			subtitle.Encoder = format.DefaultSubtitle
+		default:
+			warnings = append(warnings, fmt.Sprintf("%s 不支持 %s 字幕，已忽略字幕文件 %s", name, subtitle.Codec, filepath.Base(track.File)))
+			continue
+		}
+		subtitles = append(subtitles, subtitle)
+	}
+	return subtitles, warnings
+}
+
+// externalTracks 获取选择的外部字幕，输出格式不支持的字幕不作为输入文件
+func externalTracks(subtitles []selectedSubtitle) []SubtitleTrack {
+	var tracks []SubtitleTrack
+	for _, subtitle := range subtitles {
+		if subtitle.Track != nil {
+			tracks = append(tracks, *subtitle.Track)
+		}
+	}
+	return tracks
+}
+
+// defaultAudioStream 只保留一个音轨时选择的音轨：指定语言的音轨、源文件标记的默认音轨、第一个音轨
+func defaultAudioStream(audios []StreamInfo, language string) StreamInfo {
+	if language != "" {
+		for _, audio := range audios {
+			if normalizeLanguage(audio.Language) == language {
+				return audio
+			}
+		}
+	}
+	for _, audio := range audios {
+		if audio.Default {
+			return audio
+		}
+	}
+	return audios[0]
+}
+
+func containsLanguage(languages []string, language string) bool {
+	for _, value := range languages {
+		if normalizeLanguage(value) == normalizeLanguage(language) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveStreamParams 在启动FFmpeg前检查流的选择，需要在确定输出格式和音频参数之后调用
+//
+// 无法获取流信息时使用FFmpeg默认的选择；直接复制的音轨中有输出格式不支持的编码时改为重新编码
+//
+// 返回值:
+//
+//	TranscodeParams: 实际使用的参数
+//	[]string: 自动修改参数的说明
+//	error: 选择的流无法处理时返回错误
+func resolveStreamParams(inputFilePath string, params TranscodeParams, inputInfo *VideoInfo) (TranscodeParams, []string, error) {
+	if params.Streams.IsZero() && len(params.SoftSubtitles.Tracks) == 0 {
+		return params, nil, nil
+	}
+	format, hasFormat := outputContainerFormat(params.Container, inputFilePath)
+	if inputInfo == nil || len(inputInfo.Streams) == 0 {
+		// 外部字幕仍然封装，主输入文件使用默认的视频和音频
+		externals, warnings := selectExternalSubtitles(params, format, hasFormat)
+		params.SoftSubtitles.Tracks = externalTracks(externals)
+		if !params.Streams.IsZero() {
+			params.Streams = StreamMapping{}
+			warnings = append(warnings, "无法获取输入文件的流信息，已使用默认的音轨和字幕选择")
+		}
+		return params, warnings, nil
+	}
+	selection, warnings := selectStreams(params, inputInfo, format, hasFormat)
+	params.SoftSubtitles.Tracks = externalTracks(selection.Subtitles)
+	if params.Loudness.Enabled && len(selection.Audio) > 1 {
+		return params, nil, fmt.Errorf("响度标准化只支持一个音轨，当前选择了 %d 个音轨", len(selection.Audio))
+	}
+	if params.AudioCodec == "copy" && hasFormat {
+		for _, audio := range selection.Audio {
+			if !supportsCodec(format.AudioCodecs, audio.Codec) {
+				params.AudioCodec = format.DefaultAudio
+				warnings = append(warnings, fmt.Sprintf("%s 不支持音轨 #%d 的 %s 音频，已改为 %s 编码", strings.ToUpper(string(format.Name)), audio.Index, audio.Codec, format.DefaultAudio))
+				break
+			}
+		}
+	}
+	return params, warnings, nil
+}
+
+// streamMapArgs 生成选择流的FFmpeg参数，未设置流的选择且没有外部字幕时不生成，使用FFmpeg默认的选择
+//
+// 没有流信息时只封装外部字幕，主输入文件选择第一个视频流和音频流；两遍编码的第一遍只需要视频流
+func streamMapArgs(inputFilePath string, params TranscodeParams, inputInfo *VideoInfo, pass encodePass) []string {
+	hasInfo := inputInfo != nil && len(inputInfo.Streams) > 0
+	if len(params.SoftSubtitles.Tracks) == 0 && (params.Streams.IsZero() || !hasInfo) {
+		return nil
+	}
+	format, hasFormat := outputContainerFormat(params.Container, inputFilePath)
+	var selection streamSelection
+	if hasInfo {
+		selection, _ = selectStreams(params, inputInfo, format, hasFormat)
+	} else {
+		selection = streamSelection{DefaultAudio: -1}
+		selection.Subtitles, _ = selectExternalSubtitles(params, format, hasFormat)
+	}
+
+	var args []string
+	mapStream := func(stream StreamInfo) {
+		args = append(args, "-map", "0:"+strconv.Itoa(stream.Index))
+	}
+	switch {
+	case selection.Video != nil:
+		mapStream(*selection.Video)
+	case !hasInfo && !(hasFormat && format.AudioOnly):
+		args = append(args, "-map", "0:v:0?")
+	}
+	if pass.Number == 1 {
+		return args
+	}
+	for _, audio := range selection.Audio {
+		mapStream(audio)
+	}
+	if !hasInfo && !params.MuteAudio {
+		args = append(args, "-map", "0:a:0?")
+	}
+	for _, subtitle := range selection.Subtitles {
+		if subtitle.Track != nil {
+			args = append(args, "-map", strconv.Itoa(subtitle.Input)+":0")
+		} else {
+			mapStream(subtitle.StreamInfo)
+		}
+	}
+	if selection.Attachments {
+		args = append(args, "-map", "0:t?", "-c:t", "copy")
+	}
+
+	// 全部直接复制时统一设置，否则逐个设置字幕编码
+	converted := false
+	for _, subtitle := range selection.Subtitles {
+		converted = converted || subtitle.Encoder != "copy"
+	}
+	if converted {
+		for i, subtitle := range selection.Subtitles {
+			args = append(args, fmt.Sprintf("-c:s:%d", i), subtitle.Encoder)
+		}
+	} else if len(selection.Subtitles) > 0 {
+		args = append(args, "-c:s", "copy")
+	}
+
+	args = append(args, subtitleTrackArgs(selection.Subtitles)...)
+
+	if selection.DefaultAudio >= 0 {
+		for i := range selection.Audio {
+			disposition := "0"
+			if i == selection.DefaultAudio {
+				disposition = "default"
+			}
+			args = append(args, fmt.Sprintf("-disposition:a:%d", i), disposition)
+		}
+	}
+	return args
+}
+
+// subtitleTrackArgs 外部字幕的语言和标题标签，有外部字幕标记为默认时其它字幕都取消默认标记
+func subtitleTrackArgs(subtitles []selectedSubtitle) []string {
+	var args []string
+	hasDefault := false
+	for i, subtitle := range subtitles {
+		if subtitle.Track == nil {
+			continue
+		}
+		if subtitle.Track.Language != "" {
+			args = append(args, fmt.Sprintf("-metadata:s:s:%d", i), "language="+normalizeLanguage(subtitle.Track.Language))
+		}
+		if subtitle.Track.Title != "" {
+			args = append(args, fmt.Sprintf("-metadata:s:s:%d", i), "title="+subtitle.Track.Title)
+		}
+		hasDefault = hasDefault || subtitle.Track.Default
+	}
+	if hasDefault {
+		for i, subtitle := range subtitles {
+			disposition := "0"
+			if subtitle.Track != nil && subtitle.Track.Default {
+				disposition = "default"
+			}
+			args = append(args, fmt.Sprintf("-disposition:s:%d", i), disposition)
+		}
+	}
+	return args
+}
+
+// firstAudioMap 第一个输出音轨的 -map 参数，用于只处理一个音轨的分析命令
+func firstAudioMap(inputFilePath string, params TranscodeParams, inputInfo *VideoInfo) string {
+	if (params.Streams.IsZero() && len(params.SoftSubtitles.Tracks) == 0) || inputInfo == nil || len(inputInfo.Streams) == 0 {
+		return "0:a:0"
+	}
+	format, hasFormat := outputContainerFormat(params.Container, inputFilePath)
+	selection, _ := selectStreams(params, inputInfo, format, hasFormat)
+	if len(selection.Audio) == 0 {
+		return "0:a:0"
+	}
+	return "0:" + strconv.Itoa(selection.Audio[0].Index)
+}
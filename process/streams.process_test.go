@@ -0,0 +1,232 @@
+package process
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestSelectStreams(t *testing.T) {
+	inputInfo := &VideoInfo{Streams: []StreamInfo{
+		{Index: 0, Type: "video", Codec: "h264"},
+		{Index: 1, Type: "audio", Codec: "aac", Language: "jpn", Default: true},
+		{Index: 2, Type: "audio", Codec: "aac", Language: "eng"},
+		{Index: 3, Type: "audio", Codec: "ac3", Language: "chi"},
+		{Index: 4, Type: "subtitle", Codec: "subrip", Language: "eng"},
+		{Index: 5, Type: "subtitle", Codec: "hdmv_pgs_subtitle", Language: "chi"},
+		{Index: 6, Type: "subtitle", Codec: "ass", Language: "jpn"},
+		{Index: 7, Type: "attachment", Codec: "ttf"},
+		{Index: 8, Type: "video", Codec: "mjpeg", AttachedPic: true},
+	}}
+	mkv, _ := getContainerFormat(OutputContainer_MKV)
+	mp4, _ := getContainerFormat(OutputContainer_MP4)
+	mp3, _ := getContainerFormat(OutputContainer_MP3)
+
+	tests := []struct {
+		name         string
+		params       TranscodeParams
+		format       containerFormat
+		hasFormat    bool
+		wantVideo    int // 视频流序号，-1表示没有视频
+		wantAudio    []int
+		wantDefault  int
+		wantSubs     []string // 序号:编码器，外部字幕为 输入文件:编码器
+		wantAttach   bool
+		wantWarnings int
+	}{
+		{
+			name:        "默认只保留默认音轨",
+			format:      mkv,
+			hasFormat:   true,
+			wantVideo:   0,
+			wantAudio:   []int{1},
+			wantDefault: -1,
+		},
+		{
+			name:        "指定默认音轨的语言",
+			params:      TranscodeParams{Streams: StreamMapping{DefaultLanguage: "en"}},
+			format:      mkv,
+			hasFormat:   true,
+			wantVideo:   0,
+			wantAudio:   []int{2},
+			wantDefault: 0,
+		},
+		{
+			name:        "保留所有音轨",
+			params:      TranscodeParams{Streams: StreamMapping{AudioTracks: AudioTrackMode_All}},
+			format:      mkv,
+			hasFormat:   true,
+			wantVideo:   0,
+			wantAudio:   []int{1, 2, 3},
+			wantDefault: -1,
+		},
+		{
+			name:        "按语言的顺序选择音轨",
+			params:      TranscodeParams{Streams: StreamMapping{AudioTracks: AudioTrackMode_Language, Languages: []string{"zh", "eng"}}},
+			format:      mkv,
+			hasFormat:   true,
+			wantVideo:   0,
+			wantAudio:   []int{3, 2},
+			wantDefault: 0,
+		},
+		{
+			name:        "同一种语言的别名只选择一次",
+			params:      TranscodeParams{Streams: StreamMapping{AudioTracks: AudioTrackMode_Language, Languages: []string{"zh", "chi", "en", "eng"}}},
+			format:      mkv,
+			hasFormat:   true,
+			wantVideo:   0,
+			wantAudio:   []int{3, 2},
+			wantDefault: 0,
+		},
+		{
+			name:         "没有指定语言的音轨时保留默认音轨",
+			params:       TranscodeParams{Streams: StreamMapping{AudioTracks: AudioTrackMode_Language, Languages: []string{"fr"}}},
+			format:       mkv,
+			hasFormat:    true,
+			wantVideo:    0,
+			wantAudio:    []int{1},
+			wantDefault:  -1,
+			wantWarnings: 1,
+		},
+		{
+			name:         "没有默认语言的音轨",
+			params:       TranscodeParams{Streams: StreamMapping{AudioTracks: AudioTrackMode_All, DefaultLanguage: "kor"}},
+			format:       mkv,
+			hasFormat:    true,
+			wantVideo:    0,
+			wantAudio:    []int{1, 2, 3},
+			wantDefault:  -1,
+			wantWarnings: 1,
+		},
+		{
+			name:        "静音",
+			params:      TranscodeParams{MuteAudio: true, Streams: StreamMapping{AudioTracks: AudioTrackMode_All}},
+			format:      mkv,
+			hasFormat:   true,
+			wantVideo:   0,
+			wantDefault: -1,
+		},
+		{
+			name:        "MKV直接复制字幕",
+			params:      TranscodeParams{Streams: StreamMapping{Subtitles: true}},
+			format:      mkv,
+			hasFormat:   true,
+			wantVideo:   0,
+			wantAudio:   []int{1},
+			wantDefault: -1,
+			wantSubs:    []string{"4:copy", "5:copy", "6:copy"},
+		},
+		{
+			name:         "MP4转换文字字幕并忽略图形字幕",
+			params:       TranscodeParams{Streams: StreamMapping{Subtitles: true}},
+			format:       mp4,
+			hasFormat:    true,
+			wantVideo:    0,
+			wantAudio:    []int{1},
+			wantDefault:  -1,
+			wantSubs:     []string{"4:mov_text", "6:mov_text"},
+			wantWarnings: 1,
+		},
+		{
+			name:        "只保留指定语言的字幕",
+			params:      TranscodeParams{Streams: StreamMapping{Subtitles: true, Languages: []string{"en"}}},
+			format:      mkv,
+			hasFormat:   true,
+			wantVideo:   0,
+			wantAudio:   []int{1},
+			wantDefault: -1,
+			wantSubs:    []string{"4:copy"},
+		},
+		{
+			name:         "只输出音频的格式不支持字幕",
+			params:       TranscodeParams{Streams: StreamMapping{Subtitles: true}},
+			format:       mp3,
+			hasFormat:    true,
+			wantVideo:    -1,
+			wantAudio:    []int{1},
+			wantDefault:  -1,
+			wantWarnings: 1,
+		},
+		{
+			name:        "未知的输出格式不检查兼容性",
+			params:      TranscodeParams{Streams: StreamMapping{Subtitles: true, Attachments: true}},
+			wantVideo:   0,
+			wantAudio:   []int{1},
+			wantDefault: -1,
+			wantSubs:    []string{"4:copy", "5:copy", "6:copy"},
+			wantAttach:  true,
+		},
+		{
+			name:        "MKV保留附件",
+			params:      TranscodeParams{Streams: StreamMapping{Attachments: true}},
+			format:      mkv,
+			hasFormat:   true,
+			wantVideo:   0,
+			wantAudio:   []int{1},
+			wantDefault: -1,
+			wantAttach:  true,
+		},
+		{
+			name:         "MP4不支持附件",
+			params:       TranscodeParams{Streams: StreamMapping{Attachments: true}},
+			format:       mp4,
+			hasFormat:    true,
+			wantVideo:    0,
+			wantAudio:    []int{1},
+			wantDefault:  -1,
+			wantWarnings: 1,
+		},
+		{
+			name: "外部字幕排在内嵌字幕之后",
+			params: TranscodeParams{
+				Streams:       StreamMapping{Subtitles: true, Languages: []string{"eng"}},
+				SoftSubtitles: SoftSubtitleOptions{Tracks: []SubtitleTrack{{File: "a.srt", Language: "chi"}, {File: "b.ass"}}},
+			},
+			format:      mp4,
+			hasFormat:   true,
+			wantVideo:   0,
+			wantAudio:   []int{1},
+			wantDefault: -1,
+			wantSubs:    []string{"4:mov_text", "input1:mov_text", "input2:mov_text"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selection, warnings := selectStreams(tt.params, inputInfo, tt.format, tt.hasFormat)
+			video := -1
+			if selection.Video != nil {
+				video = selection.Video.Index
+			}
+			if video != tt.wantVideo {
+				t.Errorf("视频流 = %d, want %d", video, tt.wantVideo)
+			}
+			var audio []int
+			for _, stream := range selection.Audio {
+				audio = append(audio, stream.Index)
+			}
+			if !reflect.DeepEqual(audio, tt.wantAudio) {
+				t.Errorf("音轨 = %v, want %v", audio, tt.wantAudio)
+			}
+			if selection.DefaultAudio != tt.wantDefault {
+				t.Errorf("默认音轨 = %d, want %d", selection.DefaultAudio, tt.wantDefault)
+			}
+			var subs []string
+			for _, subtitle := range selection.Subtitles {
+				source := strconv.Itoa(subtitle.Index)
+				if subtitle.Track != nil {
+					source = "input" + strconv.Itoa(subtitle.Input)
+				}
+				subs = append(subs, source+":"+subtitle.Encoder)
+			}
+			if !reflect.DeepEqual(subs, tt.wantSubs) {
+				t.Errorf("字幕 = %v, want %v", subs, tt.wantSubs)
+			}
+			if selection.Attachments != tt.wantAttach {
+				t.Errorf("附件 = %v, want %v", selection.Attachments, tt.wantAttach)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("警告 = %q, want %d 条", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
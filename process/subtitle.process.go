@@ -0,0 +1,347 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type SubtitleSource string
+
+const (
+	SubtitleSource_None    SubtitleSource = ""        // 不烧录字幕
+	SubtitleSource_File    SubtitleSource = "file"    // 指定的字幕文件
+	SubtitleSource_Sidecar SubtitleSource = "sidecar" // 输入文件旁边同名的字幕文件
+	SubtitleSource_Stream  SubtitleSource = "stream"  // 输入文件内嵌的字幕流
+)
+
+type SubtitlePosition string
+
+const (
+	SubtitlePosition_Bottom SubtitlePosition = ""       // 底部居中
+	SubtitlePosition_Middle SubtitlePosition = "middle" // 画面中间
+	SubtitlePosition_Top    SubtitlePosition = "top"    // 顶部居中
+)
+
+// subtitleAlignments 字幕位置对应的ASS对齐方式（小键盘布局）
+var subtitleAlignments = map[SubtitlePosition]int{
+	SubtitlePosition_Bottom: 2,
+	SubtitlePosition_Middle: 5,
+	SubtitlePosition_Top:    8,
+}
+
+// sidecarSubtitleExts 自动查找的字幕文件扩展名，按优先级排列
+var sidecarSubtitleExts = []string{".ass", ".ssa", ".srt", ".vtt"}
+
+// BurnSubtitleOptions 把字幕烧录到画面中，需要重新编码视频
+//
+// 样式为空时使用字幕文件自带的样式，ASS字幕设置样式后会覆盖所有行的样式
+type BurnSubtitleOptions struct {
+	Source       SubtitleSource   `yaml:"source" json:"source"`              // 字幕来源: file、sidecar、stream，为空时不烧录
+	File         string           `yaml:"file" json:"file"`                  // 字幕文件，支持 srt、ass、ssa、vtt
+	StreamIndex  int              `yaml:"streamIndex" json:"stream_index"`   // 内嵌字幕是第几个字幕流，从0开始
+	Font         string           `yaml:"font" json:"font"`                  // 字体名称，如 Microsoft YaHei
+	FontSize     int              `yaml:"fontSize" json:"font_size"`         // 字号，按字幕的参考分辨率计算（SRT为288行），为0时不修改
+	Color        string           `yaml:"color" json:"color"`                // 文字颜色，如 #FFFFFF
+	OutlineColor string           `yaml:"outlineColor" json:"outline_color"` // 描边颜色
+	Outline      float64          `yaml:"outline" json:"outline"`            // 描边宽度，为0时不修改
+	Bold         bool             `yaml:"bold" json:"bold"`
+	Italic       bool             `yaml:"italic" json:"italic"`
+	Position     SubtitlePosition `yaml:"position" json:"position"` // 位置: top、middle，为空时在底部
+	Margin       int              `yaml:"margin" json:"margin"`     // 与画面上下边缘的距离，为0时不修改
+}
+
+// Enabled 是否烧录字幕
+func (o BurnSubtitleOptions) Enabled() bool {
+	return o.Source != SubtitleSource_None
+}
+
+// validateBurnSubtitle 校验烧录字幕的设置，不检查文件是否存在
+func validateBurnSubtitle(options BurnSubtitleOptions) error {
+	switch options.Source {
+	case SubtitleSource_None:
+		return nil
+	case SubtitleSource_File:
+		if options.File == "" {
+			return fmt.Errorf("需要选择烧录的字幕文件")
+		}
+		if !containsString(sidecarSubtitleExts, FileExt(options.File)) {
+			return fmt.Errorf("不支持的字幕文件: %s，可用: srt、ass、ssa、vtt", options.File)
+		}
+	case SubtitleSource_Sidecar:
+	case SubtitleSource_Stream:
+		if options.StreamIndex < 0 {
+			return fmt.Errorf("无效的字幕流序号: %d", options.StreamIndex)
+		}
+	default:
+		return fmt.Errorf("无效的字幕来源: %s，可用: file、sidecar、stream", options.Source)
+	}
+	if strings.ContainsAny(options.Font, `',:\`) {
+		return fmt.Errorf("字体名称不能包含 ' , : \\ 等字符: %s", options.Font)
+	}
+	if options.FontSize < 0 || options.FontSize > 200 {
+		return fmt.Errorf("字号必须在 0 到 200 之间（0 表示不修改）: %d", options.FontSize)
+	}
+	if options.Outline < 0 || options.Outline > 10 {
+		return fmt.Errorf("描边宽度必须在 0 到 10 之间: %g", options.Outline)
+	}
+	if options.Margin < 0 {
+		return fmt.Errorf("无效的字幕边距: %d", options.Margin)
+	}
+	if _, ok := subtitleAlignments[options.Position]; !ok {
+		return fmt.Errorf("无效的字幕位置: %s，可用: top、middle", options.Position)
+	}
+	for _, color := range []string{options.Color, options.OutlineColor} {
+		if _, err := assColor(color); color != "" && err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assColor 把 #RRGGBB 转换为ASS的颜色格式 &H00BBGGRR
+func assColor(color string) (string, error) {
+	value := strings.TrimPrefix(color, "#")
+	if len(value) != 6 {
+		return "", fmt.Errorf("无效的颜色: %s，应为 #RRGGBB", color)
+	}
+	if _, err := strconv.ParseUint(value, 16, 32); err != nil {
+		return "", fmt.Errorf("无效的颜色: %s，应为 #RRGGBB", color)
+	}
+	value = strings.ToUpper(value)
+	return "&H00" + value[4:6] + value[2:4] + value[0:2], nil
+}
+
+// findSidecarSubtitle 查找输入文件旁边同名的字幕文件，优先使用完全同名的文件，其次是带语言后缀的文件，如 video.zh.srt
+func findSidecarSubtitle(inputFilePath string) (string, bool) {
+	files := findSidecarSubtitles(inputFilePath)
+	if len(files) == 0 {
+		return "", false
+	}
+	return files[0], true
+}
+
+// findSidecarSubtitles 查找输入文件旁边所有同名的字幕文件，完全同名的文件按扩展名的优先级排在前面，带后缀的文件按文件名排序
+func findSidecarSubtitles(inputFilePath string) []string {
+	base := strings.TrimSuffix(inputFilePath, filepath.Ext(inputFilePath))
+	var files []string
+	for _, ext := range sidecarSubtitleExts {
+		if FileExists(base + ext) {
+			files = append(files, base+ext)
+		}
+	}
+	entries, err := os.ReadDir(filepath.Dir(inputFilePath))
+	if err != nil {
+		return files
+	}
+	prefix := filepath.Base(base) + "."
+	var matches []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !containsString(sidecarSubtitleExts, FileExt(name)) {
+			continue
+		}
+		// 完全同名的文件已经加入
+		if strings.TrimSuffix(name, filepath.Ext(name)) != filepath.Base(base) {
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+	for _, name := range matches {
+		files = append(files, filepath.Join(filepath.Dir(inputFilePath), name))
+	}
+	return files
+}
+
+// sidecarSubtitleTrack 把同名字幕文件作为字幕轨，文件名的后缀为语言代码时作为语言标签，否则作为标题，如 video.zh.srt、video.forced.srt
+func sidecarSubtitleTrack(inputFilePath, subtitlePath string) SubtitleTrack {
+	track := SubtitleTrack{File: subtitlePath}
+	base := filepath.Base(strings.TrimSuffix(inputFilePath, filepath.Ext(inputFilePath)))
+	name := filepath.Base(strings.TrimSuffix(subtitlePath, filepath.Ext(subtitlePath)))
+	suffix := strings.TrimPrefix(strings.TrimPrefix(name, base), ".")
+	if isLanguageCode(suffix) {
+		track.Language = normalizeLanguage(suffix)
+	} else {
+		track.Title = suffix
+	}
+	return track
+}
+
+// resolveBurnSubtitle 在启动FFmpeg前确定烧录的字幕，自动查找的字幕文件写入 File
+//
+// 返回值:
+//
+//	TranscodeParams: 实际使用的参数
+//	error: 找不到字幕或字幕无法烧录时返回错误
+func resolveBurnSubtitle(inputFilePath string, params TranscodeParams, inputInfo *VideoInfo) (TranscodeParams, error) {
+	options := params.BurnSubtitle
+	switch options.Source {
+	case SubtitleSource_File:
+		if !FileExists(options.File) {
+			return params, fmt.Errorf("字幕文件不存在: %s", options.File)
+		}
+	case SubtitleSource_Sidecar:
+		file, ok := findSidecarSubtitle(inputFilePath)
+		if !ok {
+			return params, fmt.Errorf("没有找到与输入文件同名的字幕文件（%s）", strings.Join(sidecarSubtitleExts, "、"))
+		}
+		params.BurnSubtitle.File = file
+	case SubtitleSource_Stream:
+		if inputInfo == nil || len(inputInfo.Streams) == 0 {
+			return params, nil
+		}
+		subtitles := streamsOfType(inputInfo, "subtitle")
+		if options.StreamIndex >= len(subtitles) {
+			return params, fmt.Errorf("输入文件没有第 %d 个字幕流，共有 %d 个字幕流", options.StreamIndex+1, len(subtitles))
+		}
+		if codec := subtitles[options.StreamIndex].Codec; !containsString(textSubtitleCodecs, codec) {
+			return params, fmt.Errorf("%s 是图形字幕，只能烧录文字字幕", codec)
+		}
+	}
+	return params, nil
+}
+
+// forceStyle 生成 subtitles 滤镜的 force_style 参数，没有设置样式时为空
+func (o BurnSubtitleOptions) forceStyle() string {
+	var styles []string
+	if o.Font != "" {
+		styles = append(styles, "FontName="+o.Font)
+	}
+	if o.FontSize > 0 {
+		styles = append(styles, "FontSize="+strconv.Itoa(o.FontSize))
+	}
+	if color, err := assColor(o.Color); err == nil {
+		styles = append(styles, "PrimaryColour="+color)
+	}
+	if color, err := assColor(o.OutlineColor); err == nil {
+		styles = append(styles, "OutlineColour="+color)
+	}
+	if o.Outline > 0 {
+		styles = append(styles, "BorderStyle=1", "Outline="+strconv.FormatFloat(o.Outline, 'f', -1, 64))
+	}
+	if o.Bold {
+		styles = append(styles, "Bold=1")
+	}
+	if o.Italic {
+		styles = append(styles, "Italic=1")
+	}
+	if o.Position != SubtitlePosition_Bottom {
+		styles = append(styles, "Alignment="+strconv.Itoa(subtitleAlignments[o.Position]))
+	}
+	if o.Margin > 0 {
+		styles = append(styles, "MarginV="+strconv.Itoa(o.Margin))
+	}
+	return strings.Join(styles, ",")
+}
+
+// burnSubtitleFilter 生成烧录字幕的 subtitles 滤镜
+//
+// subtitles 滤镜从头读取字幕文件，截取时先把时间戳加回开始时间，烧录后再从0开始
+func burnSubtitleFilter(inputFilePath string, params TranscodeParams) string {
+	options := params.BurnSubtitle
+	filter := "subtitles=filename=" + quoteFilterPath(options.File)
+	if options.Source == SubtitleSource_Stream {
+		filter = fmt.Sprintf("subtitles=filename=%s:si=%d", quoteFilterPath(inputFilePath), options.StreamIndex)
+	}
+	if style := options.forceStyle(); style != "" {
+		filter += ":force_style='" + style + "'"
+	}
+	if start, _, err := params.Trim.bounds(); err == nil && start > 0 {
+		filter = fmt.Sprintf("setpts=PTS+%s/TB,%s,setpts=PTS-STARTPTS", formatSeconds(start), filter)
+	}
+	return filter
+}
+
+// subtitleFileCodecs 字幕文件扩展名对应的ffprobe编码名称
+var subtitleFileCodecs = map[string]string{
+	".srt": "subrip",
+	".ass": "ass",
+	".ssa": "ssa",
+	".vtt": "webvtt",
+}
+
+// SubtitleTrack 作为可选字幕轨封装到输出文件中的外部字幕
+type SubtitleTrack struct {
+	File     string `yaml:"file" json:"file"`         // 字幕文件，支持 srt、ass、ssa、vtt
+	Language string `yaml:"language" json:"language"` // 语言代码，如 chi、eng
+	Title    string `yaml:"title" json:"title"`
+	Default  bool   `yaml:"default" json:"default"` // 标记为默认字幕
+}
+
+// SoftSubtitleOptions 封装外部字幕和导出内嵌字幕，不需要重新编码视频
+type SoftSubtitleOptions struct {
+	Sidecars bool            `yaml:"sidecars" json:"sidecars"`       // 封装输入文件旁边所有同名的字幕文件
+	Tracks   []SubtitleTrack `yaml:"tracks,omitempty" json:"tracks"` // 封装指定的字幕文件
+	Extract  SubtitleFormat  `yaml:"extract" json:"extract"`         // 把内嵌的文字字幕导出为 srt、ass、vtt 文件，与输出文件放在同一目录
+}
+
+// muxes 是否需要封装外部字幕
+func (o SoftSubtitleOptions) muxes() bool {
+	return o.Sidecars || len(o.Tracks) > 0
+}
+
+// validateSoftSubtitles 校验外部字幕和导出格式，不检查文件是否存在
+func validateSoftSubtitles(options SoftSubtitleOptions) error {
+	for _, track := range options.Tracks {
+		if track.File == "" {
+			return fmt.Errorf("需要指定封装的字幕文件")
+		}
+		if _, ok := subtitleFileCodecs[FileExt(track.File)]; !ok {
+			return fmt.Errorf("不支持的字幕文件: %s，可用: srt、ass、ssa、vtt", track.File)
+		}
+		if track.Language != "" && !isLanguageCode(track.Language) {
+			return fmt.Errorf("无效的语言代码: %s，应为两位或三位字母，如 zh、chi、eng", track.Language)
+		}
+	}
+	return validateSubtitleFormat(options.Extract)
+}
+
+// resolveSoftSubtitles 在启动FFmpeg前确定封装的外部字幕，找到的同名字幕文件加入 Tracks
+//
+// 返回值:
+//
+//	TranscodeParams: 实际使用的参数
+//	[]string: 没有找到同名字幕文件时的说明
+//	error: 指定的字幕文件不存在时返回错误
+func resolveSoftSubtitles(inputFilePath string, params TranscodeParams) (TranscodeParams, []string, error) {
+	options := params.SoftSubtitles
+	for _, track := range options.Tracks {
+		if !FileExists(track.File) {
+			return params, nil, fmt.Errorf("字幕文件不存在: %s", track.File)
+		}
+	}
+	if !options.Sidecars {
+		return params, nil, nil
+	}
+	files := findSidecarSubtitles(inputFilePath)
+	if len(files) == 0 {
+		return params, []string{"没有找到与输入文件同名的字幕文件"}, nil
+	}
+	tracks := append([]SubtitleTrack{}, options.Tracks...)
+	for _, file := range files {
+		// 已经指定的字幕文件不重复封装
+		duplicate := false
+		for _, track := range options.Tracks {
+			duplicate = duplicate || outputPathKey(track.File) == outputPathKey(file)
+		}
+		if !duplicate {
+			tracks = append(tracks, sidecarSubtitleTrack(inputFilePath, file))
+		}
+	}
+	params.SoftSubtitles.Tracks = tracks
+	params.SoftSubtitles.Sidecars = false
+	return params, nil, nil
+}
+
+// subtitleInputArgs 外部字幕作为额外的输入文件，截取时同样跳到开始时间
+func subtitleInputArgs(params TranscodeParams) []string {
+	var args []string
+	for _, track := range params.SoftSubtitles.Tracks {
+		args = append(args, trimInputArgs(params.Trim)...)
+		args = append(args, "-i", track.File)
+	}
+	return args
+}
@@ -0,0 +1,179 @@
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAssColor(t *testing.T) {
+	tests := []struct {
+		color   string
+		want    string
+		wantErr bool
+	}{
+		{color: "#FFFFFF", want: "&H00FFFFFF"},
+		{color: "#FF8000", want: "&H000080FF"},
+		{color: "#12abCD", want: "&H00CDAB12"},
+		{color: "00FF00", want: "&H0000FF00"},
+		{color: "#FFF", wantErr: true},
+		{color: "#GGGGGG", wantErr: true},
+		{color: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := assColor(tt.color)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("assColor(%q) = %q, %v, want %q, wantErr %v", tt.color, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestBurnSubtitleForceStyle(t *testing.T) {
+	tests := []struct {
+		name    string
+		options BurnSubtitleOptions
+		want    string
+	}{
+		{name: "使用字幕文件的样式", options: BurnSubtitleOptions{Source: SubtitleSource_File}, want: ""},
+		{
+			name:    "字体、字号和颜色",
+			options: BurnSubtitleOptions{Font: "Microsoft YaHei", FontSize: 24, Color: "#FFFF00", OutlineColor: "#000000"},
+			want:    "FontName=Microsoft YaHei,FontSize=24,PrimaryColour=&H0000FFFF,OutlineColour=&H00000000",
+		},
+		{
+			name:    "描边、粗斜体、位置和边距",
+			options: BurnSubtitleOptions{Outline: 1.5, Bold: true, Italic: true, Position: SubtitlePosition_Top, Margin: 30},
+			want:    "BorderStyle=1,Outline=1.5,Bold=1,Italic=1,Alignment=8,MarginV=30",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.options.forceStyle(); got != tt.want {
+				t.Errorf("forceStyle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBurnSubtitleFilter(t *testing.T) {
+	file := BurnSubtitleOptions{Source: SubtitleSource_File, File: "/subs/movie.srt"}
+	tests := []struct {
+		name   string
+		params TranscodeParams
+		want   string
+	}{
+		{
+			name:   "字幕文件",
+			params: TranscodeParams{BurnSubtitle: file},
+			want:   "subtitles=filename='/subs/movie.srt'",
+		},
+		{
+			name:   "内嵌字幕",
+			params: TranscodeParams{BurnSubtitle: BurnSubtitleOptions{Source: SubtitleSource_Stream, StreamIndex: 1}},
+			want:   "subtitles=filename='/videos/movie.mkv':si=1",
+		},
+		{
+			name:   "设置样式",
+			params: TranscodeParams{BurnSubtitle: BurnSubtitleOptions{Source: SubtitleSource_File, File: "/subs/movie.ass", FontSize: 20}},
+			want:   "subtitles=filename='/subs/movie.ass':force_style='FontSize=20'",
+		},
+		{
+			name:   "截取时调整时间戳",
+			params: TranscodeParams{BurnSubtitle: file, Trim: TrimRange{Start: "1:30", Duration: "60"}},
+			want:   "setpts=PTS+90.000/TB,subtitles=filename='/subs/movie.srt',setpts=PTS-STARTPTS",
+		},
+		{
+			name:   "从开头截取时不调整时间戳",
+			params: TranscodeParams{BurnSubtitle: file, Trim: TrimRange{End: "60"}},
+			want:   "subtitles=filename='/subs/movie.srt'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := burnSubtitleFilter("/videos/movie.mkv", tt.params); got != tt.want {
+				t.Errorf("burnSubtitleFilter() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindSidecarSubtitles(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  []string
+	}{
+		{name: "没有字幕", files: []string{"video.mp4", "other.srt"}, want: nil},
+		{name: "完全同名的字幕在前", files: []string{"video.mp4", "video.zh.srt", "video.srt"}, want: []string{"video.srt", "video.zh.srt"}},
+		{name: "按扩展名的优先级", files: []string{"video.mp4", "video.srt", "video.ass"}, want: []string{"video.ass", "video.srt"}},
+		{name: "带后缀的字幕按文件名排序", files: []string{"video.mp4", "video.zh.srt", "video.en.ass", "video.zh.txt"}, want: []string{"video.en.ass", "video.zh.srt"}},
+		{name: "忽略其他视频的字幕", files: []string{"video.mp4", "video2.srt", "video.zh.srt"}, want: []string{"video.zh.srt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var want []string
+			for _, name := range tt.want {
+				want = append(want, filepath.Join(dir, name))
+			}
+			input := filepath.Join(dir, "video.mp4")
+			if got := findSidecarSubtitles(input); !reflect.DeepEqual(got, want) {
+				t.Errorf("findSidecarSubtitles() = %q, want %q", got, want)
+			}
+			got, ok := findSidecarSubtitle(input)
+			if ok != (len(want) > 0) || (ok && got != want[0]) {
+				t.Errorf("findSidecarSubtitle() = %q, %v", got, ok)
+			}
+		})
+	}
+}
+
+func TestSidecarSubtitleTrack(t *testing.T) {
+	tests := []struct {
+		subtitle string
+		want     SubtitleTrack
+	}{
+		{"/videos/movie.srt", SubtitleTrack{File: "/videos/movie.srt"}},
+		{"/videos/movie.zh.srt", SubtitleTrack{File: "/videos/movie.zh.srt", Language: "chi"}},
+		{"/videos/movie.eng.ass", SubtitleTrack{File: "/videos/movie.eng.ass", Language: "eng"}},
+		{"/videos/movie.forced.srt", SubtitleTrack{File: "/videos/movie.forced.srt", Title: "forced"}},
+	}
+	for _, tt := range tests {
+		if got := sidecarSubtitleTrack("/videos/movie.mkv", tt.subtitle); got != tt.want {
+			t.Errorf("sidecarSubtitleTrack(%q) = %+v, want %+v", tt.subtitle, got, tt.want)
+		}
+	}
+}
+
+func TestValidateBurnSubtitle(t *testing.T) {
+	tests := []struct {
+		name    string
+		options BurnSubtitleOptions
+		wantErr bool
+	}{
+		{name: "不烧录", options: BurnSubtitleOptions{}},
+		{name: "字幕文件", options: BurnSubtitleOptions{Source: SubtitleSource_File, File: "a.ass", Color: "#FFFFFF"}},
+		{name: "缺少字幕文件", options: BurnSubtitleOptions{Source: SubtitleSource_File}, wantErr: true},
+		{name: "不支持的字幕文件", options: BurnSubtitleOptions{Source: SubtitleSource_File, File: "a.sub"}, wantErr: true},
+		{name: "字号为0时不修改", options: BurnSubtitleOptions{Source: SubtitleSource_Sidecar, FontSize: 0}},
+		{name: "字号过大", options: BurnSubtitleOptions{Source: SubtitleSource_Sidecar, FontSize: 201}, wantErr: true},
+		{name: "字号为负数", options: BurnSubtitleOptions{Source: SubtitleSource_Sidecar, FontSize: -1}, wantErr: true},
+		{name: "字体名称包含逗号", options: BurnSubtitleOptions{Source: SubtitleSource_Sidecar, Font: "Arial,Bold"}, wantErr: true},
+		{name: "无效的颜色", options: BurnSubtitleOptions{Source: SubtitleSource_Sidecar, OutlineColor: "black"}, wantErr: true},
+		{name: "无效的位置", options: BurnSubtitleOptions{Source: SubtitleSource_Stream, Position: "left"}, wantErr: true},
+		{name: "无效的来源", options: BurnSubtitleOptions{Source: "url"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateBurnSubtitle(tt.options); (err != nil) != tt.wantErr {
+				t.Errorf("validateBurnSubtitle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
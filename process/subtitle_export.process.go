@@ -0,0 +1,283 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SubtitleFormat string
+
+const (
+	SubtitleFormat_None SubtitleFormat = ""    // 不导出字幕
+	SubtitleFormat_SRT  SubtitleFormat = "srt" // SubRip
+	SubtitleFormat_ASS  SubtitleFormat = "ass" // Advanced SubStation Alpha，保留样式
+	SubtitleFormat_VTT  SubtitleFormat = "vtt" // WebVTT
+)
+
+// subtitleFormatEncoders 导出格式对应的FFmpeg字幕编码器，封装格式由扩展名决定
+var subtitleFormatEncoders = map[SubtitleFormat]string{
+	SubtitleFormat_SRT: "srt",
+	SubtitleFormat_ASS: "ass",
+	SubtitleFormat_VTT: "webvtt",
+}
+
+// SubtitleJobOptions 字幕任务的选项，输入为字幕文件时转换格式，为视频文件时导出所有内嵌的文字字幕
+type SubtitleJobOptions struct {
+	Format SubtitleFormat `json:"format"` // 输出格式: srt、ass、vtt
+}
+
+// SubtitleOutput 导出或转换得到的字幕文件
+type SubtitleOutput struct {
+	Path        string         `json:"path"`
+	Format      SubtitleFormat `json:"format"`
+	Language    string         `json:"language"`
+	Title       string         `json:"title"`
+	Source      string         `json:"source"`       // 字幕来源的文件
+	StreamIndex int            `json:"stream_index"` // 字幕在来源文件中的流序号
+}
+
+// subtitleExport 转码时一并导出的一个字幕文件
+type subtitleExport struct {
+	Map      string // FFmpeg的 -map 参数
+	TempPath string
+	Output   SubtitleOutput
+}
+
+// validateSubtitleFormat 校验字幕导出格式，为空时不导出
+func validateSubtitleFormat(format SubtitleFormat) error {
+	if format == SubtitleFormat_None {
+		return nil
+	}
+	if _, ok := subtitleFormatEncoders[format]; !ok {
+		return fmt.Errorf("无效的字幕格式: %s，可用: srt、ass、vtt", format)
+	}
+	return nil
+}
+
+// subtitleExportPath 导出字幕的文件名，在输出文件名后加上语言，没有语言时加上字幕的序号，如 video.chi.srt、video.2.srt
+func subtitleExportPath(outputFilePath string, format SubtitleFormat, suffix string) string {
+	base := strings.TrimSuffix(outputFilePath, filepath.Ext(outputFilePath))
+	if suffix != "" {
+		base += "." + suffix
+	}
+	return base + "." + string(format)
+}
+
+// planSubtitleExports 确定导出的内嵌文字字幕并按同名文件策略占用输出路径，设置了语言时只导出这些语言的字幕
+//
+// 返回值:
+//
+//	[]subtitleExport: 导出的字幕，调用方需要在结束后对每个 Output.Path 调用 releaseOutputPath
+//	[]string: 没有可导出的字幕、图形字幕无法导出或输出文件已存在时的说明
+func planSubtitleExports(id, inputFilePath, outputFilePath string, params TranscodeParams, inputInfo *VideoInfo) ([]subtitleExport, []string) {
+	format := params.SoftSubtitles.Extract
+	if inputInfo == nil || len(inputInfo.Streams) == 0 {
+		return nil, []string{"无法获取输入文件的流信息，没有导出字幕"}
+	}
+	var exports []subtitleExport
+	var warnings []string
+	used := map[string]int{}
+	for i, subtitle := range streamsOfType(inputInfo, "subtitle") {
+		languages := params.Streams.Languages
+		if len(languages) > 0 && !containsLanguage(languages, subtitle.Language) {
+			continue
+		}
+		if !containsString(textSubtitleCodecs, subtitle.Codec) {
+			warnings = append(warnings, fmt.Sprintf("字幕流 #%d 是 %s 图形字幕，无法导出为 %s", subtitle.Index, subtitle.Codec, format))
+			continue
+		}
+		// 同一语言有多个字幕时后面的加上序号
+		suffix := normalizeLanguage(subtitle.Language)
+		if suffix == "" || suffix == "und" {
+			suffix = strconv.Itoa(i + 1)
+		} else if used[suffix]++; used[suffix] > 1 {
+			suffix += "." + strconv.Itoa(i+1)
+		}
+		path, skip, err := reserveOutputPath(subtitleExportPath(outputFilePath, format, suffix), GetOutputCollisionPolicy())
+		if skip {
+			warnings = append(warnings, fmt.Sprintf("字幕文件已存在，跳过: %s", path))
+			continue
+		}
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("无法导出字幕流 #%d: %v: %s", subtitle.Index, err, path))
+			continue
+		}
+		exports = append(exports, subtitleExport{
+			Map:      "0:" + strconv.Itoa(subtitle.Index),
+			TempPath: tempOutputPath(path, id),
+			Output: SubtitleOutput{
+				Path:        path,
+				Format:      format,
+				Language:    subtitle.Language,
+				Title:       subtitle.Title,
+				Source:      inputFilePath,
+				StreamIndex: subtitle.Index,
+			},
+		})
+	}
+	if len(exports) == 0 && len(warnings) == 0 {
+		warnings = append(warnings, "输入文件没有可导出的文字字幕")
+	}
+	return exports, warnings
+}
+
+// buildSubtitleExportArgs 生成导出字幕的FFmpeg参数，一次调用输出所有字幕文件，截取时只导出截取范围内的字幕
+func buildSubtitleExportArgs(inputFilePath string, trim TrimRange, exports []subtitleExport) []string {
+	args := []string{"-y"}
+	args = append(args, trimInputArgs(trim)...)
+	args = append(args, "-i", inputFilePath)
+	var length []string
+	if _, duration, err := trim.bounds(); err == nil && duration > 0 {
+		length = []string{"-t", formatSeconds(duration)}
+	}
+	args = append(args, "-progress", "pipe:2", "-nostats")
+	for _, export := range exports {
+		args = append(args, "-map", export.Map)
+		args = append(args, length...)
+		args = append(args, "-c:s", subtitleFormatEncoders[export.Output.Format], export.TempPath)
+	}
+	return args
+}
+
+// commitSubtitleExports 将导出的字幕临时文件重命名为输出文件并加入结果中，失败时记录警告，返回最后一个错误
+func commitSubtitleExports(exports []subtitleExport, result *TranscodeResult) error {
+	var lastErr error
+	for _, export := range exports {
+		if err := commitOutputFile(export.TempPath, export.Output.Path); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("保存字幕文件失败: %v", err))
+			lastErr = err
+			continue
+		}
+		result.Subtitles = append(result.Subtitles, export.Output)
+	}
+	return lastErr
+}
+
+// releaseSubtitleExports 释放导出字幕占用的输出路径并删除临时文件
+func releaseSubtitleExports(exports []subtitleExport) {
+	for _, export := range exports {
+		os.Remove(export.TempPath)
+		releaseOutputPath(export.Output.Path)
+	}
+}
+
+// isSubtitleFile 是否为可以转换格式的字幕文件
+func isSubtitleFile(filePath string) bool {
+	_, ok := subtitleFileCodecs[FileExt(filePath)]
+	return ok
+}
+
+// validateSubtitleJob 校验字幕任务
+func validateSubtitleJob(job TranscodeJob) error {
+	if job.Subtitle == nil {
+		return fmt.Errorf("字幕任务缺少字幕选项")
+	}
+	if job.Subtitle.Format == SubtitleFormat_None {
+		return fmt.Errorf("需要指定字幕的输出格式")
+	}
+	return validateSubtitleFormat(job.Subtitle.Format)
+}
+
+// SubtitleJobProcessor 处理字幕任务：字幕文件转换为指定格式，视频文件导出所有内嵌的文字字幕
+//
+// 输出文件名使用文件名模板，扩展名为字幕格式；导出的字幕按语言加上后缀
+func SubtitleJobProcessor(ctx context.Context, job TranscodeJob) TranscodeResult {
+	id, inputFilePath := job.ID, job.Path
+	taskCtx, done := registerTranscodeTask(ctx, id)
+	defer done()
+
+	startTime := time.Now()
+	result := TranscodeResult{
+		ID:        id,
+		Status:    TranscodeResultStatus_Failed,
+		InputPath: inputFilePath,
+		ExitCode:  -1,
+	}
+	fail := func(kind TranscodeErrorKind, format string, a ...interface{}) TranscodeResult {
+		result.ErrorKind = kind
+		result.Error = fmt.Sprintf(format, a...)
+		result.ElapsedSeconds = time.Since(startTime).Seconds()
+		return result
+	}
+
+	if err := validateSubtitleJob(job); err != nil {
+		return fail(TranscodeErrorKind_InvalidParams, "参数无效: %v", err)
+	}
+	if !FileExists(inputFilePath) {
+		return fail(TranscodeErrorKind_BadInput, "输入文件不存在: %s", inputFilePath)
+	}
+	format := job.Subtitle.Format
+	params := job.Params
+	params.SoftSubtitles.Extract = format
+
+	// 输出文件名只使用模板，转码参数中的输出格式和截取不生效
+	job.Params.Container = ""
+	outputFilePath, err := getOutputFilePath(GetOutputDirectory(), job, nil)
+	if err != nil {
+		return fail(TranscodeErrorKind_Unknown, "生成输出文件名失败: %v", err)
+	}
+	if err := CreateFolder(filepath.Dir(outputFilePath)); err != nil {
+		return fail(classifyFileError(err), "创建输出目录失败: %v", err)
+	}
+
+	var exports []subtitleExport
+	if isSubtitleFile(inputFilePath) {
+		path := subtitleExportPath(outputFilePath, format, "")
+		path, skip, err := reserveOutputPath(path, collisionPolicyFor(path, inputFilePath))
+		result.OutputPath = path
+		if skip {
+			consolePrintf(ctx, "输出文件已存在，跳过: %s\n", path)
+			result.Status = TranscodeResultStatus_Skipped
+			result.ElapsedSeconds = time.Since(startTime).Seconds()
+			return result
+		}
+		if err != nil {
+			return fail(TranscodeErrorKind_OutputExists, "%v: %s", err, path)
+		}
+		exports = []subtitleExport{{
+			Map:      "0:s:0",
+			TempPath: tempOutputPath(path, id),
+			Output:   SubtitleOutput{Path: path, Format: format, Source: inputFilePath},
+		}}
+	} else {
+		inputInfo, err := probeVideoInfo(inputFilePath)
+		if err != nil {
+			return fail(TranscodeErrorKind_BadInput, "读取输入文件失败: %v", err)
+		}
+		inputInfo.ID = id
+		result.InputInfo = &inputInfo
+		var warnings []string
+		exports, warnings = planSubtitleExports(id, inputFilePath, outputFilePath, params, &inputInfo)
+		result.Warnings = append(result.Warnings, warnings...)
+		if len(exports) == 0 {
+			return fail(TranscodeErrorKind_BadInput, "没有导出字幕: %s", strings.Join(warnings, "；"))
+		}
+		result.OutputPath = exports[0].Output.Path
+	}
+	defer releaseSubtitleExports(exports)
+	for _, warning := range result.Warnings {
+		consolePrintf(ctx, "警告: %s\n", warning)
+	}
+
+	var duration float64
+	if result.InputInfo != nil {
+		duration = result.InputInfo.Duration
+	}
+	steps := []transcodeStep{{Args: buildSubtitleExportArgs(inputFilePath, TrimRange{}, exports), Duration: duration}}
+	if kind, err := runTranscodeSteps(ctx, taskCtx, id, steps, duration, &result); err != nil {
+		return fail(kind, "%v", err)
+	}
+	if err := commitSubtitleExports(exports, &result); err != nil && len(result.Subtitles) == 0 {
+		return fail(classifyFileError(err), "保存输出文件失败: %v", err)
+	}
+
+	consolePrintf(ctx, "处理字幕成功: %s\n", result.OutputPath)
+	result.Status = TranscodeResultStatus_Success
+	result.ElapsedSeconds = time.Since(startTime).Seconds()
+	return result
+}
@@ -0,0 +1,137 @@
+package process
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSubtitleExportPath(t *testing.T) {
+	output := filepath.Join("out", "video.mkv")
+	tests := []struct {
+		format SubtitleFormat
+		suffix string
+		want   string
+	}{
+		{SubtitleFormat_SRT, "chi", filepath.Join("out", "video.chi.srt")},
+		{SubtitleFormat_SRT, "chi.3", filepath.Join("out", "video.chi.3.srt")},
+		{SubtitleFormat_VTT, "2", filepath.Join("out", "video.2.vtt")},
+		{SubtitleFormat_ASS, "", filepath.Join("out", "video.ass")},
+	}
+	for _, tt := range tests {
+		if got := subtitleExportPath(output, tt.format, tt.suffix); got != tt.want {
+			t.Errorf("subtitleExportPath(%s, %q) = %q, want %q", tt.format, tt.suffix, got, tt.want)
+		}
+	}
+}
+
+func TestPlanSubtitleExports(t *testing.T) {
+	Config = &ConfigData{OutputCollisionPolicy: string(OutputCollisionPolicy_Rename)}
+	inputInfo := &VideoInfo{Streams: []StreamInfo{
+		{Index: 0, Type: "video", Codec: "h264"},
+		{Index: 1, Type: "audio", Codec: "aac"},
+		{Index: 2, Type: "subtitle", Codec: "subrip", Language: "chi"},
+		{Index: 3, Type: "subtitle", Codec: "ass"},
+		{Index: 4, Type: "subtitle", Codec: "subrip", Language: "zh"},
+		{Index: 5, Type: "subtitle", Codec: "hdmv_pgs_subtitle", Language: "eng"},
+		{Index: 6, Type: "subtitle", Codec: "mov_text", Language: "eng"},
+	}}
+
+	tests := []struct {
+		name         string
+		languages    []string
+		inputInfo    *VideoInfo
+		wantFiles    []string
+		wantMaps     []string
+		wantWarnings int
+	}{
+		{
+			name:         "按语言命名并跳过图形字幕",
+			inputInfo:    inputInfo,
+			wantFiles:    []string{"video.chi.srt", "video.2.srt", "video.chi.3.srt", "video.eng.srt"},
+			wantMaps:     []string{"0:2", "0:3", "0:4", "0:6"},
+			wantWarnings: 1,
+		},
+		{
+			name:      "只导出指定语言的字幕",
+			languages: []string{"zh"},
+			inputInfo: inputInfo,
+			wantFiles: []string{"video.chi.srt", "video.chi.3.srt"},
+			wantMaps:  []string{"0:2", "0:4"},
+		},
+		{
+			name:         "没有可导出的字幕",
+			languages:    []string{"jpn"},
+			inputInfo:    inputInfo,
+			wantWarnings: 1,
+		},
+		{
+			name:         "没有流信息",
+			inputInfo:    &VideoInfo{},
+			wantWarnings: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			params := TranscodeParams{SoftSubtitles: SoftSubtitleOptions{Extract: SubtitleFormat_SRT}, Streams: StreamMapping{Languages: tt.languages}}
+			exports, warnings := planSubtitleExports("test", "input.mkv", filepath.Join(dir, "video.mkv"), params, tt.inputInfo)
+			defer releaseSubtitleExports(exports)
+
+			var files, maps []string
+			for _, export := range exports {
+				files = append(files, filepath.Base(export.Output.Path))
+				maps = append(maps, export.Map)
+				if export.TempPath == export.Output.Path || filepath.Dir(export.TempPath) != dir {
+					t.Errorf("临时文件 %q 应与输出文件在同一目录且不同名", export.TempPath)
+				}
+			}
+			if !reflect.DeepEqual(files, tt.wantFiles) {
+				t.Errorf("文件 = %q, want %q", files, tt.wantFiles)
+			}
+			if !reflect.DeepEqual(maps, tt.wantMaps) {
+				t.Errorf("-map = %q, want %q", maps, tt.wantMaps)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("警告 = %q, want %d 条", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestBuildSubtitleExportArgs(t *testing.T) {
+	exports := []subtitleExport{
+		{Map: "0:2", TempPath: "video.chi.tmp.srt", Output: SubtitleOutput{Format: SubtitleFormat_SRT}},
+		{Map: "0:4", TempPath: "video.eng.tmp.vtt", Output: SubtitleOutput{Format: SubtitleFormat_VTT}},
+	}
+	tests := []struct {
+		name string
+		trim TrimRange
+		want []string
+	}{
+		{
+			name: "每个字幕一对 -map 和 -c:s",
+			want: []string{
+				"-y", "-i", "input.mkv", "-progress", "pipe:2", "-nostats",
+				"-map", "0:2", "-c:s", "srt", "video.chi.tmp.srt",
+				"-map", "0:4", "-c:s", "webvtt", "video.eng.tmp.vtt",
+			},
+		},
+		{
+			name: "截取时每个输出都限制时长",
+			trim: TrimRange{Start: "10", End: "70"},
+			want: []string{
+				"-y", "-ss", "10.000", "-i", "input.mkv", "-progress", "pipe:2", "-nostats",
+				"-map", "0:2", "-t", "60.000", "-c:s", "srt", "video.chi.tmp.srt",
+				"-map", "0:4", "-t", "60.000", "-c:s", "webvtt", "video.eng.tmp.vtt",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildSubtitleExportArgs("input.mkv", tt.trim, exports); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildSubtitleExportArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
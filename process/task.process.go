@@ -0,0 +1,158 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sync"
+)
+
+// transcodeTask 正在运行的转码任务，用于取消、暂停和恢复
+//
+// 队列启动任务时先登记，处理器调用 registerTranscodeTask 之前被取消或暂停的任务在启动后立即生效
+type transcodeTask struct {
+	cancel    context.CancelFunc // 处理器开始前为nil
+	cancelled bool
+	cmd       *exec.Cmd // 正在运行的FFmpeg进程，两个步骤之间为nil
+	paused    bool      // 已请求暂停，之后启动的FFmpeg进程也会立即暂停
+}
+
+var (
+	transcodeTasksMu sync.Mutex
+	transcodeTasks   = map[string]*transcodeTask{}
+)
+
+// reserveTranscodeTask 在任务启动前登记，使任务在处理器开始前也可以被取消和暂停，结束后调用 releaseTranscodeTask
+func reserveTranscodeTask(id string) {
+	transcodeTasksMu.Lock()
+	defer transcodeTasksMu.Unlock()
+	if _, ok := transcodeTasks[id]; !ok {
+		transcodeTasks[id] = &transcodeTask{}
+	}
+}
+
+// releaseTranscodeTask 删除任务的登记
+func releaseTranscodeTask(id string) {
+	transcodeTasksMu.Lock()
+	delete(transcodeTasks, id)
+	transcodeTasksMu.Unlock()
+}
+
+// isTranscodeTaskCancelled 任务是否在启动前已被取消
+func isTranscodeTaskCancelled(id string) bool {
+	transcodeTasksMu.Lock()
+	defer transcodeTasksMu.Unlock()
+	task, ok := transcodeTasks[id]
+	return ok && task.cancelled
+}
+
+// registerTranscodeTask 登记转码任务，返回的上下文在任务被取消时结束，已经取消的任务返回已结束的上下文
+func registerTranscodeTask(ctx context.Context, id string) (context.Context, func()) {
+	taskCtx, cancel := context.WithCancel(ctx)
+	transcodeTasksMu.Lock()
+	task, ok := transcodeTasks[id]
+	if !ok {
+		task = &transcodeTask{}
+		transcodeTasks[id] = task
+	}
+	task.cancel = cancel
+	if task.cancelled {
+		cancel()
+	}
+	transcodeTasksMu.Unlock()
+	return taskCtx, func() {
+		releaseTranscodeTask(id)
+		cancel()
+	}
+}
+
+// attachTranscodeCommand 记录任务已启动的FFmpeg进程，任务已请求暂停时立即暂停该进程
+func attachTranscodeCommand(id string, cmd *exec.Cmd) {
+	transcodeTasksMu.Lock()
+	defer transcodeTasksMu.Unlock()
+	if task, ok := transcodeTasks[id]; ok {
+		task.cmd = cmd
+		if task.paused && cmd.Process != nil {
+			suspendProcess(cmd.Process)
+		}
+	}
+}
+
+// detachTranscodeCommand FFmpeg进程结束后调用，之后的暂停只记录状态
+func detachTranscodeCommand(id string, cmd *exec.Cmd) {
+	transcodeTasksMu.Lock()
+	defer transcodeTasksMu.Unlock()
+	if task, ok := transcodeTasks[id]; ok && task.cmd == cmd {
+		task.cmd = nil
+	}
+}
+
+// CancelTranscodeTask 取消转码任务，FFmpeg进程会被结束，尚未开始处理的任务不再处理
+func CancelTranscodeTask(id string) error {
+	transcodeTasksMu.Lock()
+	defer transcodeTasksMu.Unlock()
+	task, ok := transcodeTasks[id]
+	if !ok {
+		return fmt.Errorf("任务不存在或已结束: %s", id)
+	}
+	if task.paused && task.cmd != nil && task.cmd.Process != nil {
+		resumeProcess(task.cmd.Process)
+	}
+	task.paused = false
+	task.cancelled = true
+	if task.cancel != nil {
+		task.cancel()
+	}
+	return nil
+}
+
+// PauseTranscodeTask 暂停转码任务，没有正在运行的FFmpeg进程时在下一个进程启动时暂停
+func PauseTranscodeTask(id string) error {
+	transcodeTasksMu.Lock()
+	defer transcodeTasksMu.Unlock()
+	task, ok := transcodeTasks[id]
+	if !ok {
+		return fmt.Errorf("任务不存在或已结束: %s", id)
+	}
+	if task.paused {
+		return nil
+	}
+	if task.cmd != nil && task.cmd.Process != nil {
+		if err := suspendProcess(task.cmd.Process); err != nil {
+			return fmt.Errorf("暂停FFmpeg失败: %v", err)
+		}
+	}
+	task.paused = true
+	return nil
+}
+
+// ResumeTranscodeTask 恢复被暂停的转码任务
+func ResumeTranscodeTask(id string) error {
+	transcodeTasksMu.Lock()
+	defer transcodeTasksMu.Unlock()
+	task, ok := transcodeTasks[id]
+	if !ok {
+		return fmt.Errorf("任务不存在或已结束: %s", id)
+	}
+	if !task.paused {
+		return nil
+	}
+	if task.cmd != nil && task.cmd.Process != nil {
+		if err := resumeProcess(task.cmd.Process); err != nil {
+			return fmt.Errorf("恢复FFmpeg失败: %v", err)
+		}
+	}
+	task.paused = false
+	return nil
+}
+
+// runningTranscodeTaskIDs 获取所有正在运行的任务ID
+func runningTranscodeTaskIDs() []string {
+	transcodeTasksMu.Lock()
+	defer transcodeTasksMu.Unlock()
+	ids := make([]string, 0, len(transcodeTasks))
+	for id := range transcodeTasks {
+		ids = append(ids, id)
+	}
+	return ids
+}
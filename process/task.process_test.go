@@ -0,0 +1,85 @@
+package process
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+)
+
+func isTranscodeTaskPaused(id string) bool {
+	transcodeTasksMu.Lock()
+	defer transcodeTasksMu.Unlock()
+	task, ok := transcodeTasks[id]
+	return ok && task.paused
+}
+
+func TestCancelTranscodeTask(t *testing.T) {
+	ctx, done := registerTranscodeTask(context.Background(), "cancel-running")
+	if err := CancelTranscodeTask("cancel-running"); err != nil {
+		t.Fatalf("CancelTranscodeTask() error = %v", err)
+	}
+	select {
+	case <-ctx.Done():
+	default:
+		t.Error("取消后任务的上下文应结束")
+	}
+	done()
+	if err := CancelTranscodeTask("cancel-running"); err == nil {
+		t.Error("任务结束后取消应返回错误")
+	}
+}
+
+func TestCancelTranscodeTaskBeforeRegister(t *testing.T) {
+	reserveTranscodeTask("cancel-early")
+	if err := CancelTranscodeTask("cancel-early"); err != nil {
+		t.Fatalf("CancelTranscodeTask() error = %v", err)
+	}
+	if !isTranscodeTaskCancelled("cancel-early") {
+		t.Error("启动前取消的任务应标记为已取消")
+	}
+	ctx, done := registerTranscodeTask(context.Background(), "cancel-early")
+	defer done()
+	select {
+	case <-ctx.Done():
+	default:
+		t.Error("启动前已取消的任务，登记后上下文应立即结束")
+	}
+}
+
+func TestPauseTranscodeTaskBeforeAttach(t *testing.T) {
+	reserveTranscodeTask("pause-early")
+	if err := PauseTranscodeTask("pause-early"); err != nil {
+		t.Fatalf("PauseTranscodeTask() error = %v", err)
+	}
+	if err := PauseTranscodeTask("pause-early"); err != nil {
+		t.Errorf("重复暂停不应返回错误: %v", err)
+	}
+
+	_, done := registerTranscodeTask(context.Background(), "pause-early")
+	defer done()
+	// 未启动的进程没有 Process，只记录暂停状态
+	cmd := exec.Command("ffmpeg")
+	attachTranscodeCommand("pause-early", cmd)
+	if !isTranscodeTaskPaused("pause-early") {
+		t.Fatal("登记后应保留启动前的暂停状态")
+	}
+	if err := ResumeTranscodeTask("pause-early"); err != nil {
+		t.Fatalf("ResumeTranscodeTask() error = %v", err)
+	}
+	if isTranscodeTaskPaused("pause-early") {
+		t.Error("恢复后不应处于暂停状态")
+	}
+	detachTranscodeCommand("pause-early", cmd)
+}
+
+func TestUnknownTranscodeTask(t *testing.T) {
+	for name, control := range map[string]func(string) error{
+		"取消": CancelTranscodeTask,
+		"暂停": PauseTranscodeTask,
+		"恢复": ResumeTranscodeTask,
+	} {
+		if err := control("missing"); err == nil {
+			t.Errorf("%s不存在的任务应返回错误", name)
+		}
+	}
+}
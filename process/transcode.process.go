@@ -4,13 +4,12 @@ import (
 	"bufio"
 	"context"
 	"fmt"
-	"os/exec"
+	"os"
 	"path"
-	"regexp"
+	"path/filepath"
 	"strconv"
 	"strings"
-
-	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+	"time"
 )
 
 type WatermarkPlacement string
@@ -34,132 +33,565 @@ const (
 )
 
 type TranscodeParams struct {
-	VideoCodec         string             `json:"video_codec"`
-	AudioCodec         string             `json:"audio_codec"`
-	VideoHeight        string             `json:"video_height"`
-	Fps                string             `json:"fps"`
-	VideoBitrate       string             `json:"video_bitrate"`
-	WatermarkContent   string             `json:"watermark_content"`
-	WatermarkImage     string             `json:"watermark_image"`
-	WatermarkPlacement WatermarkPlacement `json:"watermark_placement"`
-	Rotate             VideoRotate        `json:"rotate"`
-	UseGpu             bool               `json:"use_gpu"`
-	CpuThreads         int                `json:"cpu_threads"`
+	VideoCodec         string              `yaml:"videoCodec" json:"video_codec"`
+	AudioCodec         string              `yaml:"audioCodec" json:"audio_codec"`
+	VideoHeight        string              `yaml:"videoHeight" json:"video_height"`
+	Fps                string              `yaml:"fps" json:"fps"`
+	VideoBitrate       string              `yaml:"videoBitrate" json:"video_bitrate"`
+	WatermarkContent   string              `yaml:"watermarkContent" json:"watermark_content"`
+	WatermarkImage     string              `yaml:"watermarkImage" json:"watermark_image"`
+	WatermarkPlacement WatermarkPlacement  `yaml:"watermarkPlacement" json:"watermark_placement"`
+	Rotate             VideoRotate         `yaml:"rotate" json:"rotate"`
+	UseGpu             bool                `yaml:"useGpu" json:"use_gpu"`
+	CpuThreads         int                 `yaml:"cpuThreads" json:"cpu_threads"`
+	RateControl        RateControlMode     `yaml:"rateControl" json:"rate_control"`               // 码率控制方式: crf、vbr、cbr、abr，为空时只使用视频码率
+	Quality            *int                `yaml:"quality,omitempty" json:"quality"`              // CRF/CQ值，越小画质越好，为空时使用编码器默认值
+	MaxRate            string              `yaml:"maxRate" json:"max_rate"`                       // 最大码率，用于VBR和限制CRF的峰值码率
+	BufSize            string              `yaml:"bufSize" json:"buf_size"`                       // 码率控制缓冲区大小，为空时使用最大码率的两倍
+	EncoderPreset      string              `yaml:"encoderPreset" json:"encoder_preset"`           // 编码速度预设: ultrafast ... veryslow
+	Tune               string              `yaml:"tune" json:"tune"`                              // 如 film、animation、grain、zerolatency
+	Profile            string              `yaml:"profile" json:"profile"`                        // 如 high、main、main10
+	Level              string              `yaml:"level" json:"level"`                            // 如 4.1
+	PixelFormat        string              `yaml:"pixelFormat" json:"pix_fmt"`                    // 如 yuv420p、yuv420p10le
+	GOP                int                 `yaml:"gop" json:"gop"`                                // 关键帧间隔（帧数），为0时使用编码器默认值
+	TwoPass            bool                `yaml:"twoPass" json:"two_pass"`                       // 两遍编码，需要指定视频码率
+	TargetSizeMB       float64             `yaml:"targetSizeMB" json:"target_size_mb"`            // 目标文件大小（MB），大于0时根据时长计算视频码率并两遍编码
+	Container          OutputContainer     `yaml:"container" json:"container"`                    // 输出封装格式: mp4、mkv、mov、webm、ts、m4a、mp3，为空时与输入文件相同
+	Trim               TrimRange           `yaml:"trim,omitempty" json:"trim"`                    // 截取的时间范围，为空时处理整个视频
+	Segment            SegmentOptions      `yaml:"segment,omitempty" json:"segment"`              // 分段输出，为空时输出一个文件
+	Loudness           LoudnessOptions     `yaml:"loudness,omitempty" json:"loudness"`            // 响度标准化（EBU R128），需要重新编码音频
+	AudioBitrate       string              `yaml:"audioBitrate" json:"audio_bitrate"`             // 音频码率，如 128k，为空时使用编码器默认值
+	SampleRate         int                 `yaml:"sampleRate" json:"sample_rate"`                 // 音频采样率（Hz），为0时与源音频相同
+	AudioChannels      AudioChannels       `yaml:"audioChannels" json:"audio_channels"`           // 声道: mono、stereo、5.1，为空时与源音频相同
+	MuteAudio          bool                `yaml:"muteAudio" json:"mute_audio"`                   // 去掉音频
+	Streams            StreamMapping       `yaml:"streams,omitempty" json:"streams"`              // 保留的音轨、字幕和附件，为空时只保留一个视频流和一个音频流
+	BurnSubtitle       BurnSubtitleOptions `yaml:"burnSubtitle,omitempty" json:"burn_subtitle"`   // 烧录到画面中的字幕，需要重新编码视频
+	SoftSubtitles      SoftSubtitleOptions `yaml:"softSubtitles,omitempty" json:"soft_subtitles"` // 封装为可选字幕轨的外部字幕，以及导出内嵌字幕
+	Preset             string              `yaml:"-" json:"preset"`                               // 参数来源的预设名称，用于输出文件名模板
 }
 
-func VideoTranscodeProcessor(ctx context.Context, id, inputFilePath string, params TranscodeParams) string {
-	fileName := GetFileNameFromPath(inputFilePath, true)
-	outputDirectory := GetOutputDirectory()
-	err := CreateFolder(outputDirectory)
+func VideoTranscodeProcessor(ctx context.Context, job TranscodeJob) TranscodeResult {
+	id, inputFilePath, params := job.ID, job.Path, job.Params
+	if job.Trim != nil {
+		params.Trim = *job.Trim
+	}
+	taskCtx, done := registerTranscodeTask(ctx, id)
+	defer done()
+
+	startTime := time.Now()
+	result := TranscodeResult{
+		ID:        id,
+		Status:    TranscodeResultStatus_Failed,
+		InputPath: inputFilePath,
+		ExitCode:  -1,
+	}
+	fail := func(kind TranscodeErrorKind, format string, a ...interface{}) TranscodeResult {
+		result.ErrorKind = kind
+		result.Error = fmt.Sprintf(format, a...)
+		result.ElapsedSeconds = time.Since(startTime).Seconds()
+		return result
+	}
+
+	if err := validateTranscodeParams(params); err != nil {
+		return fail(TranscodeErrorKind_InvalidParams, "参数无效: %v", err)
+	}
+
+	if inputInfo, err := probeVideoInfo(inputFilePath); err == nil {
+		inputInfo.ID = id
+		result.InputInfo = &inputInfo
+	}
+
+	// 智能切割只重新编码开头和结尾不完整的GOP，无法智能切割时改为精确切割
+	params, smartCut, smartCutWarnings := resolveSmartCut(inputFilePath, params, result.InputInfo)
+	result.Warnings = append(result.Warnings, smartCutWarnings...)
+
+	// 精确切割需要重新编码视频
+	params, trimWarnings := resolveTrimParams(params, result.InputInfo)
+	result.Warnings = append(result.Warnings, trimWarnings...)
+
+	// 响度标准化需要重新编码音频
+	params, loudnessWarnings := resolveLoudnessParams(params, result.InputInfo)
+	result.Warnings = append(result.Warnings, loudnessWarnings...)
+
+	// 检查编码与输出格式是否兼容，直接复制的流不兼容时改为重新编码
+	params, containerWarnings, err := resolveContainerParams(params, result.InputInfo)
+	if err != nil {
+		return fail(TranscodeErrorKind_InvalidParams, "参数无效: %v", err)
+	}
+	result.Warnings = append(result.Warnings, containerWarnings...)
+
+	// 音频码率、采样率和声道需要重新编码音频，并检查编码是否支持
+	params, audioWarnings, err := resolveAudioParams(params, result.InputInfo)
 	if err != nil {
-		return fmt.Sprintf("创建输出目录失败: %v", err)
+		return fail(TranscodeErrorKind_InvalidParams, "参数无效: %v", err)
+	}
+	result.Warnings = append(result.Warnings, audioWarnings...)
+
+	// 查找封装的同名字幕文件
+	params, softSubtitleWarnings, err := resolveSoftSubtitles(inputFilePath, params)
+	if err != nil {
+		return fail(TranscodeErrorKind_InvalidParams, "参数无效: %v", err)
+	}
+	result.Warnings = append(result.Warnings, softSubtitleWarnings...)
+
+	// 按语言选择音轨，保留字幕和附件，封装外部字幕
+	params, streamWarnings, err := resolveStreamParams(inputFilePath, params, result.InputInfo)
+	if err != nil {
+		return fail(TranscodeErrorKind_InvalidParams, "参数无效: %v", err)
+	}
+	result.Warnings = append(result.Warnings, streamWarnings...)
+
+	// 查找烧录的字幕文件，检查内嵌字幕是否可以烧录
+	if params, err = resolveBurnSubtitle(inputFilePath, params, result.InputInfo); err != nil {
+		return fail(TranscodeErrorKind_InvalidParams, "参数无效: %v", err)
+	}
+	job.Params = params
+
+	outputFilePath, skip, kind, err := prepareOutputPath(job, result.InputInfo)
+	result.OutputPath = outputFilePath
+	if skip {
+		consolePrintf(ctx, "输出文件已存在，跳过: %s\n", outputFilePath)
+		result.Status = TranscodeResultStatus_Skipped
+		result.ElapsedSeconds = time.Since(startTime).Seconds()
+		return result
+	}
+	if err != nil {
+		return fail(kind, "%v", err)
+	}
+	defer releaseOutputPath(outputFilePath)
+
+	// 先写入临时文件，成功后再重命名，避免中断时留下不完整的输出文件
+	tempFilePath := tempOutputPath(outputFilePath, id)
+	defer os.Remove(tempFilePath)
+	if !params.Segment.IsZero() {
+		defer removeTempFiles(strings.TrimSuffix(tempFilePath, filepath.Ext(tempFilePath)))
 	}
-	outputFilePath := fmt.Sprintf("%s/%s", outputDirectory, fileName)
 
 	// 获取视频总时长（秒）
 	duration, err := getVideoDuration(inputFilePath)
 	if err != nil {
-		fmt.Printf("警告: 无法获取视频时长: %v\n", err)
+		consolePrintf(ctx, "警告: 无法获取视频时长: %v\n", err)
 		// 即使无法获取时长也继续处理
 		duration = 0
 	}
+	// 截取时进度和码率按截取后的时长计算
+	if !params.Trim.IsZero() {
+		if duration, err = trimmedDuration(params.Trim, duration); err != nil {
+			return fail(TranscodeErrorKind_InvalidParams, "参数无效: %v", err)
+		}
+	}
 
-	// 构建FFmpeg命令
-	cmd, err := buildTranscodeCommand(inputFilePath, outputFilePath, params)
+	// 根据FFmpeg支持的编码器确定实际使用的编码器
+	caps, err := GetFFmpegCapabilities()
 	if err != nil {
-		return fmt.Sprintf("构建命令失败: %v", err)
+		consolePrintf(ctx, "警告: 无法探测FFmpeg支持的编码器: %v\n", err)
+	}
+	encoders, err := resolveEncoders(params, caps)
+	if err != nil {
+		return fail(TranscodeErrorKind_MissingEncoder, "%v", err)
+	}
+	if err := validateQuality(params, encoders); err != nil {
+		return fail(TranscodeErrorKind_InvalidParams, "参数无效: %v", err)
 	}
-	fmt.Printf("命令: %v\n", cmd.Args)
+	result.VideoEncoder, result.AudioEncoder = encoders.Video, encoders.Audio
+	result.Warnings = append(result.Warnings, encoders.Warnings...)
+
+	// 目标大小模式根据时长和音频码率计算视频码率
+	var audioBitrate int64
+	if params.TargetSizeMB > 0 {
+		audioBitrate = targetAudioBitrate(params, result.InputInfo)
+		params, err = applyTargetSize(params, duration, audioBitrate)
+		if err != nil {
+			return fail(TranscodeErrorKind_InvalidParams, "参数无效: %v", err)
+		}
+		result.TargetVideoBitrate, _ = strconv.ParseInt(params.VideoBitrate, 10, 64)
+		consolePrintf(ctx, "目标大小 %gMB，视频码率: %dkbps，音频码率: %dkbps\n", params.TargetSizeMB, result.TargetVideoBitrate/1000, audioBitrate/1000)
+	}
+
+	// 两遍编码共用统计文件，硬件编码器不支持时只编码一遍
+	passes := []encodePass{{AudioBitrate: audioBitrate}}
+	if usesTwoPass(params) {
+		if supportsTwoPass(videoEncoderFamily(encoders)) {
+			logFile := passLogPrefix(id)
+			defer removeTempFiles(logFile)
+			passes = []encodePass{
+				{Number: 1, LogFile: logFile},
+				{Number: 2, LogFile: logFile, AudioBitrate: audioBitrate},
+			}
+		} else {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s 不支持两遍编码，已使用单遍编码", encoders.Video))
+		}
+	}
+	result.Passes = len(passes)
 
-	// 设置管道以便捕获FFmpeg输出
-	stderr, err := cmd.StderrPipe()
+	// 分段输出时确定切分点，按场景分段需要先检测场景切换
+	var segment *segmentPlan
+	if !params.Segment.IsZero() {
+		if params.Segment.Mode == SegmentMode_Scene {
+			consolePrintf(ctx, "正在检测场景切换...\n")
+		}
+		plan, segmentWarnings, err := resolveSegmentPlan(taskCtx, id, inputFilePath, params, result.InputInfo, encoders)
+		if taskCtx.Err() != nil {
+			result.Status = TranscodeResultStatus_Cancelled
+			return fail(TranscodeErrorKind_Cancelled, "转码已取消")
+		}
+		if err != nil {
+			return fail(TranscodeErrorKind_InvalidParams, "分段失败: %v", err)
+		}
+		segment = plan
+		result.Warnings = append(result.Warnings, segmentWarnings...)
+	}
+
+	// 生成需要依次执行的FFmpeg命令
+	var steps []transcodeStep
+	if smartCut != nil {
+		boundaryEncoder, ok := pickEncoder(caps, videoEncoderCandidates(smartCut.Source.Codec))
+		if !ok {
+			return fail(TranscodeErrorKind_MissingEncoder, "FFmpeg不支持视频编码器: %s", strings.Join(videoEncoderCandidates(smartCut.Source.Codec), "、"))
+		}
+		prefix := smartCutPrefix(id)
+		defer removeTempFiles(prefix)
+		smartCutSteps, segments, ignored := buildSmartCutSteps(inputFilePath, result.InputInfo, tempFilePath, params, encoders, boundaryEncoder, *smartCut, prefix, duration)
+		if err := writeConcatList(smartCutListPath(prefix), segments); err != nil {
+			return fail(classifyFileError(err), "创建临时文件失败: %v", err)
+		}
+		steps = smartCutSteps
+		result.Warnings = append(result.Warnings, ignored...)
+		consolePrintf(ctx, "智能切割: 关键帧 %s - %s 之间直接复制，边界片段使用 %s 重新编码\n", formatSeconds(smartCut.FirstKeyframe), formatSeconds(smartCut.LastKeyframe), boundaryEncoder)
+	} else {
+		for _, pass := range passes {
+			step := transcodeStep{Args: buildTranscodeArgs(inputFilePath, result.InputInfo, tempFilePath, params, encoders, pass, segment), Duration: duration}
+			if len(passes) > 1 {
+				step.Name = fmt.Sprintf("第%d遍编码", pass.Number)
+			}
+			steps = append(steps, step)
+		}
+		_, ignored := videoEncodeArgs(params, encoders, passes[len(passes)-1])
+		result.Warnings = append(result.Warnings, ignored...)
+	}
+	// 响度标准化先分析整个音频，之后的步骤按分析结果调整音量
+	if params.Loudness.Enabled {
+		steps = addLoudnormSteps(steps, inputFilePath, params, result.InputInfo, duration, &result)
+	}
+	// 先导出内嵌字幕，结果中的命令行仍为转码的命令
+	var exports []subtitleExport
+	if params.SoftSubtitles.Extract != SubtitleFormat_None {
+		var exportWarnings []string
+		exports, exportWarnings = planSubtitleExports(id, inputFilePath, outputFilePath, params, result.InputInfo)
+		defer releaseSubtitleExports(exports)
+		result.Warnings = append(result.Warnings, exportWarnings...)
+		if len(exports) > 0 {
+			steps = append([]transcodeStep{{Name: "导出字幕", Args: buildSubtitleExportArgs(inputFilePath, params.Trim, exports), Duration: duration}}, steps...)
+		}
+	}
+	for _, warning := range result.Warnings {
+		consolePrintf(ctx, "警告: %s\n", warning)
+	}
+
+	if kind, err := runTranscodeSteps(ctx, taskCtx, id, steps, duration, &result); err != nil {
+		return fail(kind, "%v", err)
+	}
+	if segment != nil {
+		if err := commitSegmentOutputs(ctx, id, tempFilePath, outputFilePath, segment.Pattern, &result); err != nil {
+			return fail(classifyFileError(err), "保存输出文件失败: %v", err)
+		}
+	} else if err := commitTranscodeOutput(ctx, id, tempFilePath, outputFilePath, &result); err != nil {
+		return fail(classifyFileError(err), "保存输出文件失败: %v", err)
+	}
+	commitSubtitleExports(exports, &result)
+
+	consolePrintf(ctx, "处理视频成功: %s\n", outputFilePath)
+	result.Status = TranscodeResultStatus_Success
+	result.ElapsedSeconds = time.Since(startTime).Seconds()
+	return result
+}
+
+// validateTranscodeParams 校验不依赖输入文件的参数，启动FFmpeg前调用
+func validateTranscodeParams(params TranscodeParams) error {
+	if err := validateVideoEncodeParams(params); err != nil {
+		return err
+	}
+	if err := validateTwoPassParams(params); err != nil {
+		return err
+	}
+	if err := validateTrim(params.Trim); err != nil {
+		return err
+	}
+	if err := validateSegment(params.Segment); err != nil {
+		return err
+	}
+	if err := validateLoudness(params.Loudness); err != nil {
+		return err
+	}
+	if err := validateAudioParams(params); err != nil {
+		return err
+	}
+	if err := validateStreamMapping(params.Streams); err != nil {
+		return err
+	}
+	if err := validateBurnSubtitle(params.BurnSubtitle); err != nil {
+		return err
+	}
+	if err := validateSoftSubtitles(params.SoftSubtitles); err != nil {
+		return err
+	}
+	return validateContainer(params.Container)
+}
+
+// prepareOutputPath 生成输出文件路径并按同名文件策略占用，返回true表示按策略跳过
+//
+// 返回值:
+//
+//	string: 实际使用的输出文件路径，分段输出时为不带分段后缀的路径，成功时调用方需要在结束后调用 releaseOutputPath
+//	bool: 输出文件已存在且策略为跳过
+//	TranscodeErrorKind: 失败时的错误类型
+//	error: 失败原因
+func prepareOutputPath(job TranscodeJob, inputInfo *VideoInfo) (string, bool, TranscodeErrorKind, error) {
+	outputFilePath, err := getOutputFilePath(GetOutputDirectory(), job, inputInfo)
 	if err != nil {
-		return fmt.Sprintf("创建stderr管道失败: %v", err)
+		return "", false, TranscodeErrorKind_Unknown, fmt.Errorf("生成输出文件名失败: %v", err)
 	}
+	if err := CreateFolder(filepath.Dir(outputFilePath)); err != nil {
+		return outputFilePath, false, classifyFileError(err), fmt.Errorf("创建输出目录失败: %v", err)
+	}
+	exists := FileExists
+	policy := collisionPolicyFor(outputFilePath, job.Path)
+	if segment := job.Params.Segment; !segment.IsZero() {
+		exists = func(p string) bool { return len(existingSegmentParts(p, segment.pattern())) > 0 }
+		// 输入文件是已有的某一段时同样不能覆盖
+		for _, part := range existingSegmentParts(outputFilePath, segment.pattern()) {
+			if outputPathKey(segmentPartPath(outputFilePath, segment.pattern(), part)) == outputPathKey(job.Path) {
+				policy = OutputCollisionPolicy_Rename
+			}
+		}
+	}
+	outputFilePath, skip, err := reserveOutputPathFunc(outputFilePath, policy, exists)
+	if err != nil {
+		return outputFilePath, skip, TranscodeErrorKind_OutputExists, fmt.Errorf("%v: %s", err, outputFilePath)
+	}
+	return outputFilePath, skip, "", nil
+}
 
-	// 启动FFmpeg进程
-	if err := cmd.Start(); err != nil {
-		return fmt.Sprintf("启动FFmpeg失败: %v", err)
+// runTranscodeSteps 依次执行每一步FFmpeg命令并发送进度，进度按步数合并计算
+//
+// 命令参数、退出码和stderr的最后几行记录到 result 中，任务被取消时 result 的状态设为已取消
+func runTranscodeSteps(ctx, taskCtx context.Context, id string, steps []transcodeStep, duration float64, result *TranscodeResult) (TranscodeErrorKind, error) {
+	ffmpegPath, err := IsFFmpegAvailable()
+	if err != nil {
+		return TranscodeErrorKind_FFmpegUnavailable, fmt.Errorf("构建命令失败: ffmpeg不可用: %v", err)
 	}
 
-	// 创建scanner读取FFmpeg输出
-	scanner := bufio.NewScanner(stderr)
-	progressRegex := regexp.MustCompile(`time=([0-9:.]+)`)
+	parser := newProgressParser(id, duration)
+	var tail stderrTail
+	progressEnded := false
+	for i, step := range steps {
+		parser.startPass(i+1, len(steps), step.Duration)
+
+		args := step.Args
+		if step.Prepare != nil {
+			if args, err = step.Prepare(args); err != nil {
+				return TranscodeErrorKind_Unknown, err
+			}
+		}
+
+		// 构建FFmpeg命令
+		cmd := createCommandContext(taskCtx, ffmpegPath, args...)
+		result.Args = cmd.Args
+		consolePrintf(ctx, "命令: %v\n", cmd.Args)
+
+		// 设置管道以便捕获FFmpeg输出
+		stderr, err := cmd.StderrPipe()
+		if err != nil {
+			return TranscodeErrorKind_Unknown, fmt.Errorf("创建stderr管道失败: %v", err)
+		}
 
-	// 在goroutine中读取进度
-	var progressDone = make(chan struct{}) // 添加信号通道
-	go func() {
-		defer close(progressDone) // 处理完后关闭通道
-		for scanner.Scan() {
-			line := scanner.Text()
-			matches := progressRegex.FindStringSubmatch(line)
-			if len(matches) > 1 {
-				currentTime := matches[1]
+		// 启动FFmpeg进程
+		if err := cmd.Start(); err != nil {
+			return TranscodeErrorKind_FFmpegUnavailable, fmt.Errorf("启动FFmpeg失败: %v", err)
+		}
+		attachTranscodeCommand(id, cmd)
+
+		// 创建scanner读取FFmpeg输出
+		scanner := bufio.NewScanner(stderr)
+		tail = stderrTail{}
+
+		// 在goroutine中读取进度
+		var progressDone = make(chan struct{}) // 添加信号通道
+		go func() {
+			defer close(progressDone) // 处理完后关闭通道
+			for scanner.Scan() {
+				line := scanner.Text()
+				tail.add(line)
+				if step.Output != nil {
+					step.Output(line)
+				}
+				if !parser.parseLine(line) {
+					continue
+				}
+				progress := parser.current
+				if progress.Completed {
+					progressEnded = true
+				}
+				if !parser.shouldEmit() {
+					continue
+				}
+				// 使用 \r 实现行内更新，并添加足够的空格来覆盖之前的输出
 				if duration > 0 {
-					// 计算并显示百分比进度
-					currentSeconds := timeToSeconds(currentTime)
-					percentage := (currentSeconds / duration) * 100
-					if percentage > 100 {
-						percentage = 100
-					}
-					// 使用 \r 实现行内更新，并添加足够的空格来覆盖之前的输出
-					fmt.Printf("\r进度: %.2f%% (已处理时间: %s)     ", percentage, currentTime)
-					wailsRuntime.EventsEmit(ctx, "videoTranscodeProcessor", id, percentage, currentTime)
+					consolePrintf(ctx, "\r进度: %.2f%% (已处理时间: %s, 速度: %.2fx, 剩余: %.0f秒)     ", progress.Percentage, progress.OutTime, progress.Speed, progress.EtaSeconds)
 				} else {
-					// 只显示已处理时间
-					fmt.Printf("\r已处理时间: %s     ", currentTime)
+					consolePrintf(ctx, "\r已处理时间: %s     ", progress.OutTime)
 				}
+				emitEvent(ctx, "videoTranscodeProcessor", progress)
 			}
+		}()
+
+		// 等待FFmpeg进程完成，需先等待进度goroutine读完stderr再调用Wait
+		<-progressDone
+		err = cmd.Wait()
+		detachTranscodeCommand(id, cmd)
+		result.ExitCode = exitCodeFromError(err)
+		result.StderrTail = tail.lines
+
+		if taskCtx.Err() != nil {
+			// 任务被取消，未完成的临时文件由调用方删除
+			consolePrintf(ctx, "\n任务已取消: %s\n", result.InputPath)
+			result.Status = TranscodeResultStatus_Cancelled
+			return TranscodeErrorKind_Cancelled, fmt.Errorf("转码已取消")
+		}
+		if err != nil {
+			if step.Name != "" {
+				return classifyTranscodeError(tail.lines), fmt.Errorf("FFmpeg%s失败: %v", step.Name, err)
+			}
+			return classifyTranscodeError(tail.lines), fmt.Errorf("FFmpeg处理失败: %v", err)
 		}
-	}()
-
-	// 等待FFmpeg进程完成
-	if err := cmd.Wait(); err != nil {
-		return fmt.Sprintf("FFmpeg处理失败: %v", err)
 	}
 
-	// 等待进度显示goroutine完成
-	<-progressDone
-
-	// 显示最终100%进度
-	if duration > 0 {
-		fmt.Printf("\r进度: 100.00%% (已完成) \n")
+	// FFmpeg正常退出但没有输出 progress=end 时，补发完成进度
+	if !progressEnded {
+		parser.parseLine("progress=end")
+		emitEvent(ctx, "videoTranscodeProcessor", parser.current)
+	}
+	consolePrintf(ctx, "\r进度: 100.00%% (已完成) \n")
+	return "", nil
+}
 
-	} else {
-		fmt.Printf("\r处理完成! \n")
+// commitTranscodeOutput 将临时文件重命名为输出文件，并获取输出视频的信息
+func commitTranscodeOutput(ctx context.Context, id, tempFilePath, outputFilePath string, result *TranscodeResult) error {
+	if err := commitOutputFile(tempFilePath, outputFilePath); err != nil {
+		return err
 	}
-	wailsRuntime.EventsEmit(ctx, "videoTranscodeProcessor", id, 100, "completed")
 	videoInfo, err := GetVideoInfo(outputFilePath)
 	if err == nil {
 		videoInfo.ID = id
-		wailsRuntime.EventsEmit(ctx, "videoTranscodeSuccess", videoInfo)
+		result.OutputInfo = &videoInfo
+		if result.InputInfo != nil && result.InputInfo.Size > 0 {
+			result.SizeRatio = float64(videoInfo.Size) / float64(result.InputInfo.Size)
+		}
+		emitEvent(ctx, "videoTranscodeSuccess", videoInfo)
 	}
+	return nil
+}
 
-	fmt.Printf("处理视频成功: %s\n", outputFilePath)
-	return "OK"
+// transcodeStep 转码任务中的一次FFmpeg调用，两遍编码、智能切割和响度标准化需要依次执行多次
+type transcodeStep struct {
+	Name     string                                // 步骤名称，用于错误信息，只有一步时为空
+	Args     []string                              // FFmpeg参数
+	Duration float64                               // 这一步处理的时长（秒），用于计算进度
+	Output   func(line string)                     // 接收stderr的每一行，用于读取分析结果
+	Prepare  func(args []string) ([]string, error) // 执行前修改参数，参数依赖前面步骤的分析结果时使用
 }
 
-// buildTranscodeCommand 构建FFmpeg转码命令
-func buildTranscodeCommand(inputFilePath string, outputFilePath string, params TranscodeParams) (*exec.Cmd, error) {
+// buildTranscodeArgs 生成FFmpeg转码参数，不依赖FFmpeg和硬件，可以直接检查生成的参数
+//
+// 两遍编码的第一遍只分析视频，不处理音频也不写入输出文件；inputInfo 为探测到的输入信息，探测失败时为nil；
+// segment 不为nil时使用segment封装分段输出，outputFilePath 为临时文件路径，每段的文件名在扩展名前加上序号
+func buildTranscodeArgs(inputFilePath string, inputInfo *VideoInfo, outputFilePath string, params TranscodeParams, encoders encoderSelection, pass encodePass, segment *segmentPlan) []string {
 	// 构建FFmpeg命令参数
 	var args []string
 
+	// 输出到任务独占的临时文件，直接覆盖，避免FFmpeg等待确认
+	args = append(args, "-y")
+
+	// 硬件设备初始化参数需要放在输入文件之前
+	if encoders.Backend != nil {
+		args = append(args, encoders.Backend.inputArgs(encoders.Device)...)
+	}
+
+	// 截取的开始时间放在输入文件之前
+	args = append(args, trimInputArgs(params.Trim)...)
+
 	// 输入文件
 	args = append(args, "-i", inputFilePath)
 
+	// 封装的外部字幕，第一遍编码不需要
+	if pass.Number != 1 {
+		args = append(args, subtitleInputArgs(params)...)
+	}
+
 	// 添加CPU线程数参数
 	if params.CpuThreads > 0 {
 		args = append(args, "-threads", fmt.Sprintf("%d", params.CpuThreads))
 	}
 
-	// 处理视频编码参数
-	videoCodec := getVideoCodecFormat(params)
-	args = append(args, "-c:v", videoCodec)
+	// 选择输出的音轨、字幕和附件
+	args = append(args, streamMapArgs(inputFilePath, params, inputInfo, pass)...)
 
-	// 处理音频编码参数
-	audioCodec := getAudioCodecFormat(params)
-	args = append(args, "-c:a", audioCodec)
+	// 视频和音频编码器，只输出音频的格式不包含视频流
+	format, hasFormat := getContainerFormat(params.Container)
+	audioOnly := hasFormat && format.AudioOnly
+	if audioOnly {
+		args = append(args, "-vn")
+	} else {
+		args = append(args, "-c:v", encoders.Video)
+	}
+	args = append(args, audioOutputArgs(params, encoders, pass)...)
 
+	// 如果有视频滤镜，则应用到命令
+	if videoFilters := videoFilterChain(inputFilePath, params, encoders); len(videoFilters) > 0 {
+		args = append(args, "-vf", strings.Join(videoFilters, ","))
+	}
+
+	// 添加帧率参数
+	if params.Fps != "copy" {
+		args = append(args, "-r", params.Fps)
+	}
+
+	// 码率控制和编码器参数
+	if !audioOnly {
+		encodeArgs, _ := videoEncodeArgs(params, encoders, pass)
+		args = append(args, encodeArgs...)
+	}
+
+	// 分段时在切分点插入关键帧，两遍编码的两遍需要相同
+	if segment != nil && !audioOnly && encoders.Video != "copy" {
+		args = append(args, segmentKeyframeArgs(*segment)...)
+	}
+
+	// 截取时长
+	args = append(args, trimOutputArgs(params.Trim, encoders)...)
+
+	// 封装格式参数
+	if segment != nil && pass.Number != 1 {
+		args = append(args, segmentMuxArgs(*segment, format, hasFormat, encoders, inputInfo)...)
+		outputFilePath = tempSegmentPattern(outputFilePath)
+	} else if hasFormat && pass.Number != 1 {
+		args = append(args, format.muxArgs(encoders, inputInfo)...)
+	}
+
+	// 添加进度报告参数
+	args = append(args, "-progress", "pipe:2", "-nostats")
+
+	// 输出文件，第一遍的结果直接丢弃
+	if pass.Number == 1 {
+		args = append(args, "-f", "null", os.DevNull)
+		return args
+	}
+	args = append(args, outputFilePath)
+	return args
+}
+
+// videoFilterChain 根据参数生成视频滤镜，依次为缩放、水印、旋转、烧录字幕，使用硬件编码时最后上传到GPU
+func videoFilterChain(inputFilePath string, params TranscodeParams, encoders encoderSelection) []string {
 	// 构建视频滤镜链
 	var videoFilters []string
 
@@ -185,34 +617,19 @@ func buildTranscodeCommand(inputFilePath string, outputFilePath string, params T
 		videoFilters = append(videoFilters, rotationFilter)
 	}
 
-	// 如果有视频滤镜，则应用到命令
-	if len(videoFilters) > 0 {
-		args = append(args, "-vf", strings.Join(videoFilters, ","))
+	// 字幕在旋转之后烧录，保证文字方向正确
+	if params.BurnSubtitle.Enabled() {
+		videoFilters = append(videoFilters, burnSubtitleFilter(inputFilePath, params))
 	}
 
-	// 添加帧率参数
-	if params.Fps != "copy" {
-		args = append(args, "-r", params.Fps)
-	}
-
-	// 处理视频比特率参数
-	if params.VideoBitrate != "copy" {
-		args = append(args, "-b:v", params.VideoBitrate)
-	}
-
-	// 添加进度报告参数
-	args = append(args, "-progress", "pipe:2", "-nostats")
-
-	// 输出文件
-	args = append(args, outputFilePath)
-
-	ffmpegPath, err := IsFFmpegAvailable()
-	if err != nil {
-		return nil, fmt.Errorf("ffmpeg不可用: %v", err)
+	// 硬件编码器需要的上传滤镜放在最后，前面的滤镜都在CPU上处理
+	if encoders.Backend != nil {
+		if uploadFilter := encoders.Backend.uploadFilter(params.PixelFormat); uploadFilter != "" {
+			videoFilters = append(videoFilters, uploadFilter)
+		}
 	}
 
-	cmd := createCommand(ffmpegPath, args...)
-	return cmd, nil
+	return videoFilters
 }
 
 // getRotationFilter 获取旋转滤镜
@@ -229,55 +646,6 @@ func getRotationFilter(rotation string) string {
 	}
 }
 
-// getVideoCodecFormat 获取视频编码格式
-func getVideoCodecFormat(params TranscodeParams) string {
-	switch params.VideoCodec {
-	case "h264":
-		if params.UseGpu {
-			return "h264_nvenc"
-		} else {
-			return "libx264"
-		}
-	case "h265":
-		if params.UseGpu {
-			return "hevc_nvenc"
-		} else {
-			return "libx265"
-		}
-	default:
-		// 如果要加水印，不能使用copy
-		if params.WatermarkContent != "" || params.WatermarkImage != "" {
-			// 默认使用libx264进行重新编码
-			if params.UseGpu {
-				return "h264_nvenc"
-			} else {
-				return "libx264"
-			}
-		}
-		return "copy"
-	}
-}
-
-// getAudioCodecFormat 获取音频编码格式
-func getAudioCodecFormat(params TranscodeParams) string {
-	switch params.AudioCodec {
-	case "aac":
-		if params.UseGpu {
-			return "aac"
-		} else {
-			return "libfdk_aac"
-		}
-	case "mp3":
-		if params.UseGpu {
-			return "mp3"
-		} else {
-			return "libmp3lame"
-		}
-	default:
-		return "copy"
-	}
-}
-
 // getVideoDuration 获取视频时长（秒）
 func getVideoDuration(filePath string) (float64, error) {
 	args := []string{
@@ -361,8 +729,7 @@ func getWatermarkPlacementText(text string, placement WatermarkPlacement) string
 
 // 图片水印
 func getWatermarkPlacementImage(imagePath string, placement WatermarkPlacement) string {
-	normalizedPath := strings.ReplaceAll(strings.ReplaceAll(path.Clean(imagePath), "\\", "/"), ":", "\\:")
-	quotedPath := fmt.Sprintf("'%s'", normalizedPath)
+	quotedPath := quoteFilterPath(imagePath)
 	var filter string
 	switch placement {
 	case WatermarkPlacement_Random:
@@ -387,6 +754,13 @@ func getWatermarkPlacementImage(imagePath string, placement WatermarkPlacement)
 	return filter
 }
 
+// quoteFilterPath 转义滤镜参数中的文件路径，反斜杠改为斜杠，盘符后的冒号和路径中的单引号需要转义
+func quoteFilterPath(filePath string) string {
+	normalizedPath := strings.ReplaceAll(strings.ReplaceAll(path.Clean(filePath), "\\", "/"), ":", "\\:")
+	normalizedPath = strings.ReplaceAll(normalizedPath, "'", "'\\\\\\''")
+	return fmt.Sprintf("'%s'", normalizedPath)
+}
+
 // escapeTextForFFmpeg 为FFmpeg转义特殊字符，特别是中文字符
 func escapeTextForFFmpeg(text string) string {
 	// 在Windows下，对特殊字符进行转义
@@ -0,0 +1,179 @@
+package process
+
+import (
+	"os"
+	"testing"
+)
+
+// indexArgs 查找连续的参数 want 在 args 中的位置，不存在时返回-1
+func indexArgs(args []string, want ...string) int {
+	for i := 0; i+len(want) <= len(args); i++ {
+		match := true
+		for j := range want {
+			if args[i+j] != want[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestBuildTranscodeArgs(t *testing.T) {
+	base := TranscodeParams{
+		VideoHeight:  "copy",
+		Fps:          "copy",
+		VideoBitrate: "copy",
+		Rotate:       VideoRotate_copy,
+	}
+	software := encoderSelection{Video: "libx264", Audio: "aac"}
+	vaapi := mustEncoderBackend(t, HardwareBackend_VAAPI)
+	nvenc := mustEncoderBackend(t, HardwareBackend_NVENC)
+	with := func(modify func(*TranscodeParams)) TranscodeParams {
+		params := base
+		modify(&params)
+		return params
+	}
+
+	tests := []struct {
+		name     string
+		params   TranscodeParams
+		encoders encoderSelection
+		pass     encodePass
+		segment  *segmentPlan
+		want     [][]string // 必须出现的连续参数
+		before   [][2]string
+		absent   []string
+		last     []string // 最后的参数
+	}{
+		{
+			name:     "软件编码不做处理",
+			params:   base,
+			encoders: software,
+			want:     [][]string{{"-i", "in.mp4"}, {"-c:v", "libx264"}, {"-c:a", "aac"}, {"-progress", "pipe:2", "-nostats"}},
+			absent:   []string{"-vf", "-r", "-threads", "-crf", "-b:v"},
+			last:     []string{"out.mp4"},
+		},
+		{
+			name: "缩放帧率和线程数",
+			params: with(func(p *TranscodeParams) {
+				p.VideoHeight, p.Fps, p.CpuThreads = "720", "30", 4
+			}),
+			encoders: software,
+			want:     [][]string{{"-threads", "4"}, {"-vf", "scale=-1:720"}, {"-r", "30"}},
+			last:     []string{"out.mp4"},
+		},
+		{
+			name: "CRF 0 为无损",
+			params: with(func(p *TranscodeParams) {
+				p.RateControl, p.Quality = RateControl_CRF, intPtr(0)
+			}),
+			encoders: software,
+			want:     [][]string{{"-crf", "0"}},
+		},
+		{
+			name:     "CRF未指定质量时使用编码器默认值",
+			params:   with(func(p *TranscodeParams) { p.RateControl = RateControl_CRF }),
+			encoders: encoderSelection{Video: "libx265", Audio: "aac"},
+			want:     [][]string{{"-crf", "28"}},
+		},
+		{
+			name: "VA-API初始化设备并上传到显存",
+			params: with(func(p *TranscodeParams) {
+				p.VideoHeight, p.PixelFormat, p.Quality = "720", "yuv420p10le", intPtr(20)
+			}),
+			encoders: encoderSelection{Video: "hevc_vaapi", Audio: "aac", Backend: &vaapi},
+			want:     [][]string{{"-vaapi_device", defaultVAAPIDevice}, {"-vf", "scale=-1:720,format=p010,hwupload"}, {"-qp", "20"}},
+			before:   [][2]string{{"-vaapi_device", "-i"}},
+			absent:   []string{"-pix_fmt"},
+		},
+		{
+			name:     "NVENC恒定质量",
+			params:   with(func(p *TranscodeParams) { p.RateControl, p.Quality = RateControl_CRF, intPtr(19) }),
+			encoders: encoderSelection{Video: "h264_nvenc", Audio: "aac", Backend: &nvenc},
+			want:     [][]string{{"-c:v", "h264_nvenc"}, {"-rc", "vbr", "-cq", "19", "-b:v", "0"}},
+			absent:   []string{"-vaapi_device", "-vf"},
+		},
+		{
+			name: "截取时复制视频流",
+			params: with(func(p *TranscodeParams) {
+				p.Trim = TrimRange{Start: "10", Duration: "5"}
+			}),
+			encoders: encoderSelection{Video: "copy", Audio: "copy"},
+			want:     [][]string{{"-ss", "10.000"}, {"-t", "5.000"}, {"-avoid_negative_ts", "make_zero"}},
+			before:   [][2]string{{"-ss", "-i"}, {"-i", "-t"}},
+		},
+		{
+			name: "两遍编码的第一遍",
+			params: with(func(p *TranscodeParams) {
+				p.RateControl, p.VideoBitrate = RateControl_ABR, "2M"
+			}),
+			encoders: software,
+			pass:     encodePass{Number: 1, LogFile: "passlog"},
+			want:     [][]string{{"-an"}, {"-b:v", "2M"}, {"-pass", "1", "-passlogfile", "passlog"}},
+			absent:   []string{"-c:a", "out.mp4"},
+			last:     []string{"-f", "null", os.DevNull},
+		},
+		{
+			name: "两遍编码的第二遍",
+			params: with(func(p *TranscodeParams) {
+				p.RateControl, p.VideoBitrate = RateControl_ABR, "2M"
+			}),
+			encoders: software,
+			pass:     encodePass{Number: 2, LogFile: "passlog", AudioBitrate: 128000},
+			want:     [][]string{{"-c:a", "aac", "-b:a", "128000"}, {"-pass", "2", "-passlogfile", "passlog"}},
+			last:     []string{"out.mp4"},
+		},
+		{
+			name:     "只输出音频",
+			params:   with(func(p *TranscodeParams) { p.Container = OutputContainer_MP3 }),
+			encoders: encoderSelection{Video: "libx264", Audio: "libmp3lame"},
+			want:     [][]string{{"-vn"}, {"-c:a", "libmp3lame"}},
+			absent:   []string{"-c:v", "-crf"},
+		},
+		{
+			name:     "静音",
+			params:   with(func(p *TranscodeParams) { p.MuteAudio = true }),
+			encoders: software,
+			want:     [][]string{{"-an"}},
+			absent:   []string{"-c:a"},
+		},
+		{
+			name:     "分段输出",
+			params:   base,
+			encoders: software,
+			segment:  &segmentPlan{Interval: 60, Pattern: defaultSegmentPattern},
+			want:     [][]string{{"-force_key_frames", "expr:gte(t,n_forced*60.000)"}, {"-f", "segment"}, {"-segment_time", "60.000"}},
+			last:     []string{tempSegmentPattern("out.mp4")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := buildTranscodeArgs("in.mp4", nil, "out.mp4", tt.params, tt.encoders, tt.pass, tt.segment)
+			if len(args) == 0 || args[0] != "-y" {
+				t.Fatalf("参数应以 -y 开头: %q", args)
+			}
+			for _, want := range tt.want {
+				if indexArgs(args, want...) < 0 {
+					t.Errorf("缺少参数 %q: %q", want, args)
+				}
+			}
+			for _, pair := range tt.before {
+				if i, j := indexArgs(args, pair[0]), indexArgs(args, pair[1]); i < 0 || j < 0 || i > j {
+					t.Errorf("%s 应在 %s 之前: %q", pair[0], pair[1], args)
+				}
+			}
+			for _, absent := range tt.absent {
+				if indexArgs(args, absent) >= 0 {
+					t.Errorf("不应包含参数 %s: %q", absent, args)
+				}
+			}
+			if tt.last != nil && indexArgs(args, tt.last...) != len(args)-len(tt.last) {
+				t.Errorf("最后的参数应为 %q: %q", tt.last, args)
+			}
+		})
+	}
+}
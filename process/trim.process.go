@@ -0,0 +1,155 @@
+package process
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type TrimMode string
+
+const (
+	TrimMode_Fast     TrimMode = "fast"     // 快速切割，直接复制视频流时起点对齐到之前的关键帧
+	TrimMode_Accurate TrimMode = "accurate" // 逐帧精确切割，需要重新编码视频
+	TrimMode_Smart    TrimMode = "smart"    // 智能切割，只重新编码开头和结尾不完整的GOP，中间直接复制
+)
+
+// TrimRange 截取的时间范围，时间可以写作秒数（90.5）或时间码（1:30、00:01:30.5），全部为空时不截取
+type TrimRange struct {
+	Start    string   `yaml:"start" json:"start"`       // 开始时间，为空时从头开始
+	End      string   `yaml:"end" json:"end"`           // 结束时间，不能与时长同时指定
+	Duration string   `yaml:"duration" json:"duration"` // 截取时长
+	Mode     TrimMode `yaml:"mode" json:"mode"`         // 切割方式，为空时使用快速切割
+}
+
+// IsZero 是否没有指定截取范围
+func (t TrimRange) IsZero() bool {
+	return t.Start == "" && t.End == "" && t.Duration == ""
+}
+
+// parseTrimTime 解析时间，支持秒数和 [[时:]分:]秒 格式，时间码中的分和秒必须小于60
+func parseTrimTime(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	parts := strings.Split(value, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("无效的时间: %s", value)
+	}
+	var seconds float64
+	for i, part := range parts {
+		number, err := strconv.ParseFloat(part, 64)
+		if err != nil || number < 0 || math.IsNaN(number) || math.IsInf(number, 0) {
+			return 0, fmt.Errorf("无效的时间: %s", value)
+		}
+		if i > 0 && number >= 60 {
+			return 0, fmt.Errorf("无效的时间: %s，分和秒必须小于60", value)
+		}
+		seconds = seconds*60 + number
+	}
+	return seconds, nil
+}
+
+// bounds 获取截取的开始时间和时长（秒），时长为0表示截取到结尾
+func (t TrimRange) bounds() (float64, float64, error) {
+	var start, length float64
+	var err error
+	if t.Start != "" {
+		if start, err = parseTrimTime(t.Start); err != nil {
+			return 0, 0, fmt.Errorf("无效的开始时间: %s", t.Start)
+		}
+	}
+	switch {
+	case t.End != "" && t.Duration != "":
+		return 0, 0, fmt.Errorf("结束时间和截取时长不能同时指定")
+	case t.End != "":
+		end, err := parseTrimTime(t.End)
+		if err != nil {
+			return 0, 0, fmt.Errorf("无效的结束时间: %s", t.End)
+		}
+		if end <= start {
+			return 0, 0, fmt.Errorf("结束时间 %s 必须晚于开始时间 %s", t.End, t.Start)
+		}
+		length = end - start
+	case t.Duration != "":
+		if length, err = parseTrimTime(t.Duration); err != nil {
+			return 0, 0, fmt.Errorf("无效的截取时长: %s", t.Duration)
+		}
+		if length == 0 {
+			return 0, 0, fmt.Errorf("截取时长不能为0")
+		}
+	}
+	return start, length, nil
+}
+
+// validateTrim 校验截取范围
+func validateTrim(trim TrimRange) error {
+	switch trim.Mode {
+	case "", TrimMode_Fast, TrimMode_Accurate, TrimMode_Smart:
+	default:
+		return fmt.Errorf("无效的切割方式: %s，可用: fast、accurate、smart", trim.Mode)
+	}
+	_, _, err := trim.bounds()
+	return err
+}
+
+// trimmedDuration 获取截取后的时长，用于计算进度和目标大小的码率，源视频时长未知时返回截取时长
+func trimmedDuration(trim TrimRange, duration float64) (float64, error) {
+	start, length, err := trim.bounds()
+	if err != nil {
+		return 0, err
+	}
+	if duration <= 0 {
+		return length, nil
+	}
+	if start >= duration {
+		return 0, fmt.Errorf("开始时间 %s 超过了视频时长 %.1f 秒", trim.Start, duration)
+	}
+	remaining := duration - start
+	if length == 0 || length > remaining {
+		return remaining, nil
+	}
+	return length, nil
+}
+
+// resolveTrimParams 精确切割时不能直接复制视频流，改为重新编码为与源视频相同的编码
+func resolveTrimParams(params TranscodeParams, inputInfo *VideoInfo) (TranscodeParams, []string) {
+	if params.Trim.IsZero() || params.Trim.Mode != TrimMode_Accurate || videoCodecName(params) != "" {
+		return params, nil
+	}
+	params.VideoCodec = "h264"
+	if inputInfo != nil {
+		if codec, ok := sourceVideoCodecs[inputInfo.VideoCodec]; ok {
+			params.VideoCodec = codec
+		}
+	}
+	return params, []string{fmt.Sprintf("精确切割需要重新编码视频，已使用 %s 编码", params.VideoCodec)}
+}
+
+// trimInputArgs 截取的开始时间，放在输入文件之前，FFmpeg会直接跳转而不是解码前面的内容
+func trimInputArgs(trim TrimRange) []string {
+	start, _, err := trim.bounds()
+	if err != nil || start <= 0 {
+		return nil
+	}
+	return []string{"-ss", formatSeconds(start)}
+}
+
+// trimOutputArgs 截取的时长，直接复制视频流时修正起始时间戳，避免开头出现负时间戳
+func trimOutputArgs(trim TrimRange, encoders encoderSelection) []string {
+	if trim.IsZero() {
+		return nil
+	}
+	var args []string
+	if _, length, err := trim.bounds(); err == nil && length > 0 {
+		args = append(args, "-t", formatSeconds(length))
+	}
+	if encoders.Video == "copy" {
+		args = append(args, "-avoid_negative_ts", "make_zero")
+	}
+	return args
+}
+
+// formatSeconds 将秒数格式化为FFmpeg时间参数，精确到毫秒
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', 3, 64)
+}
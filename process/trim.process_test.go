@@ -0,0 +1,74 @@
+package process
+
+import "testing"
+
+func TestParseTrimTime(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{value: "0", want: 0},
+		{value: "90.5", want: 90.5},
+		{value: " 12 ", want: 12},
+		{value: "1:30", want: 90},
+		{value: "00:01:30.5", want: 90.5},
+		{value: "1:00:00", want: 3600},
+		{value: "100:00", want: 6000},
+		{value: "25:59:59.999", want: 93599.999},
+		{value: "", wantErr: true},
+		{value: "abc", wantErr: true},
+		{value: "-5", wantErr: true},
+		{value: "1:-30", wantErr: true},
+		{value: "1:2:3:4", wantErr: true},
+		{value: "1::30", wantErr: true},
+		{value: "NaN", wantErr: true},
+		{value: "Inf", wantErr: true},
+		{value: "+Inf", wantErr: true},
+		{value: "1:NaN", wantErr: true},
+		{value: "1:60", wantErr: true},
+		{value: "1:75", wantErr: true},
+		{value: "1:60:00", wantErr: true},
+		{value: "0:0:60", wantErr: true},
+		{value: "0:59.9999", want: 59.9999},
+	}
+	for _, tt := range tests {
+		got, err := parseTrimTime(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTrimTime(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseTrimTime(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestTrimRangeBounds(t *testing.T) {
+	tests := []struct {
+		name       string
+		trim       TrimRange
+		wantStart  float64
+		wantLength float64
+		wantErr    bool
+	}{
+		{name: "不截取", trim: TrimRange{}},
+		{name: "只有开始时间", trim: TrimRange{Start: "10"}, wantStart: 10},
+		{name: "开始和结束时间", trim: TrimRange{Start: "1:00", End: "1:30"}, wantStart: 60, wantLength: 30},
+		{name: "开始时间和时长", trim: TrimRange{Start: "5", Duration: "20"}, wantStart: 5, wantLength: 20},
+		{name: "结束时间早于开始时间", trim: TrimRange{Start: "30", End: "10"}, wantErr: true},
+		{name: "结束时间和时长同时指定", trim: TrimRange{End: "10", Duration: "5"}, wantErr: true},
+		{name: "无效的开始时间", trim: TrimRange{Start: "0:99"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, length, err := tt.trim.bounds()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("bounds() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (start != tt.wantStart || length != tt.wantLength) {
+				t.Errorf("bounds() = %v, %v, want %v, %v", start, length, tt.wantStart, tt.wantLength)
+			}
+		})
+	}
+}
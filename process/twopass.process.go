@@ -0,0 +1,145 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// targetSizeOverhead 为封装格式的开销预留的比例
+	targetSizeOverhead = 0.02
+	// minTargetVideoBitrate 目标大小模式下视频码率的下限，低于该值画质已不可用
+	minTargetVideoBitrate = 50_000
+	// defaultAudioBitrate 重新编码音频时的码率，与FFmpeg aac编码器的默认值相同
+	defaultAudioBitrate = 128_000
+	// bytesPerMB 目标大小的单位，按1000计算比按1024更保守，不会超过上传限制
+	bytesPerMB = 1000 * 1000
+)
+
+// encodePass 多遍编码中的一遍，零值表示普通的单遍编码
+type encodePass struct {
+	Number       int    // 当前是第几遍，从1开始
+	LogFile      string // 两遍编码共用的统计文件前缀
+	AudioBitrate int64  // 重新编码音频时指定的码率，0表示使用编码器默认值
+}
+
+// supportsTwoPass 判断编码器是否支持 -pass 两遍编码，硬件编码器和SVT-AV1只能单遍编码
+func supportsTwoPass(family string) bool {
+	switch family {
+	case "libx264", "libx265", "libvpx-vp9", "libaom-av1", "librav1e":
+		return true
+	}
+	return false
+}
+
+// usesTwoPass 判断参数是否需要两遍编码
+func usesTwoPass(params TranscodeParams) bool {
+	return params.TwoPass || params.TargetSizeMB > 0
+}
+
+// validateTwoPassParams 校验两遍编码和目标大小参数
+func validateTwoPassParams(params TranscodeParams) error {
+	if params.TargetSizeMB < 0 {
+		return fmt.Errorf("目标大小不能为负数: %g", params.TargetSizeMB)
+	}
+	if !usesTwoPass(params) {
+		return nil
+	}
+	// 加水印或烧录字幕时即使选择copy也会重新编码
+	if videoCodecName(params) == "" {
+		return fmt.Errorf("两遍编码和目标大小需要重新编码视频，不能直接复制视频流")
+	}
+	if params.TargetSizeMB == 0 {
+		if params.RateControl == RateControl_CRF {
+			return fmt.Errorf("两遍编码不能与CRF同时使用")
+		}
+		if !hasBitrate(params.VideoBitrate) {
+			return fmt.Errorf("两遍编码需要指定视频码率或目标大小")
+		}
+	}
+	return nil
+}
+
+// targetAudioBitrate 估算输出文件中音频的码率，指定了音频码率时使用指定的码率，直接复制音频时使用源视频的音频码率
+func targetAudioBitrate(params TranscodeParams, inputInfo *VideoInfo) int64 {
+	if params.MuteAudio {
+		return 0
+	}
+	if params.AudioCodec != "copy" && hasBitrate(params.AudioBitrate) {
+		if bitrate, err := parseBitrate(params.AudioBitrate); err == nil {
+			return bitrate
+		}
+	}
+	if params.AudioCodec != "copy" {
+		return defaultAudioBitrate
+	}
+	if inputInfo == nil || inputInfo.AudioBitrate <= 0 {
+		// 未知时按默认值估算，宁可视频码率偏低也不要超过目标大小
+		return defaultAudioBitrate
+	}
+	return int64(inputInfo.AudioBitrate)
+}
+
+// targetVideoBitrate 根据目标大小、时长和音频码率计算视频码率（bit/s）
+func targetVideoBitrate(targetSizeMB, duration float64, audioBitrate int64) (int64, error) {
+	if duration <= 0 {
+		return 0, fmt.Errorf("无法获取视频时长，不能按目标大小计算码率")
+	}
+	totalBitrate := targetSizeMB * bytesPerMB * 8 * (1 - targetSizeOverhead) / duration
+	videoBitrate := int64(totalBitrate) - audioBitrate
+	if videoBitrate < minTargetVideoBitrate {
+		return 0, fmt.Errorf("目标大小 %gMB 对于 %.0f 秒的视频过小，视频码率只有 %dkbps", targetSizeMB, duration, max(videoBitrate, 0)/1000)
+	}
+	return videoBitrate, nil
+}
+
+// applyTargetSize 按目标大小计算码率并返回实际使用的参数
+//
+// 目标大小模式使用平均码率，指定了最大码率时使用受限的VBR，CRF和质量值会被忽略
+func applyTargetSize(params TranscodeParams, duration float64, audioBitrate int64) (TranscodeParams, error) {
+	videoBitrate, err := targetVideoBitrate(params.TargetSizeMB, duration, audioBitrate)
+	if err != nil {
+		return params, err
+	}
+	params.VideoBitrate = strconv.FormatInt(videoBitrate, 10)
+	params.RateControl = RateControl_ABR
+	if hasBitrate(params.MaxRate) {
+		params.RateControl = RateControl_VBR
+	}
+	params.Quality = nil
+	return params, nil
+}
+
+// twoPassArgs 生成两遍编码的参数，libx265需要通过 -x265-params 设置
+//
+// 返回值:
+//
+//	[]string: FFmpeg参数
+//	[]string: 需要合并到 -x265-params 的参数
+func twoPassArgs(family string, pass encodePass) ([]string, []string) {
+	if pass.Number == 0 {
+		return nil, nil
+	}
+	if family == "libx265" {
+		// x265参数以冒号分隔，Windows路径中的冒号需要转义
+		stats := strings.ReplaceAll(filepath.ToSlash(pass.LogFile+".log"), ":", "\\:")
+		return nil, []string{fmt.Sprintf("pass=%d", pass.Number), "stats=" + stats}
+	}
+	return []string{"-pass", strconv.Itoa(pass.Number), "-passlogfile", pass.LogFile}, nil
+}
+
+// passLogPrefix 获取任务的两遍编码统计文件前缀，放在系统临时目录中，不会出现在输出目录
+func passLogPrefix(id string) string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("cm_video_batch_process-%s-passlog", id))
+}
+
+// removeTempFiles 删除以前缀开头的临时文件，两遍编码会生成 -0.log、.mbtree、.cutree 等多个文件，智能切割会生成多个片段
+func removeTempFiles(prefix string) {
+	files, _ := filepath.Glob(prefix + "*")
+	for _, file := range files {
+		os.Remove(file)
+	}
+}
@@ -0,0 +1,83 @@
+package process
+
+import "testing"
+
+func TestValidateTwoPassParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  TranscodeParams
+		wantErr bool
+	}{
+		{name: "单遍编码", params: TranscodeParams{VideoCodec: "copy"}},
+		{name: "目标大小", params: TranscodeParams{VideoCodec: "h264", TargetSizeMB: 100}},
+		{name: "两遍编码指定码率", params: TranscodeParams{VideoCodec: "h265", TwoPass: true, VideoBitrate: "2M"}},
+		{name: "目标大小为负数", params: TranscodeParams{VideoCodec: "h264", TargetSizeMB: -1}, wantErr: true},
+		{name: "直接复制视频流", params: TranscodeParams{VideoCodec: "copy", TargetSizeMB: 100}, wantErr: true},
+		{name: "没有选择视频编码", params: TranscodeParams{TargetSizeMB: 100}, wantErr: true},
+		{name: "加水印时会重新编码", params: TranscodeParams{VideoCodec: "copy", WatermarkContent: "水印", TargetSizeMB: 100}},
+		{
+			name:   "烧录字幕时会重新编码",
+			params: TranscodeParams{VideoCodec: "copy", BurnSubtitle: BurnSubtitleOptions{Source: SubtitleSource_Sidecar}, TargetSizeMB: 100},
+		},
+		{name: "两遍编码不能使用CRF", params: TranscodeParams{VideoCodec: "h264", TwoPass: true, RateControl: RateControl_CRF}, wantErr: true},
+		{name: "两遍编码缺少码率", params: TranscodeParams{VideoCodec: "h264", TwoPass: true}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateTwoPassParams(tt.params); (err != nil) != tt.wantErr {
+				t.Errorf("validateTwoPassParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTargetVideoBitrate(t *testing.T) {
+	tests := []struct {
+		name         string
+		sizeMB       float64
+		duration     float64
+		audioBitrate int64
+		want         int64
+		wantErr      bool
+	}{
+		{name: "扣除封装开销和音频码率", sizeMB: 10, duration: 100, audioBitrate: 128000, want: 656000},
+		{name: "没有音频", sizeMB: 10, duration: 100, want: 784000},
+		{name: "长视频", sizeMB: 700, duration: 5400, audioBitrate: 192000, want: 824296},
+		{name: "刚好达到最低码率", sizeMB: 1, duration: 100, audioBitrate: 28400, want: minTargetVideoBitrate},
+		{name: "低于最低码率", sizeMB: 1, duration: 100, audioBitrate: 128000, wantErr: true},
+		{name: "没有时长", sizeMB: 10, duration: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := targetVideoBitrate(tt.sizeMB, tt.duration, tt.audioBitrate)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("targetVideoBitrate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("targetVideoBitrate() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTargetAudioBitrate(t *testing.T) {
+	tests := []struct {
+		name      string
+		params    TranscodeParams
+		inputInfo *VideoInfo
+		want      int64
+	}{
+		{name: "重新编码时使用指定的码率", params: TranscodeParams{AudioCodec: "aac", AudioBitrate: "96k"}, want: 96000},
+		{name: "重新编码时默认128k", params: TranscodeParams{AudioCodec: "aac"}, want: defaultAudioBitrate},
+		{name: "直接复制时使用源音频码率", params: TranscodeParams{AudioCodec: "copy"}, inputInfo: &VideoInfo{AudioBitrate: 320000}, want: 320000},
+		{name: "源音频码率未知", params: TranscodeParams{AudioCodec: "copy"}, inputInfo: &VideoInfo{}, want: defaultAudioBitrate},
+		{name: "静音", params: TranscodeParams{AudioCodec: "aac", AudioBitrate: "96k", MuteAudio: true}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := targetAudioBitrate(tt.params, tt.inputInfo); got != tt.want {
+				t.Errorf("targetAudioBitrate() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -10,20 +10,40 @@ import (
 )
 
 type VideoInfo struct {
-	ID           string  `json:"id"`
-	Name         string  `json:"name"`
-	Path         string  `json:"path"`
-	Thumbnail    string  `json:"thumbnail"` // 现在存储base64编码的图片数据
-	Size         int64   `json:"size"`
-	Duration     float64 `json:"duration"`
-	Bitrate      int     `json:"bitrate"`
-	Width        int     `json:"width"`
-	Height       int     `json:"height"`
-	FPS          int     `json:"fps"`
-	AudioCodec   string  `json:"audio_codec"`
-	VideoCodec   string  `json:"video_codec"`
-	VideoBitrate int     `json:"video_bitrate"` // 新增视频码率字段
-	AudioBitrate int     `json:"audio_bitrate"` // 新增音频码率字段
+	ID           string       `json:"id"`
+	Name         string       `json:"name"`
+	Path         string       `json:"path"`
+	Thumbnail    string       `json:"thumbnail"` // 现在存储base64编码的图片数据
+	Size         int64        `json:"size"`
+	Duration     float64      `json:"duration"`
+	Bitrate      int          `json:"bitrate"`
+	Width        int          `json:"width"`
+	Height       int          `json:"height"`
+	FPS          int          `json:"fps"`
+	AudioCodec   string       `json:"audio_codec"`
+	VideoCodec   string       `json:"video_codec"`
+	VideoBitrate int          `json:"video_bitrate"` // 新增视频码率字段
+	AudioBitrate int          `json:"audio_bitrate"` // 新增音频码率字段
+	BaseDir      string       `json:"base_dir"`      // 导入文件夹时的根目录，用于在输出目录中重建目录结构
+	FrameRate    string       `json:"frame_rate"`    // 原始帧率，如 30000/1001，FPS取整后无法区分29.97和30
+	SAR          string       `json:"sar"`           // 像素宽高比，如 1:1
+	PixelFormat  string       `json:"pix_fmt"`
+	SampleRate   int          `json:"sample_rate"` // 音频采样率
+	Channels     int          `json:"channels"`    // 音频声道数
+	Streams      []StreamInfo `json:"streams"`     // 所有流，用于按语言选择音轨和字幕
+}
+
+// StreamInfo 输入文件中的一个流
+type StreamInfo struct {
+	Index       int    `json:"index"` // 流在文件中的序号，用于 -map 0:序号
+	Type        string `json:"type"`  // video、audio、subtitle、attachment、data
+	Codec       string `json:"codec"`
+	Language    string `json:"language"` // 语言标签，通常为 ISO 639-2 代码，如 eng、chi、jpn
+	Title       string `json:"title"`
+	Default     bool   `json:"default"`      // 默认轨道
+	Forced      bool   `json:"forced"`       // 强制显示的字幕
+	AttachedPic bool   `json:"attached_pic"` // 封面图片
+	Channels    int    `json:"channels,omitempty"`
 }
 
 // FFprobe 输出的原始 JSON 结构
@@ -48,9 +68,39 @@ type Stream struct {
 	AvgFrameRate string `json:"avg_frame_rate,omitempty"`
 	Duration     string `json:"duration,omitempty"`
 	BitRate      string `json:"bit_rate,omitempty"`
+	SAR          string `json:"sample_aspect_ratio,omitempty"`
+	PixFmt       string `json:"pix_fmt,omitempty"`
+	SampleRate   string `json:"sample_rate,omitempty"`
+	Channels     int    `json:"channels,omitempty"`
+	Index        int    `json:"index"`
+	Tags         struct {
+		Language string `json:"language"`
+		Title    string `json:"title"`
+	} `json:"tags"`
+	Disposition struct {
+		Default     int `json:"default"`
+		Forced      int `json:"forced"`
+		AttachedPic int `json:"attached_pic"`
+	} `json:"disposition"`
 }
 
 func GetVideoInfo(path string) (VideoInfo, error) {
+	info, err := probeVideoInfo(path)
+	if err != nil {
+		return info, err
+	}
+
+	// 生成缩略图并转换为base64
+	thumbnailBase64, err := generateThumbnailBase64(path)
+	if err == nil {
+		info.Thumbnail = thumbnailBase64
+	}
+
+	return info, nil
+}
+
+// probeVideoInfo 使用ffprobe获取视频信息，不生成缩略图
+func probeVideoInfo(path string) (VideoInfo, error) {
 	var info VideoInfo
 	info.ID = GetXid()
 	// 检查ffprobe是否可用
@@ -95,16 +145,33 @@ func GetVideoInfo(path string) (VideoInfo, error) {
 		info.Bitrate = bitRate
 	}
 
-	// 查找视频流和音频流
+	// 查找视频流和音频流，有多个时使用第一个，封面图片不算视频流
 	for _, stream := range ffprobeData.Streams {
+		info.Streams = append(info.Streams, StreamInfo{
+			Index:       stream.Index,
+			Type:        stream.CodecType,
+			Codec:       stream.CodecName,
+			Language:    stream.Tags.Language,
+			Title:       stream.Tags.Title,
+			Default:     stream.Disposition.Default == 1,
+			Forced:      stream.Disposition.Forced == 1,
+			AttachedPic: stream.Disposition.AttachedPic == 1,
+			Channels:    stream.Channels,
+		})
 		switch stream.CodecType {
 		case "video":
+			if info.VideoCodec != "" || stream.Disposition.AttachedPic == 1 {
+				continue
+			}
 			info.VideoCodec = stream.CodecName
 			info.Width = stream.Width
 			info.Height = stream.Height
+			info.SAR = stream.SAR
+			info.PixelFormat = stream.PixFmt
 
 			// 解析帧率
 			if stream.AvgFrameRate != "" && stream.AvgFrameRate != "0/0" {
+				info.FrameRate = stream.AvgFrameRate
 				parts := strings.Split(stream.AvgFrameRate, "/")
 				if len(parts) == 2 {
 					numerator, err1 := strconv.Atoi(parts[0])
@@ -134,7 +201,12 @@ func GetVideoInfo(path string) (VideoInfo, error) {
 			}
 
 		case "audio":
+			if info.AudioCodec != "" {
+				continue
+			}
 			info.AudioCodec = stream.CodecName
+			info.SampleRate, _ = strconv.Atoi(stream.SampleRate)
+			info.Channels = stream.Channels
 			// 解析音频码率
 			if stream.BitRate != "" {
 				if bitRate, err := strconv.Atoi(stream.BitRate); err == nil {
@@ -144,12 +216,6 @@ func GetVideoInfo(path string) (VideoInfo, error) {
 		}
 	}
 
-	// 生成缩略图并转换为base64
-	thumbnailBase64, err := generateThumbnailBase64(path)
-	if err == nil {
-		info.Thumbnail = thumbnailBase64
-	}
-
 	return info, nil
 }
 